@@ -5,25 +5,58 @@ package config
 
 import (
 	"fmt"
+	"net"
+	"net/url"
 	"os"
+	"strings"
+	"text/template"
 	"time"
 
+	"github.com/funeypot/funeypot/internal/pkg/abuseipdb"
+	"github.com/funeypot/funeypot/internal/pkg/syslog"
+
 	"go.uber.org/zap/zapcore"
 	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
-	Log       Log       `yaml:"log"`
-	Ssh       Ssh       `yaml:"ssh"`
-	Http      Http      `yaml:"http"`
-	Ftp       Ftp       `yaml:"ftp"`
-	Database  Database  `yaml:"database"`
-	Dashboard Dashboard `yaml:"dashboard"`
-	Abuseipdb Abuseipdb `yaml:"abuseipdb"`
+	Log         Log         `yaml:"log"`
+	Ssh         Ssh         `yaml:"ssh"`
+	Http        Http        `yaml:"http"`
+	Ftp         Ftp         `yaml:"ftp"`
+	Redis       Redis       `yaml:"redis"`
+	Rdp         Rdp         `yaml:"rdp"`
+	Vnc         Vnc         `yaml:"vnc"`
+	Tcp         []Tcp       `yaml:"tcp"`
+	Database    Database    `yaml:"database"`
+	Dashboard   Dashboard   `yaml:"dashboard"`
+	Abuseipdb   Abuseipdb   `yaml:"abuseipdb"`
+	Retention   Retention   `yaml:"retention"`
+	Aggregation Aggregation `yaml:"aggregation"`
+	Campaign    Campaign    `yaml:"campaign"`
+	Syslog      Syslog      `yaml:"syslog"`
+	Chat        Chat        `yaml:"chat"`
 }
 
 type Log struct {
-	Level string `yaml:"level"`
+	Level    string      `yaml:"level"`
+	Encoding string      `yaml:"encoding"` // empty means "console"
+	Outputs  []string    `yaml:"outputs"`  // empty means "stderr"
+	Rotation LogRotation `yaml:"rotation"`
+	Sampling LogSampling `yaml:"sampling"`
+}
+
+type LogRotation struct {
+	MaxSize    int  `yaml:"max_size"` // megabytes, 0 means no rotation
+	MaxBackups int  `yaml:"max_backups"`
+	MaxAge     int  `yaml:"max_age"` // days
+	Compress   bool `yaml:"compress"`
+}
+
+type LogSampling struct {
+	Enabled    bool `yaml:"enabled"`
+	Initial    int  `yaml:"initial"`
+	Thereafter int  `yaml:"thereafter"`
 }
 
 func (l Log) Validate() error {
@@ -33,27 +66,80 @@ func (l Log) Validate() error {
 	if _, err := zapcore.ParseLevel(l.Level); err != nil {
 		return fmt.Errorf("invalid level %q: %w", l.Level, err)
 	}
+	switch l.Encoding {
+	case "", "console", "json":
+	default:
+		return fmt.Errorf("invalid encoding %q", l.Encoding)
+	}
+	for i, v := range l.Outputs {
+		if v == "" {
+			return fmt.Errorf("outputs[%d]: empty output", i)
+		}
+	}
+	if l.Rotation.MaxSize < 0 || l.Rotation.MaxBackups < 0 || l.Rotation.MaxAge < 0 {
+		return fmt.Errorf("rotation: negative max_size, max_backups or max_age")
+	}
+	if l.Sampling.Enabled && (l.Sampling.Initial <= 0 || l.Sampling.Thereafter < 0) {
+		return fmt.Errorf("sampling: initial should be positive and thereafter should not be negative")
+	}
 	return nil
 }
 
 type Ssh struct {
-	Address string        `yaml:"address"`
-	Delay   time.Duration `yaml:"delay"`
-	KeySeed string        `yaml:"key_seed"`
+	Address   string        `yaml:"address"`
+	Delay     time.Duration `yaml:"delay"`
+	KeySeed   string        `yaml:"key_seed"`
+	Listeners []SshListener `yaml:"listeners"`
+}
+
+// SshListener is an additional address to listen on, the empty fields inherit from Ssh.
+type SshListener struct {
+	Address string         `yaml:"address"`
+	Delay   *time.Duration `yaml:"delay"`
+	// Version is the server version without the "SSH-2.0-" prefix.
+	Version string `yaml:"version"`
 }
 
 func (s Ssh) Validate() error {
-	if s.Address == "" {
-		return fmt.Errorf("address is required")
+	if s.Address == "" && len(s.Listeners) == 0 {
+		return fmt.Errorf("address or listeners is required")
 	}
 	if s.Delay < 0 {
 		return fmt.Errorf("delay cannot be negative")
 	}
-	return nil
+	addresses := make([]string, 0, len(s.Listeners)+1)
+	for _, v := range s.AllListeners() {
+		if v.Delay != nil && *v.Delay < 0 {
+			return fmt.Errorf("delay of %q cannot be negative", v.Address)
+		}
+		addresses = append(addresses, v.Address)
+	}
+	return validateAddresses(addresses)
+}
+
+// AllListeners returns the listener of Address if any, followed by the additional ones, with the empty fields inherited.
+func (s Ssh) AllListeners() []SshListener {
+	var ret []SshListener
+	if s.Address != "" {
+		ret = append(ret, SshListener{Address: s.Address})
+	}
+	ret = append(ret, s.Listeners...)
+	for i := range ret {
+		if ret[i].Delay == nil {
+			ret[i].Delay = &s.Delay
+		}
+	}
+	return ret
 }
 
 type Http struct {
-	Enabled bool   `yaml:"enabled"`
+	Enabled   bool           `yaml:"enabled"`
+	Address   string         `yaml:"address"`
+	Listeners []HttpListener `yaml:"listeners"`
+}
+
+// HttpListener is an additional address to listen on.
+type HttpListener struct {
 	Address string `yaml:"address"`
 }
 
@@ -61,30 +147,175 @@ func (h Http) Validate() error {
 	if !h.Enabled {
 		return nil
 	}
-	if h.Address == "" {
-		return fmt.Errorf("address is required")
+	if h.Address == "" && len(h.Listeners) == 0 {
+		return fmt.Errorf("address or listeners is required")
 	}
-	return nil
+	addresses := make([]string, 0, len(h.Listeners)+1)
+	for _, v := range h.AllListeners() {
+		addresses = append(addresses, v.Address)
+	}
+	return validateAddresses(addresses)
+}
+
+// AllListeners returns the listener of Address if any, followed by the additional ones.
+func (h Http) AllListeners() []HttpListener {
+	var ret []HttpListener
+	if h.Address != "" {
+		ret = append(ret, HttpListener{Address: h.Address})
+	}
+	return append(ret, h.Listeners...)
 }
 
 type Ftp struct {
-	Enabled bool   `yaml:"enabled"`
+	Enabled   bool          `yaml:"enabled"`
+	Address   string        `yaml:"address"`
+	Banner    string        `yaml:"banner"`
+	Listeners []FtpListener `yaml:"listeners"`
+}
+
+// FtpListener is an additional address to listen on, the empty fields inherit from Ftp.
+type FtpListener struct {
 	Address string `yaml:"address"`
+	Banner  string `yaml:"banner"`
 }
 
 func (f Ftp) Validate() error {
 	if !f.Enabled {
 		return nil
 	}
-	if f.Address == "" {
+	if f.Address == "" && len(f.Listeners) == 0 {
+		return fmt.Errorf("address or listeners is required")
+	}
+	addresses := make([]string, 0, len(f.Listeners)+1)
+	for _, v := range f.AllListeners() {
+		addresses = append(addresses, v.Address)
+	}
+	return validateAddresses(addresses)
+}
+
+// AllListeners returns the listener of Address if any, followed by the additional ones, with the empty fields inherited.
+func (f Ftp) AllListeners() []FtpListener {
+	var ret []FtpListener
+	if f.Address != "" {
+		ret = append(ret, FtpListener{Address: f.Address})
+	}
+	ret = append(ret, f.Listeners...)
+	for i := range ret {
+		if ret[i].Banner == "" {
+			ret[i].Banner = f.Banner
+		}
+	}
+	return ret
+}
+
+// validateAddresses checks that the addresses are not empty and not duplicated.
+func validateAddresses(addresses []string) error {
+	seen := make(map[string]struct{}, len(addresses))
+	for _, v := range addresses {
+		if v == "" {
+			return fmt.Errorf("address of listener is required")
+		}
+		if _, ok := seen[v]; ok {
+			return fmt.Errorf("duplicate address %q", v)
+		}
+		seen[v] = struct{}{}
+	}
+	return nil
+}
+
+type Redis struct {
+	Enabled bool          `yaml:"enabled"`
+	Address string        `yaml:"address"`
+	Auth    bool          `yaml:"auth"`
+	Delay   time.Duration `yaml:"delay"`
+}
+
+func (r Redis) Validate() error {
+	if !r.Enabled {
+		return nil
+	}
+	if r.Address == "" {
+		return fmt.Errorf("address is required")
+	}
+	if r.Delay < 0 {
+		return fmt.Errorf("delay cannot be negative")
+	}
+	return nil
+}
+
+type Rdp struct {
+	Enabled bool   `yaml:"enabled"`
+	Address string `yaml:"address"`
+	KeySeed string `yaml:"key_seed"`
+}
+
+func (r Rdp) Validate() error {
+	if !r.Enabled {
+		return nil
+	}
+	if r.Address == "" {
 		return fmt.Errorf("address is required")
 	}
 	return nil
 }
 
+type Vnc struct {
+	Enabled  bool   `yaml:"enabled"`
+	Address  string `yaml:"address"`
+	Version  string `yaml:"version"`
+	Wordlist string `yaml:"wordlist"`
+}
+
+func (v Vnc) Validate() error {
+	if !v.Enabled {
+		return nil
+	}
+	if v.Address == "" {
+		return fmt.Errorf("address is required")
+	}
+	switch v.Version {
+	case "RFB 003.003", "RFB 003.007", "RFB 003.008":
+	default:
+		return fmt.Errorf("invalid version %q, must be one of RFB 003.003, RFB 003.007, RFB 003.008", v.Version)
+	}
+	return nil
+}
+
+// maxTcpBytes is the limit of Tcp.MaxBytes, to keep the records small.
+const maxTcpBytes = 4096
+
+type Tcp struct {
+	Address     string        `yaml:"address"`
+	Banner      string        `yaml:"banner"`
+	ReadTimeout time.Duration `yaml:"read_timeout"`
+	MaxBytes    int           `yaml:"max_bytes"`
+}
+
+func (t Tcp) Validate() error {
+	if t.Address == "" {
+		return fmt.Errorf("address is required")
+	}
+	if _, _, err := net.SplitHostPort(t.Address); err != nil {
+		return fmt.Errorf("invalid address %q: %w", t.Address, err)
+	}
+	if t.ReadTimeout <= 0 {
+		return fmt.Errorf("read_timeout must be positive")
+	}
+	if t.MaxBytes <= 0 || t.MaxBytes > maxTcpBytes {
+		return fmt.Errorf("max_bytes must be between 1 and %d", maxTcpBytes)
+	}
+	return nil
+}
+
+const (
+	MigrationAuto   = "auto"
+	MigrationManual = "manual"
+)
+
 type Database struct {
-	Driver string `yaml:"driver"`
-	Dsn    string `yaml:"dsn"`
+	Driver    string `yaml:"driver"`
+	Dsn       string `yaml:"dsn"`
+	Migration string `yaml:"migration"`
 }
 
 func (d Database) Validate() error {
@@ -95,6 +326,12 @@ func (d Database) Validate() error {
 	if d.Dsn == "" {
 		return fmt.Errorf("dsn is required")
 	}
+
+	switch d.Migration {
+	case "", MigrationAuto, MigrationManual: // empty means auto, for the config files generated by old versions
+	default:
+		return fmt.Errorf("invalid migration %q", d.Migration)
+	}
 	return nil
 }
 
@@ -118,11 +355,98 @@ func (d Dashboard) Validate() error {
 }
 
 type Abuseipdb struct {
-	Enabled  bool          `yaml:"enabled"`
+	Enabled bool `yaml:"enabled"`
+	// BaseUrl is the base URL of the API, empty means abuseipdb.DefaultBaseUrl.
+	BaseUrl  string        `yaml:"base_url"`
 	Key      string        `yaml:"key"`
 	Interval time.Duration `yaml:"interval"`
+	// MinCount is the minimum count of attempts to report, 0 means DefaultAbuseipdbMinCount.
+	MinCount int `yaml:"min_count"`
+	// MinDuration is the minimum duration of attempts to report, 0 means no minimum.
+	MinDuration time.Duration `yaml:"min_duration"`
+	// Categories are the ids or the names of the categories, empty means DefaultAbuseipdbCategories of the kind.
+	Categories []string `yaml:"categories"`
+	// Comment is the text/template of the comment, empty means DefaultAbuseipdbComment.
+	Comment string `yaml:"comment"`
+	// OmitPassword is to omit the passwords from the comments, or they are masked.
+	OmitPassword bool `yaml:"omit_password"`
+	// Kinds overrides the fields by kinds, like "ssh".
+	Kinds map[string]AbuseipdbKind `yaml:"kinds"`
+	Queue AbuseipdbQueue           `yaml:"queue"`
+	Bulk  AbuseipdbBulk            `yaml:"bulk"`
+	Check AbuseipdbCheck           `yaml:"check"`
+	// Blacklist is to fetch the blacklist periodically, to tag the known bad ips before their first attempts.
+	Blacklist AbuseipdbBlacklist `yaml:"blacklist"`
+}
+
+// AbuseipdbQueue is to keep the reports which failed or were limited, and send them later.
+type AbuseipdbQueue struct {
+	Enabled bool `yaml:"enabled"`
+	// RetryDelay is the delay of the first retry, it doubles after each failure.
+	// It's also the interval to check the queue.
+	RetryDelay    time.Duration `yaml:"retry_delay"`
+	MaxRetryDelay time.Duration `yaml:"max_retry_delay"`
+	// MaxAge is how long to keep trying since the attempt.
+	MaxAge time.Duration `yaml:"max_age"`
+	// BatchSize is the maximum count of the reports to send each time the queue is checked.
+	BatchSize int `yaml:"batch_size"`
+	// DailyQuota is the maximum count of the reports in a day of UTC, 0 means unlimited.
+	DailyQuota int `yaml:"daily_quota"`
+}
+
+// AbuseipdbBulk is to send the reports in bulk periodically, instead of one by one.
+// The reports wait in the queue, so it requires the queue, and a bulk is limited to the remaining daily quota of the queue.
+type AbuseipdbBulk struct {
+	Enabled  bool          `yaml:"enabled"`
+	Interval time.Duration `yaml:"interval"`
+	// BatchSize is the maximum count of the reports in a bulk, up to abuseipdb.MaxBulkReports.
+	BatchSize int `yaml:"batch_size"`
+}
+
+// AbuseipdbCheck is to check the reputations of the ips of the attempts, the results are cached in the database.
+type AbuseipdbCheck struct {
+	Enabled bool `yaml:"enabled"`
+	// Ttl is how long to cache the result of an ip.
+	Ttl time.Duration `yaml:"ttl"`
+	// MaxAgeInDays is how old the reports to count are, 1 to 365.
+	MaxAgeInDays int `yaml:"max_age_in_days"`
+}
+
+type AbuseipdbBlacklist struct {
+	Enabled  bool          `yaml:"enabled"`
+	Interval time.Duration `yaml:"interval"`
+	// ConfidenceMinimum is the minimum abuse confidence score of the ips, 25 to 100.
+	ConfidenceMinimum int `yaml:"confidence_minimum"`
+	// Limit is the maximum count of the ips, capped by the plan of the key.
+	Limit int `yaml:"limit"`
 }
 
+// AbuseipdbKind is the reporting of a kind, the empty fields inherit from Abuseipdb.
+type AbuseipdbKind struct {
+	MinCount    int           `yaml:"min_count"`
+	MinDuration time.Duration `yaml:"min_duration"`
+	Categories  []string      `yaml:"categories"`
+	Comment     string        `yaml:"comment"`
+}
+
+const DefaultAbuseipdbMinCount = 5
+
+// DefaultAbuseipdbCategories are the categories of the kinds, see abuseipdb.Categories.
+var DefaultAbuseipdbCategories = map[string][]string{
+	"ssh":   {"18", "22"},
+	"http":  {"18", "21"},
+	"ftp":   {"18", "5"},
+	"redis": {"18", "15"},
+	"rdp":   {"18", "15"},
+	"vnc":   {"18", "15"},
+}
+
+// DefaultAbuseipdbComment is the template of the comment, see the fields in config.yaml.
+const DefaultAbuseipdbComment = `Funeypot detected {{.Count}} {{.Kind}} attempts in {{.Duration}}. ` +
+	`Last by user {{printf "%q" .User}}{{if not .PasswordOmitted}}, password {{printf "%q" .Password}}{{end}}, ` +
+	`client {{printf "%q" .ClientVersion}}.` +
+	`{{with .Campaign}} Part of a campaign of {{.Ips}} IPs from {{.Network}}.{{end}}`
+
 func (a Abuseipdb) Validate() error {
 	if !a.Enabled {
 		return nil
@@ -130,9 +454,410 @@ func (a Abuseipdb) Validate() error {
 	if a.Key == "" {
 		return fmt.Errorf("key is required when enabled")
 	}
+	if a.BaseUrl != "" {
+		if u, err := url.Parse(a.BaseUrl); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return fmt.Errorf("invalid base_url %q", a.BaseUrl)
+		}
+	}
 	if a.Interval < 15*time.Minute {
 		return fmt.Errorf("interval must be at least 15 minutes")
 	}
+	if err := a.validateKind(AbuseipdbKind{
+		MinCount:    a.MinCount,
+		MinDuration: a.MinDuration,
+		Categories:  a.Categories,
+		Comment:     a.Comment,
+	}); err != nil {
+		return err
+	}
+	for name, v := range a.Kinds {
+		if err := a.validateKind(v); err != nil {
+			return fmt.Errorf("kinds.%s: %w", name, err)
+		}
+	}
+	if q := a.Queue; q.Enabled {
+		if q.RetryDelay <= 0 || q.MaxRetryDelay < q.RetryDelay {
+			return fmt.Errorf("queue: retry_delay must be positive, and max_retry_delay must be at least retry_delay")
+		}
+		if q.MaxAge <= 0 {
+			return fmt.Errorf("queue: max_age must be positive")
+		}
+		if q.BatchSize <= 0 {
+			return fmt.Errorf("queue: batch_size must be positive")
+		}
+		if q.DailyQuota < 0 {
+			return fmt.Errorf("queue: daily_quota cannot be negative")
+		}
+	}
+	if c := a.Check; c.Enabled {
+		if c.Ttl <= 0 {
+			return fmt.Errorf("check: ttl must be positive")
+		}
+		if c.MaxAgeInDays < 1 || c.MaxAgeInDays > 365 {
+			return fmt.Errorf("check: max_age_in_days must be 1 to 365")
+		}
+	}
+	if b := a.Blacklist; b.Enabled {
+		if b.Interval < time.Hour {
+			return fmt.Errorf("blacklist: interval must be at least 1 hour")
+		}
+		if b.ConfidenceMinimum < 25 || b.ConfidenceMinimum > 100 {
+			return fmt.Errorf("blacklist: confidence_minimum must be 25 to 100")
+		}
+		if b.Limit <= 0 {
+			return fmt.Errorf("blacklist: limit must be positive")
+		}
+	}
+	if b := a.Bulk; b.Enabled {
+		if !a.Queue.Enabled {
+			return fmt.Errorf("bulk: queue.enabled must be true")
+		}
+		if b.Interval <= 0 {
+			return fmt.Errorf("bulk: interval must be positive")
+		}
+		if b.BatchSize <= 0 || b.BatchSize > abuseipdb.MaxBulkReports {
+			return fmt.Errorf("bulk: batch_size must be 1 to %d", abuseipdb.MaxBulkReports)
+		}
+	}
+	return nil
+}
+
+func (a Abuseipdb) validateKind(k AbuseipdbKind) error {
+	if k.MinCount < 0 {
+		return fmt.Errorf("min_count cannot be negative")
+	}
+	if k.MinDuration < 0 {
+		return fmt.Errorf("min_duration cannot be negative")
+	}
+	for _, v := range k.Categories {
+		if _, ok := abuseipdb.ParseCategory(v); !ok {
+			return fmt.Errorf("unknown category %q, see https://www.abuseipdb.com/categories", v)
+		}
+	}
+	if k.Comment != "" {
+		if _, err := template.New("comment").Parse(k.Comment); err != nil {
+			return fmt.Errorf("invalid comment: %w", err)
+		}
+	}
+	return nil
+}
+
+// KindOf returns the reporting of the kind, with the empty fields inherited.
+func (a Abuseipdb) KindOf(kind string) AbuseipdbKind {
+	ret := a.Kinds[kind]
+	if ret.MinCount == 0 {
+		ret.MinCount = a.MinCount
+	}
+	if ret.MinCount == 0 {
+		ret.MinCount = DefaultAbuseipdbMinCount
+	}
+	if ret.MinDuration == 0 {
+		ret.MinDuration = a.MinDuration
+	}
+	if len(ret.Categories) == 0 {
+		ret.Categories = a.Categories
+	}
+	if len(ret.Categories) == 0 {
+		ret.Categories = DefaultAbuseipdbCategories[kind]
+	}
+	if ret.Comment == "" {
+		ret.Comment = a.Comment
+	}
+	if ret.Comment == "" {
+		ret.Comment = DefaultAbuseipdbComment
+	}
+	return ret
+}
+
+const (
+	AggregationModeGap   = "gap"
+	AggregationModeFixed = "fixed"
+)
+
+// Aggregation is how the attempts of an ip and kind are aggregated into rows of brute attempts.
+type Aggregation struct {
+	AggregationRule `yaml:",inline"`
+	// Kinds overrides the rule by kinds, like "ssh".
+	Kinds map[string]AggregationRule `yaml:"kinds"`
+}
+
+// AggregationRule decides when to start a new row, the empty fields inherit from Aggregation.
+type AggregationRule struct {
+	// Mode is AggregationModeGap to start a new row after no attempts for Gap,
+	// or AggregationModeFixed to start a new row once the row has lasted for Window since its first attempt,
+	// the windows are fixed at the first attempts of the rows rather than sliding.
+	// Empty means AggregationModeGap, for the config files generated by old versions.
+	Mode   string        `yaml:"mode"`
+	Gap    time.Duration `yaml:"gap"`    // 0 means DefaultAggregationDuration
+	Window time.Duration `yaml:"window"` // 0 means DefaultAggregationDuration
+}
+
+const DefaultAggregationDuration = 24 * time.Hour
+
+func (a Aggregation) Validate() error {
+	if err := a.AggregationRule.Validate(); err != nil {
+		return err
+	}
+	for name, v := range a.Kinds {
+		if err := v.Validate(); err != nil {
+			return fmt.Errorf("kinds.%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func (r AggregationRule) Validate() error {
+	switch r.Mode {
+	case "", AggregationModeGap, AggregationModeFixed:
+	default:
+		return fmt.Errorf("invalid mode %q", r.Mode)
+	}
+	if r.Gap < 0 {
+		return fmt.Errorf("gap cannot be negative")
+	}
+	if r.Window < 0 {
+		return fmt.Errorf("window cannot be negative")
+	}
+	return nil
+}
+
+// RuleOf returns the rule of the kind, with the empty fields inherited.
+func (a Aggregation) RuleOf(kind string) AggregationRule {
+	ret := a.Kinds[kind]
+	if ret.Mode == "" {
+		ret.Mode = a.Mode
+	}
+	if ret.Mode == "" {
+		ret.Mode = AggregationModeGap
+	}
+	for _, v := range []struct {
+		value   *time.Duration
+		inherit time.Duration
+	}{
+		{&ret.Gap, a.Gap},
+		{&ret.Window, a.Window},
+	} {
+		if *v.value == 0 {
+			*v.value = v.inherit
+		}
+		if *v.value == 0 {
+			*v.value = DefaultAggregationDuration
+		}
+	}
+	return ret
+}
+
+// Campaign is the detection of the attempts from many ips in a same network.
+type Campaign struct {
+	Enabled    bool          `yaml:"enabled"`
+	Ipv4Prefix int           `yaml:"ipv4_prefix"` // 0 means not to aggregate ipv4 by prefixes
+	Ipv6Prefix int           `yaml:"ipv6_prefix"` // 0 means not to aggregate ipv6 by prefixes
+	Asn        bool          `yaml:"asn"`
+	Gap        time.Duration `yaml:"gap"`
+	MinIps     int           `yaml:"min_ips"`
+	Report     bool          `yaml:"report"`
+}
+
+func (c Campaign) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Ipv4Prefix < 0 || c.Ipv4Prefix > 32 {
+		return fmt.Errorf("ipv4_prefix must be between 0 and 32")
+	}
+	if c.Ipv6Prefix < 0 || c.Ipv6Prefix > 128 {
+		return fmt.Errorf("ipv6_prefix must be between 0 and 128")
+	}
+	if c.Ipv4Prefix == 0 && c.Ipv6Prefix == 0 && !c.Asn {
+		return fmt.Errorf("at least one of ipv4_prefix, ipv6_prefix and asn is required")
+	}
+	if c.Gap <= 0 {
+		return fmt.Errorf("gap must be positive")
+	}
+	if c.MinIps < 2 {
+		return fmt.Errorf("min_ips must be at least 2")
+	}
+	return nil
+}
+
+type Syslog struct {
+	Enabled      bool          `yaml:"enabled"`
+	Network      string        `yaml:"network"` // "udp", "tcp" or "tls"
+	Address      string        `yaml:"address"`
+	Facility     string        `yaml:"facility"`
+	Hostname     string        `yaml:"hostname"` // empty means the hostname of the system
+	AppName      string        `yaml:"app_name"`
+	MaskPassword bool          `yaml:"mask_password"`
+	Timeout      time.Duration `yaml:"timeout"`
+	BufferSize   int           `yaml:"buffer_size"`
+	Tls          SyslogTls     `yaml:"tls"`
+}
+
+type SyslogTls struct {
+	CaFile             string `yaml:"ca_file"`     // empty means the CAs of the system
+	ServerName         string `yaml:"server_name"` // empty means the host of the address
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+}
+
+func (s Syslog) Validate() error {
+	if !s.Enabled {
+		return nil
+	}
+	switch s.Network {
+	case "udp", "tcp", "tls":
+	default:
+		return fmt.Errorf("invalid network %q, it should be udp, tcp or tls", s.Network)
+	}
+	if _, _, err := net.SplitHostPort(s.Address); err != nil {
+		return fmt.Errorf("invalid address %q: %w", s.Address, err)
+	}
+	if _, ok := syslog.ParseFacility(s.Facility); !ok {
+		return fmt.Errorf("invalid facility %q", s.Facility)
+	}
+	if s.Timeout <= 0 {
+		return fmt.Errorf("timeout must be positive")
+	}
+	if s.BufferSize <= 0 {
+		return fmt.Errorf("buffer_size must be positive")
+	}
+	return nil
+}
+
+type Chat struct {
+	Enabled      bool          `yaml:"enabled"`
+	Provider     string        `yaml:"provider"` // "slack", "discord" or "telegram"
+	Url          string        `yaml:"url"`      // the webhook of slack and discord, or the API of telegram
+	Token        string        `yaml:"token"`    // the bot token of telegram
+	ChatId       string        `yaml:"chat_id"`  // the chat of telegram
+	MaskPassword bool          `yaml:"mask_password"`
+	Timeout      time.Duration `yaml:"timeout"`
+	BufferSize   int           `yaml:"buffer_size"`
+	Alerts       ChatAlerts    `yaml:"alerts"`
+	Templates    ChatTemplates `yaml:"templates"`
+	RateLimit    ChatRateLimit `yaml:"rate_limit"`
+}
+
+type ChatAlerts struct {
+	NewIp      bool  `yaml:"new_ip"`     // the first attempt of an ip
+	Thresholds []int `yaml:"thresholds"` // the counts of the attempts of an ip in a row
+	Session    bool  `yaml:"session"`    // a session which gets past the fake authentication
+	Campaign   bool  `yaml:"campaign"`   // a detected campaign of a network
+}
+
+// ChatTemplates are the text/template of the messages, empty means the default one.
+type ChatTemplates struct {
+	NewIp     string `yaml:"new_ip"`
+	Threshold string `yaml:"threshold"`
+	Session   string `yaml:"session"`
+	Campaign  string `yaml:"campaign"`
+}
+
+type ChatRateLimit struct {
+	PerIp   int `yaml:"per_ip"`   // the maximum alerts of an ip in an hour, 0 means unlimited
+	PerHour int `yaml:"per_hour"` // the maximum alerts in an hour, 0 means unlimited
+}
+
+func (c Chat) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	switch c.Provider {
+	case "slack", "discord":
+		if c.Url == "" {
+			return fmt.Errorf("url is required for %s", c.Provider)
+		}
+	case "telegram":
+		if c.Token == "" || c.ChatId == "" {
+			return fmt.Errorf("token and chat_id are required for telegram")
+		}
+	default:
+		return fmt.Errorf("invalid provider %q, it should be slack, discord or telegram", c.Provider)
+	}
+	if c.Url != "" {
+		if u, err := url.Parse(c.Url); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return fmt.Errorf("invalid url %q", c.Url)
+		}
+	}
+	for i, v := range c.Alerts.Thresholds {
+		if v <= 0 {
+			return fmt.Errorf("alerts.thresholds[%d] must be positive", i)
+		}
+	}
+	if c.Timeout <= 0 {
+		return fmt.Errorf("timeout must be positive")
+	}
+	if c.BufferSize <= 0 {
+		return fmt.Errorf("buffer_size must be positive")
+	}
+	if c.RateLimit.PerIp < 0 || c.RateLimit.PerHour < 0 {
+		return fmt.Errorf("rate_limit must not be negative")
+	}
+	return nil
+}
+
+type Retention struct {
+	Enabled    bool          `yaml:"enabled"`
+	Interval   time.Duration `yaml:"interval"`
+	BatchSize  int           `yaml:"batch_size"`
+	BatchPause time.Duration `yaml:"batch_pause"`
+	Downsample bool          `yaml:"downsample"`
+	Tables     struct {
+		BruteAttempts      time.Duration `yaml:"brute_attempts"`
+		AttemptRecords     time.Duration `yaml:"attempt_records"`
+		SessionCommands    time.Duration `yaml:"session_commands"`
+		TcpPayloads        time.Duration `yaml:"tcp_payloads"`
+		IpGeos             time.Duration `yaml:"ip_geos"`
+		IpReputations      time.Duration `yaml:"ip_reputations"`
+		AbuseipdbReports   time.Duration `yaml:"abuseipdb_reports"`
+		AbuseipdbPendings  time.Duration `yaml:"abuseipdb_pendings"`
+		Campaigns          time.Duration `yaml:"campaigns"`
+		CampaignMembers    time.Duration `yaml:"campaign_members"`
+		AttemptDailies     time.Duration `yaml:"attempt_dailies"`
+		AttemptStats       time.Duration `yaml:"attempt_stats"`
+		AttemptStatMembers time.Duration `yaml:"attempt_stat_members"`
+	} `yaml:"tables"`
+}
+
+func (r Retention) Validate() error {
+	if !r.Enabled {
+		return nil
+	}
+	if r.Interval < time.Minute {
+		return fmt.Errorf("interval must be at least 1 minute")
+	}
+	if r.BatchSize <= 0 {
+		return fmt.Errorf("batch_size must be positive")
+	}
+	if r.BatchPause < 0 {
+		return fmt.Errorf("batch_pause cannot be negative")
+	}
+	for name, v := range map[string]time.Duration{
+		"brute_attempts":       r.Tables.BruteAttempts,
+		"attempt_records":      r.Tables.AttemptRecords,
+		"session_commands":     r.Tables.SessionCommands,
+		"tcp_payloads":         r.Tables.TcpPayloads,
+		"ip_geos":              r.Tables.IpGeos,
+		"ip_reputations":       r.Tables.IpReputations,
+		"abuseipdb_reports":    r.Tables.AbuseipdbReports,
+		"abuseipdb_pendings":   r.Tables.AbuseipdbPendings,
+		"campaigns":            r.Tables.Campaigns,
+		"campaign_members":     r.Tables.CampaignMembers,
+		"attempt_dailies":      r.Tables.AttemptDailies,
+		"attempt_stats":        r.Tables.AttemptStats,
+		"attempt_stat_members": r.Tables.AttemptStatMembers,
+	} {
+		if v < 0 {
+			return fmt.Errorf("tables.%s cannot be negative", name)
+		}
+	}
+	if v := r.Tables.AttemptStatMembers; v > 0 && v < 24*time.Hour {
+		// the members of a daily stat are needed until the day ends
+		return fmt.Errorf("tables.attempt_stat_members must be at least 24 hours")
+	}
+	if v := r.Tables.CampaignMembers; v > 0 && (r.Tables.Campaigns == 0 || v < r.Tables.Campaigns) {
+		// or an ip of a continuing campaign is counted again once its member is pruned
+		return fmt.Errorf("tables.campaign_members must be at least tables.campaigns")
+	}
 	return nil
 }
 
@@ -158,7 +883,16 @@ func Load(file string, generate bool) (*Config, error) {
 		return nil, fmt.Errorf("decode yaml: %w", err)
 	}
 
+	applied, err := applyEnv(ret, os.LookupEnv)
+	if err != nil {
+		return nil, fmt.Errorf("apply environment variables: %w", err)
+	}
+
 	if err := ret.Validate(); err != nil {
+		if len(applied) > 0 {
+			return nil, fmt.Errorf("validate config: %w (note that %s override the config file, directly or by the %s variants)",
+				err, strings.Join(applied, ", "), envFileSuffix)
+		}
 		return nil, fmt.Errorf("validate config: %w", err)
 	}
 
@@ -178,6 +912,25 @@ func (c *Config) Validate() error {
 	if err := c.Ftp.Validate(); err != nil {
 		return fmt.Errorf("ftp: %w", err)
 	}
+	if err := c.Redis.Validate(); err != nil {
+		return fmt.Errorf("redis: %w", err)
+	}
+	if err := c.Rdp.Validate(); err != nil {
+		return fmt.Errorf("rdp: %w", err)
+	}
+	if err := c.Vnc.Validate(); err != nil {
+		return fmt.Errorf("vnc: %w", err)
+	}
+	addresses := map[string]struct{}{}
+	for i, v := range c.Tcp {
+		if err := v.Validate(); err != nil {
+			return fmt.Errorf("tcp[%d]: %w", i, err)
+		}
+		if _, ok := addresses[v.Address]; ok {
+			return fmt.Errorf("tcp[%d]: duplicate address %q", i, v.Address)
+		}
+		addresses[v.Address] = struct{}{}
+	}
 	if err := c.Database.Validate(); err != nil {
 		return fmt.Errorf("database: %w", err)
 	}
@@ -187,10 +940,45 @@ func (c *Config) Validate() error {
 	if err := c.Abuseipdb.Validate(); err != nil {
 		return fmt.Errorf("abuse ipdb: %w", err)
 	}
+	if err := c.Aggregation.Validate(); err != nil {
+		return fmt.Errorf("aggregation: %w", err)
+	}
+	if err := c.Campaign.Validate(); err != nil {
+		return fmt.Errorf("campaign: %w", err)
+	}
+	if err := c.Retention.Validate(); err != nil {
+		return fmt.Errorf("retention: %w", err)
+	}
+	if err := c.Syslog.Validate(); err != nil {
+		return fmt.Errorf("syslog: %w", err)
+	}
+	if err := c.Chat.Validate(); err != nil {
+		return fmt.Errorf("chat: %w", err)
+	}
+
+	if c.Campaign.Enabled && c.Campaign.Report && !c.Abuseipdb.Enabled {
+		return fmt.Errorf("abuseipdb.enabled must be true when campaign.report is true")
+	}
 
 	if !c.Http.Enabled && c.Dashboard.Enabled {
 		return fmt.Errorf("http.enabled must be true when dashboard.enabled is true")
 	}
 
+	if c.Retention.Enabled && c.Abuseipdb.Enabled &&
+		c.Retention.Tables.AbuseipdbReports > 0 && c.Retention.Tables.AbuseipdbReports < c.Abuseipdb.Interval {
+		// or the same ip could be reported again within the interval
+		return fmt.Errorf("retention.tables.abuseipdb_reports must be at least abuseipdb.interval")
+	}
+	if c.Retention.Enabled && c.Abuseipdb.Enabled && c.Abuseipdb.Queue.Enabled && c.Abuseipdb.Queue.DailyQuota > 0 &&
+		c.Retention.Tables.AbuseipdbReports > 0 && c.Retention.Tables.AbuseipdbReports < 24*time.Hour {
+		// or the reports of the day are not counted
+		return fmt.Errorf("retention.tables.abuseipdb_reports must be at least 24h when abuseipdb.queue.daily_quota is set")
+	}
+	if c.Retention.Enabled && c.Abuseipdb.Enabled && c.Abuseipdb.Queue.Enabled &&
+		c.Retention.Tables.AbuseipdbPendings > 0 && c.Retention.Tables.AbuseipdbPendings < c.Abuseipdb.Queue.MaxAge {
+		// or the pending reports are dropped before they expire
+		return fmt.Errorf("retention.tables.abuseipdb_pendings must be at least abuseipdb.queue.max_age")
+	}
+
 	return nil
 }
@@ -10,6 +10,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
 )
 
 func TestConfig_Validate(t *testing.T) {
@@ -37,6 +38,45 @@ func TestConfig_Validate(t *testing.T) {
 			},
 			wantErr: assert.Error,
 		},
+		{
+			name: "json log to files",
+			modifyConfig: func(cfg *Config) {
+				cfg.Log.Encoding = "json"
+				cfg.Log.Outputs = []string{"stdout", "/var/log/funeypot.log"}
+				cfg.Log.Rotation.MaxSize = 100
+				cfg.Log.Sampling.Enabled = true
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name: "invalid log encoding",
+			modifyConfig: func(cfg *Config) {
+				cfg.Log.Encoding = "text"
+			},
+			wantErr: assert.Error,
+		},
+		{
+			name: "empty log output",
+			modifyConfig: func(cfg *Config) {
+				cfg.Log.Outputs = []string{"stderr", ""}
+			},
+			wantErr: assert.Error,
+		},
+		{
+			name: "negative log rotation",
+			modifyConfig: func(cfg *Config) {
+				cfg.Log.Rotation.MaxBackups = -1
+			},
+			wantErr: assert.Error,
+		},
+		{
+			name: "invalid log sampling",
+			modifyConfig: func(cfg *Config) {
+				cfg.Log.Sampling.Enabled = true
+				cfg.Log.Sampling.Initial = 0
+			},
+			wantErr: assert.Error,
+		},
 		{
 			name: "empty ssh address",
 			modifyConfig: func(cfg *Config) {
@@ -51,6 +91,37 @@ func TestConfig_Validate(t *testing.T) {
 			},
 			wantErr: assert.Error,
 		},
+		{
+			name: "ssh listeners only",
+			modifyConfig: func(cfg *Config) {
+				cfg.Ssh.Address = ""
+				cfg.Ssh.Listeners = []SshListener{{Address: ":2222"}}
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name: "duplicate ssh address",
+			modifyConfig: func(cfg *Config) {
+				cfg.Ssh.Address = ":22"
+				cfg.Ssh.Listeners = []SshListener{{Address: ":2222"}, {Address: ":22"}}
+			},
+			wantErr: assert.Error,
+		},
+		{
+			name: "empty ssh listener address",
+			modifyConfig: func(cfg *Config) {
+				cfg.Ssh.Listeners = []SshListener{{Version: "OpenSSH_9.6"}}
+			},
+			wantErr: assert.Error,
+		},
+		{
+			name: "invalid ssh listener delay",
+			modifyConfig: func(cfg *Config) {
+				delay := -time.Second
+				cfg.Ssh.Listeners = []SshListener{{Address: ":2222", Delay: &delay}}
+			},
+			wantErr: assert.Error,
+		},
 		{
 			name: "empty http address",
 			modifyConfig: func(cfg *Config) {
@@ -67,6 +138,24 @@ func TestConfig_Validate(t *testing.T) {
 			},
 			wantErr: assert.NoError,
 		},
+		{
+			name: "valid http listeners",
+			modifyConfig: func(cfg *Config) {
+				cfg.Http.Enabled = true
+				cfg.Http.Address = ":80"
+				cfg.Http.Listeners = []HttpListener{{Address: ":8080"}, {Address: ":8000"}}
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name: "duplicate http address",
+			modifyConfig: func(cfg *Config) {
+				cfg.Http.Enabled = true
+				cfg.Http.Address = ""
+				cfg.Http.Listeners = []HttpListener{{Address: ":8080"}, {Address: ":8080"}}
+			},
+			wantErr: assert.Error,
+		},
 		{
 			name: "empty ftp address",
 			modifyConfig: func(cfg *Config) {
@@ -83,6 +172,496 @@ func TestConfig_Validate(t *testing.T) {
 			},
 			wantErr: assert.NoError,
 		},
+		{
+			name: "valid ftp listeners",
+			modifyConfig: func(cfg *Config) {
+				cfg.Ftp.Enabled = true
+				cfg.Ftp.Address = ""
+				cfg.Ftp.Listeners = []FtpListener{{Address: ":2121", Banner: "(vsFTPd 3.0.5)"}}
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name: "empty redis address",
+			modifyConfig: func(cfg *Config) {
+				cfg.Redis.Enabled = true
+				cfg.Redis.Address = ""
+			},
+			wantErr: assert.Error,
+		},
+		{
+			name: "invalid redis delay",
+			modifyConfig: func(cfg *Config) {
+				cfg.Redis.Enabled = true
+				cfg.Redis.Delay = -1
+			},
+			wantErr: assert.Error,
+		},
+		{
+			name: "valid redis",
+			modifyConfig: func(cfg *Config) {
+				cfg.Redis.Enabled = true
+				cfg.Redis.Address = ":6379"
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name: "empty rdp address",
+			modifyConfig: func(cfg *Config) {
+				cfg.Rdp.Enabled = true
+				cfg.Rdp.Address = ""
+			},
+			wantErr: assert.Error,
+		},
+		{
+			name: "valid rdp",
+			modifyConfig: func(cfg *Config) {
+				cfg.Rdp.Enabled = true
+				cfg.Rdp.Address = ":3389"
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name: "empty vnc address",
+			modifyConfig: func(cfg *Config) {
+				cfg.Vnc.Enabled = true
+				cfg.Vnc.Address = ""
+			},
+			wantErr: assert.Error,
+		},
+		{
+			name: "invalid vnc version",
+			modifyConfig: func(cfg *Config) {
+				cfg.Vnc.Enabled = true
+				cfg.Vnc.Version = "RFB 004.000"
+			},
+			wantErr: assert.Error,
+		},
+		{
+			name: "valid vnc",
+			modifyConfig: func(cfg *Config) {
+				cfg.Vnc.Enabled = true
+				cfg.Vnc.Address = ":5900"
+				cfg.Vnc.Version = "RFB 003.003"
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name: "invalid tcp address",
+			modifyConfig: func(cfg *Config) {
+				cfg.Tcp = []Tcp{{Address: "23", ReadTimeout: time.Second, MaxBytes: 1024}}
+			},
+			wantErr: assert.Error,
+		},
+		{
+			name: "invalid tcp read timeout",
+			modifyConfig: func(cfg *Config) {
+				cfg.Tcp = []Tcp{{Address: ":23", MaxBytes: 1024}}
+			},
+			wantErr: assert.Error,
+		},
+		{
+			name: "too large tcp max bytes",
+			modifyConfig: func(cfg *Config) {
+				cfg.Tcp = []Tcp{{Address: ":23", ReadTimeout: time.Second, MaxBytes: 1 << 20}}
+			},
+			wantErr: assert.Error,
+		},
+		{
+			name: "duplicate tcp address",
+			modifyConfig: func(cfg *Config) {
+				cfg.Tcp = []Tcp{
+					{Address: ":23", ReadTimeout: time.Second, MaxBytes: 1024},
+					{Address: ":23", ReadTimeout: time.Second, MaxBytes: 1024},
+				}
+			},
+			wantErr: assert.Error,
+		},
+		{
+			name: "valid tcp",
+			modifyConfig: func(cfg *Config) {
+				cfg.Tcp = []Tcp{
+					{Address: ":23", Banner: "login: ", ReadTimeout: time.Second, MaxBytes: 1024},
+					{Address: ":445", ReadTimeout: time.Second, MaxBytes: 4096},
+				}
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name: "valid aggregation",
+			modifyConfig: func(cfg *Config) {
+				cfg.Aggregation.Mode = AggregationModeFixed
+				cfg.Aggregation.Kinds = map[string]AggregationRule{
+					"ssh": {Mode: AggregationModeGap, Gap: time.Hour},
+				}
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name: "invalid aggregation mode",
+			modifyConfig: func(cfg *Config) {
+				cfg.Aggregation.Kinds = map[string]AggregationRule{
+					"ssh": {Mode: "sliding"},
+				}
+			},
+			wantErr: assert.Error,
+		},
+		{
+			name: "negative aggregation gap",
+			modifyConfig: func(cfg *Config) {
+				cfg.Aggregation.Gap = -time.Hour
+			},
+			wantErr: assert.Error,
+		},
+		{
+			name: "negative abuseipdb min count",
+			modifyConfig: func(cfg *Config) {
+				cfg.Abuseipdb.Enabled = true
+				cfg.Abuseipdb.Key = "test"
+				cfg.Abuseipdb.Kinds = map[string]AbuseipdbKind{
+					"ssh": {MinCount: -1},
+				}
+			},
+			wantErr: assert.Error,
+		},
+		{
+			name: "valid abuseipdb kinds",
+			modifyConfig: func(cfg *Config) {
+				cfg.Abuseipdb.Enabled = true
+				cfg.Abuseipdb.Key = "test"
+				cfg.Abuseipdb.Categories = []string{"Brute-Force", "15"}
+				cfg.Abuseipdb.Comment = "{{.Count}} {{.Kind}} attempts"
+				cfg.Abuseipdb.Kinds = map[string]AbuseipdbKind{
+					"ssh": {MinDuration: time.Minute, Categories: []string{"ssh"}},
+				}
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name: "unknown abuseipdb category",
+			modifyConfig: func(cfg *Config) {
+				cfg.Abuseipdb.Enabled = true
+				cfg.Abuseipdb.Key = "test"
+				cfg.Abuseipdb.Kinds = map[string]AbuseipdbKind{
+					"http": {Categories: []string{"18", "99"}},
+				}
+			},
+			wantErr: assert.Error,
+		},
+		{
+			name: "negative abuseipdb min duration",
+			modifyConfig: func(cfg *Config) {
+				cfg.Abuseipdb.Enabled = true
+				cfg.Abuseipdb.Key = "test"
+				cfg.Abuseipdb.MinDuration = -time.Minute
+			},
+			wantErr: assert.Error,
+		},
+		{
+			name: "invalid abuseipdb comment",
+			modifyConfig: func(cfg *Config) {
+				cfg.Abuseipdb.Enabled = true
+				cfg.Abuseipdb.Key = "test"
+				cfg.Abuseipdb.Comment = "{{.Count"
+			},
+			wantErr: assert.Error,
+		},
+		{
+			name: "invalid abuseipdb queue retry delay",
+			modifyConfig: func(cfg *Config) {
+				cfg.Abuseipdb.Enabled = true
+				cfg.Abuseipdb.Key = "test"
+				cfg.Abuseipdb.Queue.RetryDelay = time.Hour
+				cfg.Abuseipdb.Queue.MaxRetryDelay = time.Minute
+			},
+			wantErr: assert.Error,
+		},
+		{
+			name: "invalid abuseipdb queue batch size",
+			modifyConfig: func(cfg *Config) {
+				cfg.Abuseipdb.Enabled = true
+				cfg.Abuseipdb.Key = "test"
+				cfg.Abuseipdb.Queue.BatchSize = 0
+			},
+			wantErr: assert.Error,
+		},
+		{
+			name: "disabled abuseipdb queue",
+			modifyConfig: func(cfg *Config) {
+				cfg.Abuseipdb.Enabled = true
+				cfg.Abuseipdb.Key = "test"
+				cfg.Abuseipdb.Queue = AbuseipdbQueue{}
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name: "valid abuseipdb bulk",
+			modifyConfig: func(cfg *Config) {
+				cfg.Abuseipdb.Enabled = true
+				cfg.Abuseipdb.Key = "test"
+				cfg.Abuseipdb.BaseUrl = "http://127.0.0.1:8081/api/v2"
+				cfg.Abuseipdb.Bulk.Enabled = true
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name: "invalid abuseipdb base url",
+			modifyConfig: func(cfg *Config) {
+				cfg.Abuseipdb.Enabled = true
+				cfg.Abuseipdb.Key = "test"
+				cfg.Abuseipdb.BaseUrl = "api.abuseipdb.com"
+			},
+			wantErr: assert.Error,
+		},
+		{
+			name: "abuseipdb bulk without queue",
+			modifyConfig: func(cfg *Config) {
+				cfg.Abuseipdb.Enabled = true
+				cfg.Abuseipdb.Key = "test"
+				cfg.Abuseipdb.Queue.Enabled = false
+				cfg.Abuseipdb.Bulk.Enabled = true
+			},
+			wantErr: assert.Error,
+		},
+		{
+			name: "too large abuseipdb bulk batch size",
+			modifyConfig: func(cfg *Config) {
+				cfg.Abuseipdb.Enabled = true
+				cfg.Abuseipdb.Key = "test"
+				cfg.Abuseipdb.Bulk.Enabled = true
+				cfg.Abuseipdb.Bulk.BatchSize = 10001
+			},
+			wantErr: assert.Error,
+		},
+		{
+			name: "valid abuseipdb check and blacklist",
+			modifyConfig: func(cfg *Config) {
+				cfg.Abuseipdb.Enabled = true
+				cfg.Abuseipdb.Key = "test"
+				cfg.Abuseipdb.Check.Enabled = true
+				cfg.Abuseipdb.Blacklist.Enabled = true
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name: "invalid abuseipdb check max age",
+			modifyConfig: func(cfg *Config) {
+				cfg.Abuseipdb.Enabled = true
+				cfg.Abuseipdb.Key = "test"
+				cfg.Abuseipdb.Check.Enabled = true
+				cfg.Abuseipdb.Check.MaxAgeInDays = 366
+			},
+			wantErr: assert.Error,
+		},
+		{
+			name: "invalid abuseipdb blacklist confidence minimum",
+			modifyConfig: func(cfg *Config) {
+				cfg.Abuseipdb.Enabled = true
+				cfg.Abuseipdb.Key = "test"
+				cfg.Abuseipdb.Blacklist.Enabled = true
+				cfg.Abuseipdb.Blacklist.ConfidenceMinimum = 10
+			},
+			wantErr: assert.Error,
+		},
+		{
+			name: "valid campaign",
+			modifyConfig: func(cfg *Config) {
+				cfg.Campaign.Enabled = true
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name: "campaign without networks",
+			modifyConfig: func(cfg *Config) {
+				cfg.Campaign.Enabled = true
+				cfg.Campaign.Ipv4Prefix = 0
+				cfg.Campaign.Ipv6Prefix = 0
+				cfg.Campaign.Asn = false
+			},
+			wantErr: assert.Error,
+		},
+		{
+			name: "invalid campaign prefix",
+			modifyConfig: func(cfg *Config) {
+				cfg.Campaign.Enabled = true
+				cfg.Campaign.Ipv4Prefix = 33
+			},
+			wantErr: assert.Error,
+		},
+		{
+			name: "campaign report without abuseipdb",
+			modifyConfig: func(cfg *Config) {
+				cfg.Campaign.Enabled = true
+				cfg.Campaign.Report = true
+			},
+			wantErr: assert.Error,
+		},
+		{
+			name: "valid retention",
+			modifyConfig: func(cfg *Config) {
+				cfg.Retention.Enabled = true
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name: "invalid retention batch size",
+			modifyConfig: func(cfg *Config) {
+				cfg.Retention.Enabled = true
+				cfg.Retention.BatchSize = 0
+			},
+			wantErr: assert.Error,
+		},
+		{
+			name: "negative retention",
+			modifyConfig: func(cfg *Config) {
+				cfg.Retention.Enabled = true
+				cfg.Retention.Tables.TcpPayloads = -time.Hour
+			},
+			wantErr: assert.Error,
+		},
+		{
+			name: "retention of attempt stat members shorter than a day",
+			modifyConfig: func(cfg *Config) {
+				cfg.Retention.Enabled = true
+				cfg.Retention.Tables.AttemptStatMembers = time.Hour
+			},
+			wantErr: assert.Error,
+		},
+		{
+			name: "retention of abuseipdb reports shorter than interval",
+			modifyConfig: func(cfg *Config) {
+				cfg.Retention.Enabled = true
+				cfg.Retention.Tables.AbuseipdbReports = time.Hour
+				cfg.Abuseipdb.Enabled = true
+				cfg.Abuseipdb.Key = "test"
+				cfg.Abuseipdb.Interval = 24 * time.Hour
+			},
+			wantErr: assert.Error,
+		},
+		{
+			name: "retention of abuseipdb reports shorter than daily quota",
+			modifyConfig: func(cfg *Config) {
+				cfg.Retention.Enabled = true
+				cfg.Retention.Tables.AbuseipdbReports = 12 * time.Hour
+				cfg.Abuseipdb.Enabled = true
+				cfg.Abuseipdb.Key = "test"
+				cfg.Abuseipdb.Interval = time.Hour
+				cfg.Abuseipdb.Queue.DailyQuota = 1000
+			},
+			wantErr: assert.Error,
+		},
+		{
+			name: "retention of abuseipdb pendings shorter than max age",
+			modifyConfig: func(cfg *Config) {
+				cfg.Retention.Enabled = true
+				cfg.Retention.Tables.AbuseipdbPendings = time.Hour
+				cfg.Abuseipdb.Enabled = true
+				cfg.Abuseipdb.Key = "test"
+				cfg.Abuseipdb.Queue.MaxAge = 24 * time.Hour
+			},
+			wantErr: assert.Error,
+		},
+		{
+			name: "retention of campaign members shorter than campaigns",
+			modifyConfig: func(cfg *Config) {
+				cfg.Retention.Enabled = true
+				cfg.Retention.Tables.Campaigns = 48 * time.Hour
+				cfg.Retention.Tables.CampaignMembers = 24 * time.Hour
+			},
+			wantErr: assert.Error,
+		},
+		{
+			name: "valid syslog",
+			modifyConfig: func(cfg *Config) {
+				cfg.Syslog.Enabled = true
+				cfg.Syslog.Network = "tls"
+				cfg.Syslog.Address = "siem.example.com:6514"
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name: "invalid syslog network",
+			modifyConfig: func(cfg *Config) {
+				cfg.Syslog.Enabled = true
+				cfg.Syslog.Network = "unix"
+			},
+			wantErr: assert.Error,
+		},
+		{
+			name: "invalid syslog address",
+			modifyConfig: func(cfg *Config) {
+				cfg.Syslog.Enabled = true
+				cfg.Syslog.Address = "127.0.0.1"
+			},
+			wantErr: assert.Error,
+		},
+		{
+			name: "invalid syslog facility",
+			modifyConfig: func(cfg *Config) {
+				cfg.Syslog.Enabled = true
+				cfg.Syslog.Facility = "local8"
+			},
+			wantErr: assert.Error,
+		},
+		{
+			name: "invalid syslog buffer size",
+			modifyConfig: func(cfg *Config) {
+				cfg.Syslog.Enabled = true
+				cfg.Syslog.BufferSize = 0
+			},
+			wantErr: assert.Error,
+		},
+		{
+			name: "valid chat",
+			modifyConfig: func(cfg *Config) {
+				cfg.Chat.Enabled = true
+				cfg.Chat.Provider = "telegram"
+				cfg.Chat.Token = "token"
+				cfg.Chat.ChatId = "42"
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name: "chat without webhook",
+			modifyConfig: func(cfg *Config) {
+				cfg.Chat.Enabled = true
+				cfg.Chat.Provider = "slack"
+			},
+			wantErr: assert.Error,
+		},
+		{
+			name: "invalid chat provider",
+			modifyConfig: func(cfg *Config) {
+				cfg.Chat.Enabled = true
+				cfg.Chat.Provider = "teams"
+				cfg.Chat.Url = "https://example.com/webhook"
+			},
+			wantErr: assert.Error,
+		},
+		{
+			name: "invalid chat url",
+			modifyConfig: func(cfg *Config) {
+				cfg.Chat.Enabled = true
+				cfg.Chat.Url = "ftp://example.com/webhook"
+			},
+			wantErr: assert.Error,
+		},
+		{
+			name: "invalid chat threshold",
+			modifyConfig: func(cfg *Config) {
+				cfg.Chat.Enabled = true
+				cfg.Chat.Url = "https://example.com/webhook"
+				cfg.Chat.Alerts.Thresholds = []int{0}
+			},
+			wantErr: assert.Error,
+		},
+		{
+			name: "invalid database migration",
+			modifyConfig: func(cfg *Config) {
+				cfg.Database.Migration = "always"
+			},
+			wantErr: assert.Error,
+		},
 		{
 			name: "empty database driver",
 			modifyConfig: func(cfg *Config) {
@@ -185,3 +764,72 @@ func TestLoad(t *testing.T) {
 		assert.Error(t, err)
 	})
 }
+
+func TestSsh_AllListeners(t *testing.T) {
+	cfg := Ssh{}
+	require.NoError(t, yaml.Unmarshal([]byte(`
+address: ":22"
+delay: "2s"
+listeners:
+  - address: ":2222"
+    delay: "0s"
+  - address: ":2200"
+    version: "OpenSSH_9.6"
+`), &cfg))
+
+	listeners := cfg.AllListeners()
+	require.Len(t, listeners, 3)
+	assert.Equal(t, ":22", listeners[0].Address)
+	assert.Equal(t, 2*time.Second, *listeners[0].Delay)
+	assert.Equal(t, ":2222", listeners[1].Address)
+	assert.Equal(t, time.Duration(0), *listeners[1].Delay)
+	assert.Equal(t, ":2200", listeners[2].Address)
+	assert.Equal(t, 2*time.Second, *listeners[2].Delay)
+	assert.Equal(t, "OpenSSH_9.6", listeners[2].Version)
+}
+
+func TestFtp_AllListeners(t *testing.T) {
+	cfg := Ftp{
+		Address: ":21",
+		Banner:  "welcome",
+		Listeners: []FtpListener{
+			{Address: ":2121"},
+			{Address: ":2100", Banner: "(vsFTPd 3.0.5)"},
+		},
+	}
+	assert.Equal(t, []FtpListener{
+		{Address: ":21", Banner: "welcome"},
+		{Address: ":2121", Banner: "welcome"},
+		{Address: ":2100", Banner: "(vsFTPd 3.0.5)"},
+	}, cfg.AllListeners())
+}
+
+func TestAggregation_RuleOf(t *testing.T) {
+	cfg := Aggregation{
+		AggregationRule: AggregationRule{Gap: time.Hour},
+		Kinds: map[string]AggregationRule{
+			"ssh":  {Mode: AggregationModeFixed},
+			"http": {Gap: time.Minute},
+		},
+	}
+	assert.Equal(t, AggregationRule{Mode: AggregationModeFixed, Gap: time.Hour, Window: DefaultAggregationDuration}, cfg.RuleOf("ssh"))
+	assert.Equal(t, AggregationRule{Mode: AggregationModeGap, Gap: time.Minute, Window: DefaultAggregationDuration}, cfg.RuleOf("http"))
+	assert.Equal(t, AggregationRule{Mode: AggregationModeGap, Gap: time.Hour, Window: DefaultAggregationDuration}, cfg.RuleOf("ftp"))
+}
+
+func TestAbuseipdb_KindOf(t *testing.T) {
+	cfg := Abuseipdb{
+		Kinds: map[string]AbuseipdbKind{
+			"http": {MinCount: 10},
+		},
+	}
+	assert.Equal(t, AbuseipdbKind{MinCount: 10, Categories: []string{"18", "21"}, Comment: DefaultAbuseipdbComment}, cfg.KindOf("http"))
+	assert.Equal(t, AbuseipdbKind{MinCount: DefaultAbuseipdbMinCount, Categories: []string{"18", "22"}, Comment: DefaultAbuseipdbComment}, cfg.KindOf("ssh"))
+	cfg.MinCount = 3
+	cfg.MinDuration = time.Minute
+	cfg.Categories = []string{"Hacking"}
+	cfg.Comment = "{{.Ip}}"
+	assert.Equal(t, AbuseipdbKind{MinCount: 3, MinDuration: time.Minute, Categories: []string{"Hacking"}, Comment: "{{.Ip}}"}, cfg.KindOf("ssh"))
+	cfg.Kinds["ssh"] = AbuseipdbKind{Categories: []string{"SSH"}, Comment: "{{.User}}"}
+	assert.Equal(t, AbuseipdbKind{MinCount: 3, MinDuration: time.Minute, Categories: []string{"SSH"}, Comment: "{{.User}}"}, cfg.KindOf("ssh"))
+}
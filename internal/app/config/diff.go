@@ -0,0 +1,82 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Diff returns the yaml paths of the fields changed from the config to the other one, like "ssh.listeners.0.delay".
+// The paths are sorted, and the elements of lists are compared by their indexes.
+func (c *Config) Diff(other *Config) ([]string, error) {
+	a, err := flatten(c)
+	if err != nil {
+		return nil, err
+	}
+	b, err := flatten(other)
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []string
+	for k, v := range a {
+		if w, ok := b[k]; !ok || w != v {
+			ret = append(ret, k)
+		}
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			ret = append(ret, k)
+		}
+	}
+	sort.Strings(ret)
+	return ret, nil
+}
+
+// flatten returns the values of the config by their yaml paths.
+func flatten(cfg *Config) (map[string]string, error) {
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("marshal config: %w", err)
+	}
+	var tree any
+	if err := yaml.Unmarshal(data, &tree); err != nil {
+		return nil, fmt.Errorf("unmarshal config: %w", err)
+	}
+
+	ret := map[string]string{}
+	var walk func(path string, v any)
+	walk = func(path string, v any) {
+		join := func(k string) string {
+			if path == "" {
+				return k
+			}
+			return path + "." + k
+		}
+		switch v := v.(type) {
+		case map[string]any:
+			for k, e := range v {
+				walk(join(k), e)
+			}
+			if len(v) == 0 && path != "" {
+				ret[path] = "{}"
+			}
+		case []any:
+			for i, e := range v {
+				walk(join(strconv.Itoa(i)), e)
+			}
+			if len(v) == 0 {
+				ret[path] = "[]"
+			}
+		default:
+			ret[path] = fmt.Sprint(v)
+		}
+	}
+	walk("", tree)
+	return ret, nil
+}
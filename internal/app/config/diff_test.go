@@ -0,0 +1,48 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_Diff(t *testing.T) {
+	a := &Config{
+		Log: Log{Level: "info"},
+		Ssh: Ssh{Address: ":22", Delay: time.Second, Listeners: []SshListener{{Address: ":2222"}}},
+		Tcp: []Tcp{{Address: ":23"}},
+	}
+
+	diff, err := a.Diff(a)
+	require.NoError(t, err)
+	assert.Empty(t, diff)
+
+	delay := 2 * time.Second
+	b := &Config{
+		Log: Log{Level: "debug"},
+		Ssh: Ssh{Address: ":22", Delay: time.Second, Listeners: []SshListener{{Address: ":2222", Delay: &delay}}},
+		Aggregation: Aggregation{
+			Kinds: map[string]AggregationRule{"ssh": {Gap: time.Hour}},
+		},
+	}
+	diff, err = a.Diff(b)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"aggregation.kinds",
+		"aggregation.kinds.ssh.gap",
+		"aggregation.kinds.ssh.mode",
+		"aggregation.kinds.ssh.window",
+		"log.level",
+		"ssh.listeners.0.delay",
+		"tcp",
+		"tcp.0.address",
+		"tcp.0.banner",
+		"tcp.0.max_bytes",
+		"tcp.0.read_timeout",
+	}, diff)
+}
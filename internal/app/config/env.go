@@ -0,0 +1,111 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix is the prefix of the environment variables overriding the config.
+//
+// Every field can be overridden by the variable named by its yaml path, like FUNEYPOT_ABUSEIPDB_KEY for abuseipdb.key,
+// and the value is in yaml except for strings, like FUNEYPOT_SSH_DELAY="2s" or FUNEYPOT_TCP='[{address: ":23"}]'.
+// The variable with the suffix "_FILE" reads the value from the file, like FUNEYPOT_ABUSEIPDB_KEY_FILE=/run/secrets/key,
+// the trailing newlines of the file are trimmed.
+//
+// Either the variable or the one with "_FILE" overrides the config file, it's an error to set both of them.
+// An empty variable is ignored.
+const EnvPrefix = "FUNEYPOT"
+
+const envFileSuffix = "_FILE"
+
+// applyEnv overrides the config by the environment variables, and returns the names of the applied variables.
+func applyEnv(cfg *Config, lookup func(string) (string, bool)) ([]string, error) {
+	var applied []string
+	err := applyEnvFields(reflect.ValueOf(cfg).Elem(), EnvPrefix, lookup, &applied)
+	return applied, err
+}
+
+func applyEnvFields(v reflect.Value, prefix string, lookup func(string) (string, bool), applied *[]string) error {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		name, inline := yamlName(v.Type().Field(i))
+		if name == "" && !inline {
+			continue
+		}
+		envName := prefix
+		if !inline {
+			envName = prefix + "_" + strings.ToUpper(name)
+		}
+
+		if field.Kind() == reflect.Struct {
+			if err := applyEnvFields(field, envName, lookup, applied); err != nil {
+				return err
+			}
+			continue
+		}
+
+		value, ok, err := lookupEnv(envName, lookup)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		if field.Kind() == reflect.String {
+			field.SetString(value)
+		} else {
+			// replace rather than merge the maps and the slices
+			field.Set(reflect.Zero(field.Type()))
+			if err := yaml.Unmarshal([]byte(value), field.Addr().Interface()); err != nil {
+				return fmt.Errorf("invalid %s: %w", envName, err)
+			}
+		}
+		*applied = append(*applied, envName)
+	}
+	return nil
+}
+
+// lookupEnv returns the value of the variable, or the content of the file of the variable with "_FILE".
+func lookupEnv(name string, lookup func(string) (string, bool)) (string, bool, error) {
+	value, _ := lookup(name)
+	file, _ := lookup(name + envFileSuffix)
+	switch {
+	case value != "" && file != "":
+		return "", false, fmt.Errorf("both %s and %s%s are set, only one is allowed", name, name, envFileSuffix)
+	case value != "":
+		return value, true, nil
+	case file != "":
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return "", false, fmt.Errorf("read %s%s: %w", name, envFileSuffix, err)
+		}
+		return strings.TrimRight(string(content), "\r\n"), true, nil
+	}
+	return "", false, nil
+}
+
+// yamlName returns the name of the field in yaml, and whether it's inline.
+func yamlName(field reflect.StructField) (string, bool) {
+	if !field.IsExported() {
+		return "", false
+	}
+	tag := field.Tag.Get("yaml")
+	name, options, _ := strings.Cut(tag, ",")
+	if name == "-" {
+		return "", false
+	}
+	if options == "inline" {
+		return "", true
+	}
+	if name == "" {
+		name = strings.ToLower(field.Name)
+	}
+	return name, false
+}
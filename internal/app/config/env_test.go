@@ -0,0 +1,102 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_applyEnv(t *testing.T) {
+	secretFile := filepath.Join(t.TempDir(), "key")
+	require.NoError(t, os.WriteFile(secretFile, []byte("secret_key\n"), 0o600))
+
+	newLookup := func(env map[string]string) func(string) (string, bool) {
+		return func(name string) (string, bool) {
+			v, ok := env[name]
+			return v, ok
+		}
+	}
+
+	t.Run("override", func(t *testing.T) {
+		cfg := &Config{
+			Ssh:         Ssh{Address: ":22", Delay: time.Second},
+			Tcp:         []Tcp{{Address: ":23"}, {Address: ":24"}},
+			Aggregation: Aggregation{Kinds: map[string]AggregationRule{"http": {Gap: time.Hour}}},
+		}
+		applied, err := applyEnv(cfg, newLookup(map[string]string{
+			"FUNEYPOT_SSH_ADDRESS":           ":2222",
+			"FUNEYPOT_SSH_DELAY":             "2s",
+			"FUNEYPOT_HTTP_ENABLED":          "true",
+			"FUNEYPOT_DASHBOARD_PASSWORD":    "123: #456",
+			"FUNEYPOT_ABUSEIPDB_KEY_FILE":    secretFile,
+			"FUNEYPOT_TCP":                   `[{address: ":25"}]`,
+			"FUNEYPOT_AGGREGATION_MODE":      "fixed",
+			"FUNEYPOT_AGGREGATION_KINDS":     `{ssh: {gap: "30m"}}`,
+			"FUNEYPOT_SSH_LISTENERS":         "",
+			"FUNEYPOT_RETENTION_BATCH_PAUSE": "1s",
+		}))
+		require.NoError(t, err)
+		assert.Equal(t, ":2222", cfg.Ssh.Address)
+		assert.Equal(t, 2*time.Second, cfg.Ssh.Delay)
+		assert.True(t, cfg.Http.Enabled)
+		assert.Equal(t, "123: #456", cfg.Dashboard.Password)
+		assert.Equal(t, "secret_key", cfg.Abuseipdb.Key)
+		assert.Equal(t, []Tcp{{Address: ":25"}}, cfg.Tcp)
+		assert.Equal(t, "fixed", cfg.Aggregation.Mode, "the inline fields have no prefix")
+		assert.Equal(t, map[string]AggregationRule{"ssh": {Gap: 30 * time.Minute}}, cfg.Aggregation.Kinds)
+		assert.Equal(t, time.Second, cfg.Retention.BatchPause)
+		assert.ElementsMatch(t, []string{
+			"FUNEYPOT_SSH_ADDRESS",
+			"FUNEYPOT_SSH_DELAY",
+			"FUNEYPOT_HTTP_ENABLED",
+			"FUNEYPOT_DASHBOARD_PASSWORD",
+			"FUNEYPOT_ABUSEIPDB_KEY",
+			"FUNEYPOT_TCP",
+			"FUNEYPOT_AGGREGATION_MODE",
+			"FUNEYPOT_AGGREGATION_KINDS",
+			"FUNEYPOT_RETENTION_BATCH_PAUSE",
+		}, applied)
+	})
+
+	t.Run("both", func(t *testing.T) {
+		_, err := applyEnv(&Config{}, newLookup(map[string]string{
+			"FUNEYPOT_ABUSEIPDB_KEY":      "key",
+			"FUNEYPOT_ABUSEIPDB_KEY_FILE": secretFile,
+		}))
+		assert.ErrorContains(t, err, "both FUNEYPOT_ABUSEIPDB_KEY and FUNEYPOT_ABUSEIPDB_KEY_FILE are set")
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := applyEnv(&Config{}, newLookup(map[string]string{
+			"FUNEYPOT_SSH_DELAY": "two seconds",
+		}))
+		assert.ErrorContains(t, err, "invalid FUNEYPOT_SSH_DELAY")
+
+		_, err = applyEnv(&Config{}, newLookup(map[string]string{
+			"FUNEYPOT_DATABASE_DSN_FILE": filepath.Join(t.TempDir(), "not_found"),
+		}))
+		assert.ErrorContains(t, err, "read FUNEYPOT_DATABASE_DSN_FILE")
+	})
+}
+
+func TestLoad_Env(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "funeypot.yaml")
+	require.NoError(t, Generate(file))
+
+	t.Setenv("FUNEYPOT_DATABASE_DSN", "/data/funeypot.db")
+	cfg, err := Load(file, false)
+	require.NoError(t, err)
+	assert.Equal(t, "/data/funeypot.db", cfg.Database.Dsn)
+
+	t.Setenv("FUNEYPOT_DASHBOARD_ENABLED", "true")
+	_, err = Load(file, false)
+	assert.ErrorContains(t, err, "dashboard: username is required")
+	assert.ErrorContains(t, err, "FUNEYPOT_DATABASE_DSN, FUNEYPOT_DASHBOARD_ENABLED override the config file, directly or by the _FILE variants")
+}
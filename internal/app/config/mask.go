@@ -0,0 +1,67 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package config
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const maskedSecret = "******"
+
+// Masked returns a copy of the config with the secrets masked, so it's safe to print.
+func (c *Config) Masked() *Config {
+	ret := *c
+	ret.Ssh.KeySeed = maskSecret(ret.Ssh.KeySeed)
+	ret.Rdp.KeySeed = maskSecret(ret.Rdp.KeySeed)
+	ret.Database.Dsn = maskDsn(ret.Database.Dsn)
+	ret.Dashboard.Password = maskSecret(ret.Dashboard.Password)
+	ret.Abuseipdb.Key = maskSecret(ret.Abuseipdb.Key)
+	ret.Chat.Url = maskWebhook(ret.Chat.Url)
+	ret.Chat.Token = maskSecret(ret.Chat.Token)
+	return &ret
+}
+
+func maskSecret(s string) string {
+	if s == "" {
+		return ""
+	}
+	return maskedSecret
+}
+
+// maskWebhook masks the path of the webhook, since the path is the secret of slack and discord.
+func maskWebhook(s string) string {
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" {
+		return maskSecret(s)
+	}
+	if u.Path == "" || u.Path == "/" {
+		return s
+	}
+	return u.Scheme + "://" + u.Host + "/" + maskedSecret
+}
+
+var dsnPasswordPattern = regexp.MustCompile(`(?i)(password=)('[^']*'|[^\s&]*)`)
+
+// maskDsn masks the password in the dsn, in the forms of URL, "key=value" and "user:password@protocol(address)".
+func maskDsn(dsn string) string {
+	if u, err := url.Parse(dsn); err == nil && u.Scheme != "" && u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			// don't use u.String, it escapes the mask
+			dsn = u.Scheme + "://" + u.User.Username() + ":" + maskedSecret + "@" + u.Host + u.EscapedPath()
+			if u.RawQuery != "" {
+				dsn += "?" + u.RawQuery
+			}
+		}
+	} else if slash := strings.LastIndex(dsn, "/"); slash > 0 {
+		// the same as the mysql driver, the password is between the first ":" and the last "@" before the last "/"
+		if at := strings.LastIndex(dsn[:slash], "@"); at > 0 {
+			if colon := strings.Index(dsn[:at], ":"); colon >= 0 {
+				dsn = dsn[:colon+1] + maskedSecret + dsn[at:]
+			}
+		}
+	}
+	return dsnPasswordPattern.ReplaceAllString(dsn, "${1}"+maskedSecret)
+}
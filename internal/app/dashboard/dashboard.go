@@ -6,9 +6,11 @@ package dashboard
 import (
 	"crypto/subtle"
 	"embed"
+	"fmt"
 	"io/fs"
 	"net/http"
 	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/funeypot/funeypot/internal/app/config"
@@ -21,8 +23,7 @@ import (
 )
 
 type Server struct {
-	username string
-	password string
+	credentials atomic.Pointer[credentials]
 
 	db           *model.Database
 	ipgeoQuerier ipgeo.Querier
@@ -36,11 +37,10 @@ func NewServer(cfg config.Dashboard, db *model.Database, ipgeoQuerier ipgeo.Quer
 	}
 
 	server := &Server{
-		username:     cfg.Username,
-		password:     cfg.Password,
 		db:           db,
 		ipgeoQuerier: ipgeoQuerier,
 	}
+	server.SetCredentials(cfg.Username, cfg.Password)
 
 	gin.SetMode(gin.ReleaseMode)
 	engine := gin.New()
@@ -50,6 +50,12 @@ func NewServer(cfg config.Dashboard, db *model.Database, ipgeoQuerier ipgeo.Quer
 	apiGroup := engine.Group("/api/v1")
 	apiGroup.GET("/points", server.handleGetPoints)
 	apiGroup.GET("/self", server.handleGetSelf)
+	apiGroup.GET("/records", server.handleGetRecords)
+	apiGroup.GET("/stats", server.handleGetStats)
+	apiGroup.GET("/campaigns", server.handleGetCampaigns)
+	apiGroup.GET("/campaigns/:id", server.handleGetCampaign)
+	apiGroup.GET("/credentials/top", server.handleGetTopCredentials)
+	apiGroup.GET("/credentials/new", server.handleGetNewCredentials)
 
 	staticFs, err := fs.Sub(static, "static")
 	if err != nil {
@@ -57,6 +63,7 @@ func NewServer(cfg config.Dashboard, db *model.Database, ipgeoQuerier ipgeo.Quer
 	}
 	httpFs := http.FS(staticFs)
 	engine.StaticFileFS("/", "/", httpFs)
+	engine.StaticFileFS("/credentials", "/credentials.html", httpFs)
 	engine.StaticFS("/static", httpFs)
 
 	server.engine = engine
@@ -68,9 +75,23 @@ func (s *Server) Enabled() bool {
 	return s != nil
 }
 
+type credentials struct {
+	username string
+	password string
+}
+
 func (s *Server) Verify(username, password string) bool {
-	return subtle.ConstantTimeCompare([]byte(s.username), []byte(username)) == 1 &&
-		subtle.ConstantTimeCompare([]byte(s.password), []byte(password)) == 1
+	c := s.credentials.Load()
+	return subtle.ConstantTimeCompare([]byte(c.username), []byte(username)) == 1 &&
+		subtle.ConstantTimeCompare([]byte(c.password), []byte(password)) == 1
+}
+
+// SetCredentials changes the credentials of the dashboard, it's safe to call concurrently.
+func (s *Server) SetCredentials(username, password string) {
+	s.credentials.Store(&credentials{
+		username: username,
+		password: password,
+	})
 }
 
 //go:embed static
@@ -81,11 +102,20 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 }
 
 type responsePoint struct {
-	Ip          string    `json:"ip"`
-	Count       int64     `json:"count"`
-	Latitude    float64   `json:"latitude"`
-	Longitude   float64   `json:"longitude"`
-	ActivatedAt time.Time `json:"activated_at"`
+	Ip          string              `json:"ip"`
+	Count       int64               `json:"count"`
+	Latitude    float64             `json:"latitude"`
+	Longitude   float64             `json:"longitude"`
+	ActivatedAt time.Time           `json:"activated_at"`
+	Reputation  *responseReputation `json:"reputation,omitempty"` // absent if unknown
+}
+
+type responseReputation struct {
+	Score        int    `json:"score"`
+	UsageType    string `json:"usage_type"`
+	Isp          string `json:"isp"`
+	TotalReports int    `json:"total_reports"`
+	Blacklisted  bool   `json:"blacklisted"`
 }
 
 type responseGetPoints struct {
@@ -102,10 +132,16 @@ func (s *Server) handleGetPoints(c *gin.Context) {
 		after = time.Now().AddDate(0, 0, -30) // TODO: make default range configurable
 	}
 
+	filter, err := parseFilter(c)
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
 	pointM := map[string]struct{}{}
 	var points []*responsePoint
 	next := after
-	if err := s.db.ScanBruteAttempt(c, after, func(attempt *model.BruteAttempt, geo *model.IpGeo) bool {
+	if err := s.db.ScanBruteAttempt(c, after, filter, func(attempt *model.BruteAttempt, geo *model.IpGeo) bool {
 		if _, ok := pointM[attempt.Ip]; ok {
 			return true
 		}
@@ -132,12 +168,541 @@ func (s *Server) handleGetPoints(c *gin.Context) {
 		return
 	}
 
+	ips := make([]string, 0, len(points))
+	for _, v := range points {
+		ips = append(ips, v.Ip)
+	}
+	reputations, err := s.db.ListIpReputations(c, ips)
+	if err != nil {
+		logger.Errorf("list ip reputations: %v", err)
+		_ = c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	for _, v := range points {
+		if r, ok := reputations[v.Ip]; ok {
+			v.Reputation = &responseReputation{
+				Score:        r.Score,
+				UsageType:    r.UsageType,
+				Isp:          r.Isp,
+				TotalReports: r.TotalReports,
+				Blacklisted:  r.Blacklisted(),
+			}
+		}
+	}
+
 	c.JSON(http.StatusOK, &responseGetPoints{
 		Points: points,
 		Next:   next.Unix(),
 	})
 }
 
+type responseRecord struct {
+	Id            int64             `json:"id"`
+	Ip            string            `json:"ip"`
+	Kind          string            `json:"kind"`
+	LocalAddr     string            `json:"local_addr"`
+	LocalPort     int               `json:"local_port"`
+	RemotePort    int               `json:"remote_port"`
+	Variant       string            `json:"variant"`
+	User          string            `json:"user"`
+	Password      string            `json:"password"`
+	ClientVersion string            `json:"client_version"`
+	Extra         map[string]string `json:"extra"`
+	Time          time.Time         `json:"time"`
+}
+
+type responseGetRecords struct {
+	Records []*responseRecord `json:"records"`
+	Next    int64             `json:"next"` // the before_id of the next page, 0 if there are no more records
+}
+
+func (s *Server) handleGetRecords(c *gin.Context) {
+	logger := logs.From(c)
+
+	filter, err := parseFilter(c)
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	limit := 100
+	if v := c.Query("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit <= 0 || limit > 1000 {
+			c.String(http.StatusBadRequest, "invalid limit %q, it must be between 1 and 1000", v)
+			return
+		}
+	}
+
+	records, err := s.db.ListAttemptRecords(c, filter, limit)
+	if err != nil {
+		logger.Errorf("list attempt records: %v", err)
+		_ = c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	resp := &responseGetRecords{
+		Records: make([]*responseRecord, 0, len(records)),
+	}
+	for _, v := range records {
+		resp.Records = append(resp.Records, &responseRecord{
+			Id:            v.Id,
+			Ip:            v.Ip,
+			Kind:          v.Kind.String(),
+			LocalAddr:     v.LocalAddr,
+			LocalPort:     v.LocalPort,
+			RemotePort:    v.RemotePort,
+			Variant:       v.Variant,
+			User:          v.User,
+			Password:      v.Password,
+			ClientVersion: v.ClientVersion,
+			Extra:         v.Extra,
+			Time:          v.Time,
+		})
+	}
+	if len(records) == limit {
+		resp.Next = records[len(records)-1].Id
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+type responseStatPoint struct {
+	Start             time.Time `json:"start"`
+	Attempts          int64     `json:"attempts"`
+	UniqueIps         int64     `json:"unique_ips"`
+	UniqueCredentials int64     `json:"unique_credentials"`
+}
+
+type responseGetStats struct {
+	Period string               `json:"period"`
+	Points []*responseStatPoint `json:"points"`
+}
+
+// handleGetStats serves the time series of the rollup stats, without scanning the attempts.
+func (s *Server) handleGetStats(c *gin.Context) {
+	logger := logs.From(c)
+
+	filter := model.AttemptStatFilter{
+		Period:      model.StatPeriodHour,
+		CountryCode: c.Query("country_code"),
+	}
+	if v := c.Query("period"); v != "" {
+		period, ok := model.ParseStatPeriod(v)
+		if !ok {
+			c.String(http.StatusBadRequest, "invalid period %q", v)
+			return
+		}
+		filter.Period = period
+	}
+	if v := c.Query("kind"); v != "" {
+		kind, ok := model.ParseBruteAttemptKind(v)
+		if !ok {
+			c.String(http.StatusBadRequest, "invalid kind %q", v)
+			return
+		}
+		filter.Kind = kind
+	}
+	if v := c.Query("asn"); v != "" {
+		asn, err := strconv.Atoi(v)
+		if err != nil || asn <= 0 {
+			c.String(http.StatusBadRequest, "invalid asn %q", v)
+			return
+		}
+		filter.Asn = asn
+	}
+	for _, v := range []struct {
+		name  string
+		value *time.Time
+	}{
+		{"after", &filter.After},
+		{"before", &filter.Before},
+	} {
+		if q := c.Query(v.name); q != "" {
+			unix, err := strconv.ParseInt(q, 10, 64)
+			if err != nil {
+				c.String(http.StatusBadRequest, "invalid %s %q", v.name, q)
+				return
+			}
+			*v.value = time.Unix(unix, 0)
+		}
+	}
+	if filter.After.IsZero() {
+		if filter.Period == model.StatPeriodDay {
+			filter.After = time.Now().AddDate(0, 0, -30)
+		} else {
+			filter.After = time.Now().Add(-24 * time.Hour)
+		}
+	}
+
+	points, err := s.db.ListAttemptStats(c, filter)
+	if err != nil {
+		logger.Errorf("list attempt stats: %v", err)
+		_ = c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	resp := &responseGetStats{
+		Period: string(filter.Period),
+		Points: make([]*responseStatPoint, 0, len(points)),
+	}
+	for _, v := range points {
+		resp.Points = append(resp.Points, &responseStatPoint{
+			Start:             v.Start,
+			Attempts:          v.Attempts,
+			UniqueIps:         v.UniqueIps,
+			UniqueCredentials: v.UniqueCredentials,
+		})
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+type responseCampaign struct {
+	Id         int64      `json:"id"`
+	Type       string     `json:"type"`
+	Network    string     `json:"network"`
+	Kind       string     `json:"kind"`
+	Ips        int64      `json:"ips"`
+	Attempts   int64      `json:"attempts"`
+	StartedAt  time.Time  `json:"started_at"`
+	StoppedAt  time.Time  `json:"stopped_at"`
+	DetectedAt *time.Time `json:"detected_at"`
+}
+
+func newResponseCampaign(v *model.Campaign) *responseCampaign {
+	return &responseCampaign{
+		Id:         v.Id,
+		Type:       v.Type,
+		Network:    v.Network,
+		Kind:       v.Kind.String(),
+		Ips:        v.Ips,
+		Attempts:   v.Attempts,
+		StartedAt:  v.StartedAt,
+		StoppedAt:  v.StoppedAt,
+		DetectedAt: v.DetectedAt,
+	}
+}
+
+type responseGetCampaigns struct {
+	Campaigns []*responseCampaign `json:"campaigns"`
+	Next      int64               `json:"next"` // the before_id of the next page, 0 if there are no more campaigns
+}
+
+// handleGetCampaigns serves the detected campaigns, the candidates are not included.
+func (s *Server) handleGetCampaigns(c *gin.Context) {
+	logger := logs.From(c)
+
+	filter := model.CampaignFilter{}
+	switch v := c.Query("type"); v {
+	case "", model.CampaignTypePrefix, model.CampaignTypeAsn:
+		filter.Type = v
+	default:
+		c.String(http.StatusBadRequest, "invalid type %q", v)
+		return
+	}
+	if v := c.Query("kind"); v != "" {
+		kind, ok := model.ParseBruteAttemptKind(v)
+		if !ok {
+			c.String(http.StatusBadRequest, "invalid kind %q", v)
+			return
+		}
+		filter.Kind = kind
+	}
+	if v := c.Query("after"); v != "" {
+		unix, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			c.String(http.StatusBadRequest, "invalid after %q", v)
+			return
+		}
+		filter.After = time.Unix(unix, 0)
+	}
+	if v := c.Query("before_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			c.String(http.StatusBadRequest, "invalid before_id %q", v)
+			return
+		}
+		filter.BeforeId = id
+	}
+	limit := 100
+	if v := c.Query("limit"); v != "" {
+		var err error
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit <= 0 || limit > 1000 {
+			c.String(http.StatusBadRequest, "invalid limit %q, it must be between 1 and 1000", v)
+			return
+		}
+	}
+
+	campaigns, err := s.db.ListCampaigns(c, filter, limit)
+	if err != nil {
+		logger.Errorf("list campaigns: %v", err)
+		_ = c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	resp := &responseGetCampaigns{
+		Campaigns: make([]*responseCampaign, 0, len(campaigns)),
+	}
+	for _, v := range campaigns {
+		resp.Campaigns = append(resp.Campaigns, newResponseCampaign(v))
+	}
+	if len(campaigns) == limit {
+		resp.Next = campaigns[len(campaigns)-1].Id
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+type responseCampaignMember struct {
+	Ip       string `json:"ip"`
+	Attempts int64  `json:"attempts"`
+}
+
+type responseGetCampaign struct {
+	*responseCampaign
+	Members []*responseCampaignMember `json:"members"`
+}
+
+func (s *Server) handleGetCampaign(c *gin.Context) {
+	logger := logs.From(c)
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.String(http.StatusBadRequest, "invalid id %q", c.Param("id"))
+		return
+	}
+
+	campaign, members, ok, err := s.db.GetCampaign(c, id)
+	if err != nil {
+		logger.Errorf("get campaign: %v", err)
+		_ = c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	if !ok {
+		c.String(http.StatusNotFound, "campaign %d not found", id)
+		return
+	}
+
+	resp := &responseGetCampaign{
+		responseCampaign: newResponseCampaign(campaign),
+		Members:          make([]*responseCampaignMember, 0, len(members)),
+	}
+	for _, v := range members {
+		resp.Members = append(resp.Members, &responseCampaignMember{
+			Ip:       v.Ip,
+			Attempts: v.Attempts,
+		})
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+type responseCredential struct {
+	User      *string   `json:"user,omitempty"`
+	Password  *string   `json:"password,omitempty"`
+	Ip        *string   `json:"ip,omitempty"`
+	Attempts  int64     `json:"attempts"`
+	Ips       int64     `json:"ips"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+type responseGetCredentials struct {
+	Group       string                `json:"group"`
+	Credentials []*responseCredential `json:"credentials"`
+}
+
+func newResponseGetCredentials(group model.CredentialGroup, counts []*model.CredentialCount) *responseGetCredentials {
+	resp := &responseGetCredentials{
+		Group:       string(group),
+		Credentials: make([]*responseCredential, 0, len(counts)),
+	}
+	for _, v := range counts {
+		credential := &responseCredential{
+			Attempts:  v.Attempts,
+			Ips:       v.Ips,
+			FirstSeen: v.FirstSeen,
+			LastSeen:  v.LastSeen,
+		}
+		switch group {
+		case model.CredentialGroupUser:
+			credential.User = &v.User
+		case model.CredentialGroupPassword:
+			credential.Password = &v.Password
+		case model.CredentialGroupPair:
+			credential.User = &v.User
+			credential.Password = &v.Password
+		case model.CredentialGroupIp:
+			credential.Ip = &v.Ip
+		}
+		resp.Credentials = append(resp.Credentials, credential)
+	}
+	return resp
+}
+
+// handleGetTopCredentials serves the most attempted credentials in the time range, 7 days by default.
+func (s *Server) handleGetTopCredentials(c *gin.Context) {
+	logger := logs.From(c)
+
+	group, filter, limit, err := parseCredentialQuery(c)
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	if filter.After.IsZero() {
+		filter.After = time.Now().AddDate(0, 0, -7)
+	}
+
+	counts, err := s.db.TopCredentials(c, group, filter, limit)
+	if err != nil {
+		logger.Errorf("top credentials: %v", err)
+		_ = c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newResponseGetCredentials(group, counts))
+}
+
+// handleGetNewCredentials serves the credentials first seen after the time, 24 hours ago by default.
+func (s *Server) handleGetNewCredentials(c *gin.Context) {
+	logger := logs.From(c)
+
+	group, filter, limit, err := parseCredentialQuery(c)
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	if !filter.Before.IsZero() {
+		c.String(http.StatusBadRequest, "before is not supported")
+		return
+	}
+	after := filter.After
+	if after.IsZero() {
+		after = time.Now().Add(-24 * time.Hour)
+	}
+
+	counts, err := s.db.NewCredentials(c, group, filter, after, limit)
+	if err != nil {
+		logger.Errorf("new credentials: %v", err)
+		_ = c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newResponseGetCredentials(group, counts))
+}
+
+// parseCredentialQuery parses the group, the filter and the limit of credentials from the query.
+func parseCredentialQuery(c *gin.Context) (model.CredentialGroup, model.CredentialFilter, int, error) {
+	group := model.CredentialGroupPair
+	if v := c.Query("group"); v != "" {
+		g, ok := model.ParseCredentialGroup(v)
+		if !ok {
+			return "", model.CredentialFilter{}, 0, fmt.Errorf("invalid group %q", v)
+		}
+		group = g
+	}
+
+	filter := model.CredentialFilter{
+		Ip:       c.Query("ip"),
+		User:     c.Query("user"),
+		Password: c.Query("password"),
+	}
+	if v := c.Query("kind"); v != "" {
+		kind, ok := model.ParseBruteAttemptKind(v)
+		if !ok {
+			return "", filter, 0, fmt.Errorf("invalid kind %q", v)
+		}
+		filter.Kind = kind
+	}
+	for _, v := range []struct {
+		name  string
+		value *time.Time
+	}{
+		{"after", &filter.After},
+		{"before", &filter.Before},
+	} {
+		if s := c.Query(v.name); s != "" {
+			unix, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				return "", filter, 0, fmt.Errorf("invalid %s %q", v.name, s)
+			}
+			*v.value = time.Unix(unix, 0)
+		}
+	}
+
+	if v := c.Query("min_attempts"); v != "" {
+		minAttempts, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || minAttempts < 0 {
+			return "", filter, 0, fmt.Errorf("invalid min_attempts %q", v)
+		}
+		filter.MinAttempts = minAttempts
+	}
+
+	limit := 20
+	if v := c.Query("limit"); v != "" {
+		var err error
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit <= 0 || limit > 1000 {
+			return "", filter, 0, fmt.Errorf("invalid limit %q, it must be between 1 and 1000", v)
+		}
+	}
+	return group, filter, limit, nil
+}
+
+// parseFilter parses the filter of attempts from the query.
+func parseFilter(c *gin.Context) (model.AttemptRecordFilter, error) {
+	filter := model.AttemptRecordFilter{
+		Ip:      c.Query("ip"),
+		Variant: c.Query("variant"),
+	}
+	if v := c.Query("kind"); v != "" {
+		kind, ok := model.ParseBruteAttemptKind(v)
+		if !ok {
+			return filter, fmt.Errorf("invalid kind %q", v)
+		}
+		filter.Kind = kind
+	}
+	for _, v := range []struct {
+		name  string
+		value *int
+	}{
+		{"local_port", &filter.LocalPort},
+		{"remote_port", &filter.RemotePort},
+	} {
+		if s := c.Query(v.name); s != "" {
+			port, err := strconv.Atoi(s)
+			if err != nil || port <= 0 || port > 65535 {
+				return filter, fmt.Errorf("invalid %s %q", v.name, s)
+			}
+			*v.value = port
+		}
+	}
+	for _, v := range []struct {
+		name  string
+		value *time.Time
+	}{
+		{"after", &filter.After},
+		{"before", &filter.Before},
+	} {
+		if s := c.Query(v.name); s != "" {
+			unix, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				return filter, fmt.Errorf("invalid %s %q", v.name, s)
+			}
+			*v.value = time.Unix(unix, 0)
+		}
+	}
+	if s := c.Query("before_id"); s != "" {
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return filter, fmt.Errorf("invalid before_id %q", s)
+		}
+		filter.BeforeId = id
+	}
+	return filter, nil
+}
+
 type responseGetSelf struct {
 	Latitude  float64 `json:"latitude"`
 	Longitude float64 `json:"longitude"`
@@ -0,0 +1,104 @@
+const form = document.getElementById("filter");
+
+function credentialName(credential) {
+    if (credential.user !== undefined && credential.password !== undefined) {
+        return `${credential.user}:${credential.password}`;
+    }
+    if (credential.user !== undefined) {
+        return credential.user;
+    }
+    if (credential.password !== undefined) {
+        return credential.password;
+    }
+    return credential.ip;
+}
+
+function renderTable(table, credentials, onClick) {
+    table.replaceChildren();
+    const header = table.insertRow();
+    for (const name of ["credential", "attempts", "ips", "first seen", "last seen"]) {
+        const th = document.createElement("th");
+        th.textContent = name;
+        header.appendChild(th);
+    }
+    for (const credential of credentials) {
+        const row = table.insertRow();
+        const name = row.insertCell();
+        if (onClick) {
+            const link = document.createElement("a");
+            link.href = "#";
+            link.textContent = credentialName(credential);
+            link.addEventListener("click", event => {
+                event.preventDefault();
+                onClick(credential);
+            });
+            name.appendChild(link);
+        } else {
+            name.textContent = credentialName(credential);
+        }
+        for (const value of [credential.attempts, credential.ips]) {
+            const cell = row.insertCell();
+            cell.className = "number";
+            cell.textContent = value;
+        }
+        for (const value of [credential.first_seen, credential.last_seen]) {
+            row.insertCell().textContent = new Date(value).toLocaleString();
+        }
+    }
+}
+
+function queryParams() {
+    const data = new FormData(form);
+    const params = new URLSearchParams();
+    params.set("after", Math.floor(Date.now() / 1000) - Number(data.get("range")));
+    for (const name of ["group", "kind", "ip"]) {
+        if (data.get(name)) {
+            params.set(name, data.get(name));
+        }
+    }
+    return params;
+}
+
+async function fetchCredentials(path, params) {
+    try {
+        const response = await fetch(`/api/v1/credentials/${path}?${params.toString()}`);
+        if (!response.ok) {
+            console.error(`Fetch error: ${response.status} ${await response.text()}`);
+            return [];
+        }
+        const data = await response.json();
+        return data.credentials;
+    } catch (error) {
+        console.error(`Fetch error: ${error}`);
+        return [];
+    }
+}
+
+async function showIps(credential) {
+    const params = queryParams();
+    params.set("group", "ip");
+    for (const name of ["user", "password"]) {
+        if (credential[name] !== undefined) {
+            params.set(name, credential[name]);
+        }
+    }
+    document.getElementById("ips-title").textContent = `IPs of ${credentialName(credential)}`;
+    renderTable(document.getElementById("ips"), await fetchCredentials("top", params));
+}
+
+async function refresh() {
+    const params = queryParams();
+    const [top, fresh] = await Promise.all([
+        fetchCredentials("top", params),
+        fetchCredentials("new", params),
+    ]);
+    renderTable(document.getElementById("top"), top, showIps);
+    renderTable(document.getElementById("new"), fresh, showIps);
+}
+
+form.addEventListener("change", refresh);
+form.addEventListener("submit", event => {
+    event.preventDefault();
+    refresh();
+});
+refresh();
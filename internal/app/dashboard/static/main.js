@@ -81,13 +81,13 @@ class Map {
             .catch(error => console.error(error));
     }
 
-    addPoint(name, location, count, activatedAt) {
+    addPoint(name, location, count, activatedAt, reputation) {
         let point = this.config.points[name];
         if (point) {
-            point.update(count, activatedAt);
+            point.update(count, activatedAt, reputation);
             return;
         }
-        point = new Point(name, location, count, activatedAt, this.config.aim, this.svg, this.config.map.projection);
+        point = new Point(name, location, count, activatedAt, reputation, this.config.aim, this.svg, this.config.map.projection);
         point.start();
         this.config.points[name] = point;
     }
@@ -98,7 +98,10 @@ class Map {
             this.after = 0;
         }
         try {
-            const response = await fetch('/api/v1/points?after='+this.after);
+            // the filters of the page, like "?kind=ssh&local_port=2222", are passed to the api
+            const params = new URLSearchParams(window.location.search);
+            params.set("after", this.after);
+            const response = await fetch('/api/v1/points?'+params.toString());
             if (!response.ok) {
                 return;
             }
@@ -109,7 +112,7 @@ class Map {
         if (data) {
             if (data.points) {
                 for (const point of data.points) {
-                    map.addPoint(point.ip, [point.longitude, point.latitude], point.count, point.activated_at);
+                    map.addPoint(point.ip, [point.longitude, point.latitude], point.count, point.activated_at, point.reputation);
                 }
             }
             this.after = data.next;
@@ -119,11 +122,12 @@ class Map {
 }
 
 class Point {
-    constructor(name, location, count, activatedAt, aim, svg, projection) {
+    constructor(name, location, count, activatedAt, reputation, aim, svg, projection) {
         this.name = name;
         this.location = location
         this.count = count;
         this.activatedAt = activatedAt;
+        this.reputation = reputation;
         this.aim = aim;
         this.svg = svg;
         this.projection = projection;
@@ -131,11 +135,12 @@ class Point {
     }
 
     start() {
-        this.svg.append("circle")
+        this.circle = this.svg.append("circle")
             .attr("cx", this.projection(this.location)[0])
             .attr("cy", this.projection(this.location)[1])
             .attr("r", Math.log(this.count) / Math.log(5))
-            .attr("fill", "rgba(255, 0, 0, 0.5)");
+            .attr("fill", this.fill());
+        this.title = this.circle.append("title").text(this.describe());
 
         const line = d3.line().curve(d3.curveBasis);
         const intermediatePoint = [
@@ -166,14 +171,41 @@ class Point {
         this.check();
     }
 
-    update(count, activatedAt) {
+    update(count, activatedAt, reputation) {
         this.count = count;
         this.activatedAt = activatedAt;
+        this.reputation = reputation;
+        this.circle.attr("fill", this.fill());
+        this.title.text(this.describe());
         if (!this.checking) {
             this.check();
         }
     }
 
+    // fill returns the color of the point, the known bad ips on AbuseIPDB are darker
+    fill() {
+        if (this.reputation && (this.reputation.blacklisted || this.reputation.score >= 75)) {
+            return "rgba(139, 0, 0, 0.7)";
+        }
+        return "rgba(255, 0, 0, 0.5)";
+    }
+
+    describe() {
+        let text = `${this.name}\nattempts: ${this.count}`;
+        const r = this.reputation;
+        if (r) {
+            text += `\nabuse score: ${r.score}${r.blacklisted ? " (blacklisted)" : ""}`;
+            if (r.usage_type) {
+                text += `\nusage type: ${r.usage_type}`;
+            }
+            if (r.isp) {
+                text += `\nisp: ${r.isp}`;
+            }
+            text += `\ntotal reports: ${r.total_reports}`;
+        }
+        return text;
+    }
+
     check() {
         this.checking = true;
 
@@ -0,0 +1,71 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package entry
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/funeypot/funeypot/internal/app/config"
+	"github.com/funeypot/funeypot/internal/app/server"
+
+	"gopkg.in/yaml.v3"
+)
+
+const configUsage = `Usage: funeypot config [-c config.yaml] <command>
+
+Commands:
+  check           validate the config file
+  print           print the effective config with the secrets masked
+`
+
+func runConfig(_ context.Context, args []string, stdout io.Writer) error {
+	set := flag.NewFlagSet("funeypot config", flag.ContinueOnError)
+	set.Usage = func() {
+		_, _ = fmt.Fprint(set.Output(), configUsage)
+	}
+	configFile := set.String("c", "config.yaml", "config file")
+	if err := set.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if set.NArg() == 0 {
+		set.Usage()
+		return fmt.Errorf("missing command")
+	}
+
+	switch command := set.Arg(0); command {
+	case "check":
+		cfg, err := config.Load(*configFile, false)
+		if err == nil {
+			err = server.CheckKinds(cfg.Aggregation, cfg.Abuseipdb)
+		}
+		if err != nil {
+			_, _ = fmt.Fprintf(stdout, "invalid config %q: %v\n", *configFile, err)
+			return err
+		}
+		_, _ = fmt.Fprintf(stdout, "config %q is valid\n", *configFile)
+		return nil
+	case "print":
+		cfg, err := config.Load(*configFile, false)
+		if err != nil {
+			_, _ = fmt.Fprintf(stdout, "invalid config %q: %v\n", *configFile, err)
+			return err
+		}
+		encoder := yaml.NewEncoder(stdout)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(cfg.Masked()); err != nil {
+			return err
+		}
+		return encoder.Close()
+	default:
+		set.Usage()
+		return fmt.Errorf("unknown command %q", command)
+	}
+}
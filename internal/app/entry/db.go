@@ -0,0 +1,74 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package entry
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/funeypot/funeypot/internal/app/config"
+	"github.com/funeypot/funeypot/internal/pkg/logs"
+)
+
+const dbUsage = `Usage: funeypot db [-c config.yaml] <command>
+
+Commands:
+  stats           show the rows of the tables
+`
+
+func runDb(ctx context.Context, args []string, stdout io.Writer) error {
+	set := flag.NewFlagSet("funeypot db", flag.ContinueOnError)
+	set.Usage = func() {
+		_, _ = fmt.Fprint(set.Output(), dbUsage)
+	}
+	configFile := set.String("c", "config.yaml", "config file")
+	if err := set.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if set.NArg() == 0 {
+		set.Usage()
+		return fmt.Errorf("missing command")
+	}
+	if command := set.Arg(0); command != "stats" {
+		set.Usage()
+		return fmt.Errorf("unknown command %q", command)
+	}
+
+	logger := logs.Default()
+
+	cfg, err := config.Load(*configFile, false)
+	if err != nil {
+		logger.Errorf("load config: %v", err)
+		return err
+	}
+	ctx = logs.With(ctx, logs.SetLevel(cfg.Log.Level))
+
+	inspector, err := newInspector(ctx, cfg)
+	if err != nil {
+		logger.Errorf("new inspector: %v", err)
+		return err
+	}
+	defer inspector.Close() //nolint:errcheck
+
+	stats, err := inspector.Db.TableStats(ctx)
+	if err != nil {
+		logger.Errorf("get table stats: %v", err)
+		return err
+	}
+
+	_, _ = fmt.Fprintf(stdout, "driver: %s\n\n", cfg.Database.Driver)
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "TABLE\tROWS")
+	for _, v := range stats {
+		_, _ = fmt.Fprintf(w, "%s\t%d\n", v.Name, v.Rows)
+	}
+	return w.Flush()
+}
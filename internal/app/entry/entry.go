@@ -7,18 +7,69 @@ import (
 	"context"
 	"errors"
 	"flag"
+	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
 	"github.com/funeypot/funeypot/internal/app/config"
 	"github.com/funeypot/funeypot/internal/pkg/logs"
 )
 
+const usage = `Usage: funeypot [command] [options]
+
+Commands:
+  serve           start the servers, it's the default command
+  config          check or print the config
+  db              show the statistics of the database
+  ip              show the attempts, the geolocation and the reports of an ip
+  migrate         manage the migrations of the database
+  wordlist        export the wordlists of the attempts
+  version         print the version
+
+Run "funeypot <command> -h" for the usage of a command.
+`
+
 func Run(ctx context.Context, version string, args []string) error {
 	defer logs.Close()
 
-	set := flag.NewFlagSet("funeypot", flag.ContinueOnError)
+	// the flags without a command are of serve, to be compatible with the versions without commands
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return runServe(ctx, version, args)
+	}
+
+	switch command, args := args[0], args[1:]; command {
+	case "serve":
+		return runServe(ctx, version, args)
+	case "config":
+		return runConfig(ctx, args, os.Stdout)
+	case "db":
+		return runDb(ctx, args, os.Stdout)
+	case "ip":
+		return runIp(ctx, args, os.Stdout)
+	case "migrate":
+		return runMigrate(ctx, args, os.Stdout)
+	case "wordlist":
+		return runWordlist(ctx, args, os.Stdout)
+	case "version":
+		_, _ = fmt.Fprintf(os.Stdout, "funeypot %s\n", version)
+		return nil
+	case "help":
+		_, _ = fmt.Fprint(os.Stdout, usage)
+		return nil
+	default:
+		_, _ = fmt.Fprint(os.Stderr, usage)
+		return fmt.Errorf("unknown command %q", command)
+	}
+}
+
+func runServe(ctx context.Context, version string, args []string) error {
+	set := flag.NewFlagSet("funeypot serve", flag.ContinueOnError)
+	set.Usage = func() {
+		_, _ = fmt.Fprint(set.Output(), usage, "\nOptions of serve:\n")
+		set.PrintDefaults()
+	}
 	configFile := set.String("c", "config.yaml", "config file")
 	configDisableGenerate := set.Bool("disable-generate", false, "don't generate config file if not exists")
 	if err := set.Parse(args); err != nil {
@@ -40,7 +91,12 @@ func Run(ctx context.Context, version string, args []string) error {
 		logger.Errorf("load config: %v", err)
 		return err
 	}
-	logger = logs.SetLevel(cfg.Log.Level)
+	logger, err = logs.Setup(logOptions(cfg.Log))
+	if err != nil {
+		logger = logs.Default()
+		logger.Errorf("setup logger: %v", err)
+		return err
+	}
 	ctx = logs.With(ctx, logger)
 
 	entrypoint, err := NewEntrypoint(ctx, cfg)
@@ -51,9 +107,47 @@ func Run(ctx context.Context, version string, args []string) error {
 
 	entrypoint.Startup(ctx, cancel)
 
-	<-ctx.Done()
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for done := false; !done; {
+		select {
+		case <-ctx.Done():
+			done = true
+		case <-hup:
+			logger.Infof("reload config %s", *configFile)
+			cfg, err := config.Load(*configFile, false)
+			if err != nil {
+				logger.Errorf("reload config: %v", err)
+				continue
+			}
+			if err := entrypoint.Reload(ctx, cancel, cfg); err != nil {
+				logger.Errorf("reload config: %v", err)
+			}
+		}
+	}
 	logger.Infof("shutdown")
 	entrypoint.Shutdown(ctx)
 
 	return nil
 }
+
+func logOptions(cfg config.Log) logs.Options {
+	return logs.Options{
+		Level:    cfg.Level,
+		Encoding: cfg.Encoding,
+		Outputs:  cfg.Outputs,
+		Rotation: logs.Rotation{
+			MaxSize:    cfg.Rotation.MaxSize,
+			MaxBackups: cfg.Rotation.MaxBackups,
+			MaxAge:     cfg.Rotation.MaxAge,
+			Compress:   cfg.Rotation.Compress,
+		},
+		Sampling: logs.Sampling{
+			Enabled:    cfg.Sampling.Enabled,
+			Initial:    cfg.Sampling.Initial,
+			Thereafter: cfg.Sampling.Thereafter,
+		},
+	}
+}
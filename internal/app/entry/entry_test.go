@@ -4,12 +4,18 @@
 package entry
 
 import (
+	"bytes"
 	"context"
+	"os"
 	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/funeypot/funeypot/internal/app/config"
+	"github.com/funeypot/funeypot/internal/app/model"
+
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestRun(t *testing.T) {
@@ -58,3 +64,239 @@ func testRun(args []string) error {
 		return retErr
 	}
 }
+
+func TestRunMigrate(t *testing.T) {
+	ctx := context.Background()
+
+	configFile := newTestConfigFile(t, `migration: "auto"`, `migration: "manual"`)
+
+	run := func(args ...string) (string, error) {
+		buf := &bytes.Buffer{}
+		err := runMigrate(ctx, append([]string{"-c", configFile}, args...), buf)
+		return buf.String(), err
+	}
+
+	out, err := run("status")
+	require.NoError(t, err)
+	assert.Regexp(t, `(?m)^1\s+baseline\s+pending$`, out)
+
+	// refuse to start with pending migrations
+	assert.ErrorContains(t, testRun([]string{"-c", configFile}), "pending migrations")
+
+	out, err = run("up", "-to", "1")
+	require.NoError(t, err)
+	assert.Equal(t, "applied 1 baseline\n", out)
+
+	out, err = run("up")
+	require.NoError(t, err)
+	assert.Contains(t, out, "applied 2 ")
+	assert.NotContains(t, out, "applied 1 ")
+
+	out, err = run("up")
+	require.NoError(t, err)
+	assert.Equal(t, "no pending migrations\n", out)
+
+	out, err = run("status")
+	require.NoError(t, err)
+	assert.NotContains(t, out, "pending")
+
+	_, err = run()
+	assert.Error(t, err)
+	_, err = run("down")
+	assert.Error(t, err)
+	assert.NoError(t, testRun([]string{"migrate", "-h"}))
+}
+
+func TestRunWordlist(t *testing.T) {
+	ctx := context.Background()
+
+	configFile := newTestConfigFile(t)
+
+	cfg, err := config.Load(configFile, false)
+	require.NoError(t, err)
+	db, err := model.NewDatabase(ctx, cfg.Database)
+	require.NoError(t, err)
+	now := time.Now()
+	for _, v := range []struct {
+		kind     model.BruteAttemptKind
+		user     string
+		password string
+		time     time.Time
+	}{
+		{model.BruteAttemptKindSsh, "root", "123456", now},
+		{model.BruteAttemptKindSsh, "root", "123456", now},
+		{model.BruteAttemptKindSsh, "admin", "123456", now},
+		{model.BruteAttemptKindSsh, "root", "password", now.Add(-48 * time.Hour)},
+		{model.BruteAttemptKindHttp, "admin", "admin", now},
+	} {
+		require.NoError(t, db.Create(ctx, &model.AttemptRecord{
+			Ip:       "10.0.0.1",
+			Kind:     v.kind,
+			User:     v.user,
+			Password: v.password,
+			Time:     v.time,
+		}))
+	}
+	require.NoError(t, db.Close())
+
+	run := func(args ...string) (string, error) {
+		buf := &bytes.Buffer{}
+		err := runWordlist(ctx, append([]string{"-c", configFile}, args...), buf)
+		return buf.String(), err
+	}
+
+	out, err := run()
+	require.NoError(t, err)
+	assert.Equal(t, "123456\nadmin\npassword\n", out)
+
+	out, err = run("-type", "user", "-kind", "ssh")
+	require.NoError(t, err)
+	assert.Equal(t, "root\nadmin\n", out)
+
+	out, err = run("-type", "combo", "-after", "24h", "-min-count", "2")
+	require.NoError(t, err)
+	assert.Equal(t, "root:123456\n", out)
+
+	out, err = run("-type", "combo", "-separator", "\t", "-limit", "1", "-before", now.Add(-24*time.Hour).Format(time.RFC3339))
+	require.NoError(t, err)
+	assert.Equal(t, "root\tpassword\n", out)
+
+	output := filepath.Join(t.TempDir(), "passwords.txt")
+	out, err = run("-o", output, "-kind", "http")
+	require.NoError(t, err)
+	assert.Empty(t, out)
+	content, err := os.ReadFile(output)
+	require.NoError(t, err)
+	assert.Equal(t, "admin\n", string(content))
+
+	_, err = run("-type", "email")
+	assert.Error(t, err)
+	_, err = run("-after", "yesterday")
+	assert.Error(t, err)
+	assert.NoError(t, testRun([]string{"wordlist", "-h"}))
+}
+
+func TestRunConfig(t *testing.T) {
+	ctx := context.Background()
+	configFile := newTestConfigFile(t, `password: ""`, `password: "dashboard_password"`, `key: ""`, `key: "abuseipdb_key"`)
+
+	run := func(args ...string) (string, error) {
+		buf := &bytes.Buffer{}
+		err := runConfig(ctx, args, buf)
+		return buf.String(), err
+	}
+
+	out, err := run("-c", configFile, "check")
+	require.NoError(t, err)
+	assert.Contains(t, out, "is valid")
+
+	out, err = run("-c", configFile, "print")
+	require.NoError(t, err)
+	assert.Contains(t, out, `password: '******'`)
+	assert.Contains(t, out, `key: '******'`)
+	assert.NotContains(t, out, "dashboard_password")
+	assert.NotContains(t, out, "abuseipdb_key")
+	assert.Contains(t, out, `driver: sqlite`)
+
+	invalidFile := newTestConfigFile(t, `level: "info"`, `level: "verbose"`)
+	out, err = run("-c", invalidFile, "check")
+	assert.ErrorContains(t, err, "log: invalid level")
+	assert.Contains(t, out, "invalid config")
+
+	unknownKindFile := newTestConfigFile(t, "  kinds: {}\n\n# Configuration for detecting campaigns",
+		"  kinds:\n    telnet:\n      gap: \"1h\"\n\n# Configuration for detecting campaigns")
+	out, err = run("-c", unknownKindFile, "check")
+	assert.ErrorContains(t, err, `unknown kind "telnet" in aggregation.kinds`)
+	assert.Contains(t, out, "invalid config")
+
+	_, err = run("-c", filepath.Join(t.TempDir(), "config.yaml"), "check")
+	assert.ErrorContains(t, err, "does not exist")
+	_, err = run("-c", configFile)
+	assert.Error(t, err)
+	_, err = run("-c", configFile, "edit")
+	assert.Error(t, err)
+}
+
+func TestRunDb(t *testing.T) {
+	ctx := context.Background()
+	configFile := newTestConfigFile(t)
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, runDb(ctx, []string{"-c", configFile, "stats"}, buf))
+	assert.Contains(t, buf.String(), "driver: sqlite")
+	assert.Regexp(t, `(?m)^brute_attempts\s+0$`, buf.String())
+
+	assert.Error(t, runDb(ctx, []string{"-c", configFile}, buf))
+	assert.Error(t, runDb(ctx, []string{"-c", configFile, "vacuum"}, buf))
+}
+
+func TestRunIp(t *testing.T) {
+	ctx := context.Background()
+	configFile := newTestConfigFile(t)
+
+	cfg, err := config.Load(configFile, false)
+	require.NoError(t, err)
+	db, err := model.NewDatabase(ctx, cfg.Database)
+	require.NoError(t, err)
+	now := time.Now()
+	_, err = db.IncrBruteAttempt(ctx, "192.0.2.1", model.BruteAttemptKindSsh, now,
+		"root", "123456", "SSH-2.0-Go", 22, model.AttemptWindow{Gap: time.Hour})
+	require.NoError(t, err)
+	require.NoError(t, db.Create(ctx, &model.AbuseipdbReport{Ip: "192.0.2.1", ReportedAt: now, Score: 100}))
+	require.NoError(t, db.Close())
+
+	run := func(args ...string) (string, error) {
+		buf := &bytes.Buffer{}
+		err := runIp(ctx, append([]string{"-c", configFile}, args...), buf)
+		return buf.String(), err
+	}
+
+	out, err := run("show", "192.0.2.1")
+	require.NoError(t, err)
+	assert.Regexp(t, `(?m)^IP:\s+192.0.2.1$`, out)
+	assert.Contains(t, out, "ATTEMPTS (1):")
+	assert.Regexp(t, `(?m)^ssh\s+1\s+.+"root"\s+"123456"`, out)
+	assert.Contains(t, out, "REPORTS (1):")
+	assert.Regexp(t, `(?m)\s+100$`, out)
+
+	out, err = run("show", "192.0.2.2")
+	require.NoError(t, err)
+	assert.Contains(t, out, "ATTEMPTS (0):")
+
+	_, err = run("show")
+	assert.EqualError(t, err, "missing ip")
+	_, err = run("show", "192.0.2.1", "192.0.2.2")
+	assert.EqualError(t, err, "too many arguments")
+	_, err = run("show", "192.0.2")
+	assert.EqualError(t, err, `invalid ip "192.0.2"`)
+	_, err = run("block", "192.0.2.1")
+	assert.Error(t, err)
+}
+
+func TestRun_Commands(t *testing.T) {
+	assert.NoError(t, testRun([]string{"version"}))
+	assert.NoError(t, testRun([]string{"help"}))
+	assert.NoError(t, testRun([]string{"serve", "-h"}))
+	assert.NoError(t, testRun([]string{"config", "-h"}))
+	assert.NoError(t, testRun([]string{"db", "-h"}))
+	assert.NoError(t, testRun([]string{"ip", "-h"}))
+	assert.ErrorContains(t, testRun([]string{"start"}), "unknown command")
+}
+
+// newTestConfigFile generates a config file with the database in a temporary directory,
+// and replaces the contents by the pairs of old and new.
+func newTestConfigFile(t *testing.T, replaces ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "config.yaml")
+	require.NoError(t, config.Generate(configFile))
+	content, err := os.ReadFile(configFile)
+	require.NoError(t, err)
+	replaces = append(replaces, `dsn: "funeypot.db"`, `dsn: "`+filepath.Join(dir, "funeypot.db")+`"`)
+	for i := 0; i+1 < len(replaces); i += 2 {
+		require.True(t, bytes.Contains(content, []byte(replaces[i])), "missing %q", replaces[i])
+		content = bytes.Replace(content, []byte(replaces[i]), []byte(replaces[i+1]), 1)
+	}
+	require.NoError(t, os.WriteFile(configFile, content, 0o644))
+	return configFile
+}
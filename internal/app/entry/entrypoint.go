@@ -5,46 +5,246 @@ package entry
 
 import (
 	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
 
 	"github.com/funeypot/funeypot/internal/app/config"
+	"github.com/funeypot/funeypot/internal/app/dashboard"
+	"github.com/funeypot/funeypot/internal/app/retention"
 	"github.com/funeypot/funeypot/internal/app/server"
+	"github.com/funeypot/funeypot/internal/app/sink"
+	"github.com/funeypot/funeypot/internal/pkg/abuseipdb"
 	"github.com/funeypot/funeypot/internal/pkg/logs"
 )
 
 type Entrypoint struct {
-	Config     *config.Config
-	SshServer  *server.SshServer
-	HttpServer *server.HttpServer
-	FtpServer  *server.FtpServer
+	Config    *config.Config
+	Servers   []server.Server
+	Retention *retention.Runner
+
+	// the dependencies to apply the reloaded config
+	handler         *server.Handler
+	dashboardServer *dashboard.Server
+	abuseipdbClient *abuseipdb.Client
+	sinks           sink.Sinks
 }
 
 func newEntrypoint(
-	sshServer *server.SshServer,
-	httpServer *server.HttpServer,
-	ftpServer *server.FtpServer,
+	cfg *config.Config,
+	servers []server.Server,
+	retentionRunner *retention.Runner,
+	handler *server.Handler,
+	dashboardServer *dashboard.Server,
+	abuseipdbClient *abuseipdb.Client,
+	sinks sink.Sinks,
 ) *Entrypoint {
 	return &Entrypoint{
-		SshServer:  sshServer,
-		HttpServer: httpServer,
-		FtpServer:  ftpServer,
+		Config:          cfg,
+		Servers:         servers,
+		Retention:       retentionRunner,
+		handler:         handler,
+		dashboardServer: dashboardServer,
+		abuseipdbClient: abuseipdbClient,
+		sinks:           sinks,
 	}
 }
 
 func (e *Entrypoint) Startup(ctx context.Context, cancel context.CancelFunc) {
-	e.SshServer.Startup(ctx, cancel)
-	e.HttpServer.Startup(ctx, cancel)
-	e.FtpServer.Startup(ctx, cancel)
+	for _, s := range e.Servers {
+		s.Startup(ctx, cancel)
+	}
+	e.Retention.Startup(ctx, cancel)
 }
 
 func (e *Entrypoint) Shutdown(ctx context.Context) {
 	logger := logs.From(ctx)
-	if err := e.SshServer.Shutdown(ctx); err != nil {
-		logger.Warnf("shutdown ssh server: %v", err)
+	for _, s := range e.Servers {
+		if err := s.Shutdown(ctx); err != nil {
+			logger.Warnf("shutdown server: %v", err)
+		}
+	}
+	if err := e.Retention.Shutdown(ctx); err != nil {
+		logger.Warnf("shutdown retention: %v", err)
+	}
+}
+
+var (
+	// liveFields are the fields applied without restarting anything
+	liveFields = regexp.MustCompile(`^(log\.level|dashboard\.(username|password)|abuseipdb\.interval|ssh\.delay|ssh\.listeners\.\d+\.delay|redis\.delay)$`)
+	// listenerFields are the fields applied by starting or stopping the listeners of the addresses
+	listenerFields = regexp.MustCompile(`^((ssh|http|ftp|redis|rdp|vnc)\.(enabled|address)|(ssh|http|ftp)\.listeners(\.\d+\.address)?|tcp(\.\d+\.address)?)$`)
+	// listenerElement is the element of a listener list, its fields are applied with a new listener if its address changes
+	listenerElement = regexp.MustCompile(`^((ssh|http|ftp)\.listeners|tcp)\.\d+\.`)
+	// sinkFields are the fields applied by replacing all the sinks
+	sinkFields = regexp.MustCompile(`^(syslog|chat)\.`)
+)
+
+// Reload applies the changes of the config which can be applied without restarting the process,
+// starts or stops the listeners whose addresses change, and replaces the sinks if any of them changes.
+// The other changes are logged and ignored. It changes nothing if a new listener fails to listen.
+func (e *Entrypoint) Reload(ctx context.Context, cancel context.CancelFunc, cfg *config.Config) error {
+	logger := logs.From(ctx)
+
+	changes, err := e.Config.Diff(cfg)
+	if err != nil {
+		return fmt.Errorf("diff config: %w", err)
+	}
+	if len(changes) == 0 {
+		logger.Infof("reload config, nothing changed")
+		return nil
+	}
+
+	// build the servers before applying anything, so a failure leaves everything unchanged
+	servers, err := newServers(cfg, e.handler, e.dashboardServer)
+	if err != nil {
+		return fmt.Errorf("new servers: %w", err)
+	}
+
+	sinksChanged := slices.ContainsFunc(changes, sinkFields.MatchString)
+	var sinks sink.Sinks
+	if sinksChanged {
+		sinks, err = newSinks(ctx, cfg)
+		if err != nil {
+			return fmt.Errorf("new sinks: %w", err)
+		}
+	}
+
+	changed := map[string]bool{}
+	for _, v := range changes {
+		changed[v] = true
+	}
+	var applied, ignored []string
+	for _, v := range changes {
+		switch {
+		case liveFields.MatchString(v):
+			if strings.HasPrefix(v, "dashboard.") && !e.dashboardServer.Enabled() ||
+				strings.HasPrefix(v, "abuseipdb.") && !e.abuseipdbClient.Enabled() {
+				ignored = append(ignored, v)
+				continue
+			}
+			applied = append(applied, v)
+		case listenerFields.MatchString(v), sinkFields.MatchString(v):
+			applied = append(applied, v)
+		case listenerElement.MatchString(v) && changed[listenerElement.FindString(v)+"address"]:
+			applied = append(applied, v)
+		default:
+			ignored = append(ignored, v)
+		}
+	}
+
+	// listen on the new addresses first, so a busy or privileged one leaves everything unchanged
+	next, err := e.reloadServers(ctx, cancel, servers)
+	if err != nil {
+		if sinksChanged {
+			sinks.Close()
+		}
+		return err
+	}
+	e.Servers = next
+
+	logs.SetLevel(cfg.Log.Level)
+	if e.dashboardServer.Enabled() {
+		e.dashboardServer.SetCredentials(cfg.Dashboard.Username, cfg.Dashboard.Password)
+	}
+	if e.abuseipdbClient.Enabled() {
+		e.abuseipdbClient.SetInterval(cfg.Abuseipdb.Interval)
+	}
+	if sinksChanged {
+		e.handler.SetSinks(sinks)
+		e.sinks.Close()
+		e.sinks = sinks
+	}
+	e.Config = cfg
+
+	if len(applied) > 0 {
+		logger.Infof("reload config, applied changes of %s", strings.Join(applied, ", "))
+	}
+	if len(ignored) > 0 {
+		logger.Warnf("reload config, ignored changes of %s, restart to apply them", strings.Join(ignored, ", "))
+	}
+	return nil
+}
+
+// reloadServers keeps the current servers which have the same addresses as the next ones, and applies their settings,
+// then stops the other current ones and starts the other next ones. It returns the servers after reloading.
+// The new servers listen before any current one stops, if one of them fails, they are stopped and nothing changes.
+// But a new server taking the address of a removed one can only listen after the removed one stops,
+// so it's skipped with an error logged if it fails.
+func (e *Entrypoint) reloadServers(ctx context.Context, cancel context.CancelFunc, next []server.Server) ([]server.Server, error) {
+	logger := logs.From(ctx)
+
+	key := func(s server.Server) string {
+		return fmt.Sprintf("%T %s", s, s.Addr())
+	}
+	nextKeys := map[string]bool{}
+	for _, s := range next {
+		if s.Addr() != "" {
+			nextKeys[key(s)] = true
+		}
+	}
+
+	current := map[string]server.Server{}
+	var removed []server.Server
+	removedAddrs := map[string]bool{}
+	for _, s := range e.Servers {
+		if s.Addr() == "" {
+			continue
+		}
+		if !nextKeys[key(s)] {
+			removed = append(removed, s)
+			removedAddrs[s.Addr()] = true
+			continue
+		}
+		current[key(s)] = s
 	}
-	if err := e.HttpServer.Shutdown(ctx); err != nil {
-		logger.Warnf("shutdown http server: %v", err)
+
+	listening := map[server.Server]bool{}
+	var waiting []server.Server // the ones taking the addresses of the removed ones
+	for _, s := range next {
+		if _, ok := current[key(s)]; ok || s.Addr() == "" {
+			continue
+		}
+		if removedAddrs[s.Addr()] {
+			waiting = append(waiting, s)
+			continue
+		}
+		if err := s.Listen(); err != nil {
+			for v := range listening {
+				_ = v.Shutdown(ctx)
+			}
+			return nil, fmt.Errorf("listen on %s: %w", s.Addr(), err)
+		}
+		listening[s] = true
+	}
+
+	for _, s := range removed {
+		if err := s.Shutdown(ctx); err != nil {
+			logger.Warnf("shutdown server: %v", err)
+		}
 	}
-	if err := e.FtpServer.Shutdown(ctx); err != nil {
-		logger.Warnf("shutdown ftp server: %v", err)
+	for _, s := range waiting {
+		if err := s.Listen(); err != nil {
+			logger.Errorf("listen on %s: %v", s.Addr(), err)
+			continue
+		}
+		listening[s] = true
+	}
+
+	var ret []server.Server
+	for _, s := range next {
+		if c, ok := current[key(s)]; ok && s.Addr() != "" {
+			if r, ok := c.(server.Reloadable); ok {
+				r.Reload(ctx, s)
+			}
+			ret = append(ret, c)
+			continue
+		}
+		if listening[s] {
+			s.Startup(ctx, cancel)
+			ret = append(ret, s)
+		}
 	}
+	return ret, nil
 }
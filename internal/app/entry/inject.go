@@ -7,7 +7,9 @@ import (
 	"github.com/funeypot/funeypot/internal/app/config"
 	"github.com/funeypot/funeypot/internal/app/dashboard"
 	"github.com/funeypot/funeypot/internal/app/model"
+	"github.com/funeypot/funeypot/internal/app/retention"
 	"github.com/funeypot/funeypot/internal/app/server"
+	"github.com/funeypot/funeypot/internal/app/sink"
 	"github.com/funeypot/funeypot/internal/pkg/abuseipdb"
 	"github.com/funeypot/funeypot/internal/pkg/ipgeo"
 
@@ -16,32 +18,101 @@ import (
 
 var providerSet = wire.NewSet(
 	newEntrypoint,
+	serverSet,
 	wire.FieldsOf(new(*config.Config),
 		"Database",
 		"Abuseipdb",
 		"Dashboard",
-		"Ssh",
-		"Http",
-		"Ftp",
+		"Retention",
+		"Aggregation",
+		"Campaign",
 	),
+	sinkSet,
 	model.NewDatabase,
 	newAbuseipdbClient,
 	dashboard.NewServer,
 	server.NewHandler,
-	server.NewSshServer,
-	server.NewHttpServer,
-	server.NewFtpServer,
+	retention.NewRunner,
 	newCachedIpGeoQuerier,
 )
 
+// serverSet builds the servers from the config, with the shared handler and dashboard.
+var serverSet = wire.NewSet(
+	wire.FieldsOf(new(*config.Config),
+		"Ssh",
+		"Http",
+		"Ftp",
+		"Redis",
+		"Rdp",
+		"Vnc",
+		"Tcp",
+	),
+	server.NewSshServers,
+	server.NewHttpServers,
+	server.NewFtpServers,
+	server.NewRedisServer,
+	server.NewRdpServer,
+	server.NewVncServer,
+	server.NewTcpServers,
+	collectServers,
+)
+
+// sinkSet builds the sinks from the config.
+var sinkSet = wire.NewSet(
+	wire.FieldsOf(new(*config.Config),
+		"Syslog",
+		"Chat",
+	),
+	sink.NewSyslog,
+	sink.NewChat,
+	sink.NewSinks,
+)
+
+// inspector is the dependencies of the commands inspecting the data, without the servers.
+type inspector struct {
+	Db           *model.Database
+	IpGeoQuerier ipgeo.Querier
+}
+
+func (i *inspector) Close() error {
+	return i.Db.Close()
+}
+
 // to suppress "unused" error
 var _ = providerSet
 
+func collectServers(
+	sshServers []*server.SshServer,
+	httpServers []*server.HttpServer,
+	ftpServers []*server.FtpServer,
+	redisServer *server.RedisServer,
+	rdpServer *server.RdpServer,
+	vncServer *server.VncServer,
+	tcpServers []*server.TcpServer,
+) []server.Server {
+	var servers []server.Server
+	servers = appendServers(servers, sshServers...)
+	servers = appendServers(servers, httpServers...)
+	servers = appendServers(servers, ftpServers...)
+	// a disabled server is a nil pointer, it's kept so it can log that it's skipped
+	servers = append(servers, redisServer, rdpServer, vncServer)
+	servers = appendServers(servers, tcpServers...)
+	return servers
+}
+
+// appendServers appends the servers of a concrete type, since a []*XServer can't be converted to []Server directly.
+func appendServers[S server.Server](servers []server.Server, s ...S) []server.Server {
+	for _, v := range s {
+		servers = append(servers, v)
+	}
+	return servers
+}
+
 func newAbuseipdbClient(cfg config.Abuseipdb) *abuseipdb.Client {
 	if !cfg.Enabled {
 		return nil
 	}
-	return abuseipdb.NewClient(cfg.Key, cfg.Interval)
+	return abuseipdb.NewClient(cfg.BaseUrl, cfg.Key, cfg.Interval)
 }
 
 func newCachedIpGeoQuerier(db *model.Database) ipgeo.Querier {
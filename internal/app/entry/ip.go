@@ -0,0 +1,135 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package entry
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"text/tabwriter"
+	"time"
+
+	"github.com/funeypot/funeypot/internal/app/config"
+	"github.com/funeypot/funeypot/internal/pkg/logs"
+)
+
+const ipUsage = `Usage: funeypot ip [-c config.yaml] [-limit N] <command>
+
+Commands:
+  show <ip>       show the attempts, the geolocation and the reports of the ip
+
+Options:
+`
+
+func runIp(ctx context.Context, args []string, stdout io.Writer) error {
+	set := flag.NewFlagSet("funeypot ip", flag.ContinueOnError)
+	set.Usage = func() {
+		_, _ = fmt.Fprint(set.Output(), ipUsage)
+		set.PrintDefaults()
+	}
+	configFile := set.String("c", "config.yaml", "config file")
+	limit := set.Int("limit", 20, "the max count of the attempts and the reports")
+	if err := set.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if set.NArg() == 0 {
+		set.Usage()
+		return fmt.Errorf("missing command")
+	}
+	if command := set.Arg(0); command != "show" {
+		set.Usage()
+		return fmt.Errorf("unknown command %q", command)
+	}
+	switch {
+	case set.NArg() < 2:
+		set.Usage()
+		return fmt.Errorf("missing ip")
+	case set.NArg() > 2:
+		set.Usage()
+		return fmt.Errorf("too many arguments")
+	case net.ParseIP(set.Arg(1)) == nil:
+		set.Usage()
+		return fmt.Errorf("invalid ip %q", set.Arg(1))
+	}
+	ip := net.ParseIP(set.Arg(1)).String()
+
+	logger := logs.Default()
+
+	cfg, err := config.Load(*configFile, false)
+	if err != nil {
+		logger.Errorf("load config: %v", err)
+		return err
+	}
+	ctx = logs.With(ctx, logs.SetLevel(cfg.Log.Level))
+
+	inspector, err := newInspector(ctx, cfg)
+	if err != nil {
+		logger.Errorf("new inspector: %v", err)
+		return err
+	}
+	defer inspector.Close() //nolint:errcheck
+
+	attempts, err := inspector.Db.ListBruteAttempts(ctx, ip, *limit)
+	if err != nil {
+		logger.Errorf("list attempts: %v", err)
+		return err
+	}
+	reports, err := inspector.Db.ListAbuseipdbReports(ctx, ip, *limit)
+	if err != nil {
+		logger.Errorf("list reports: %v", err)
+		return err
+	}
+
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintf(w, "IP:\t%s\n", ip)
+	// the geolocation is not necessary, show the others even if it fails
+	if geo, err := inspector.IpGeoQuerier.Query(ctx, ip); err != nil {
+		logger.Warnf("query ip geo: %v", err)
+		_, _ = fmt.Fprintf(w, "LOCATION:\tunknown\n")
+	} else {
+		_, _ = fmt.Fprintf(w, "LOCATION:\t%s\n", geo.Location)
+		_, _ = fmt.Fprintf(w, "COUNTRY:\t%s\n", geo.CountryCode)
+		_, _ = fmt.Fprintf(w, "ASN:\tAS%d\n", geo.Asn)
+	}
+	// the cached one only, it doesn't call the API
+	if reputation, ok, err := inspector.Db.TakeIpReputation(ctx, ip); err != nil {
+		logger.Warnf("get ip reputation: %v", err)
+	} else if ok {
+		_, _ = fmt.Fprintf(w, "ABUSE SCORE:\t%d\n", reputation.Score)
+		_, _ = fmt.Fprintf(w, "USAGE TYPE:\t%s\n", reputation.UsageType)
+		_, _ = fmt.Fprintf(w, "ISP:\t%s\n", reputation.Isp)
+		_, _ = fmt.Fprintf(w, "TOTAL REPORTS:\t%d\n", reputation.TotalReports)
+		_, _ = fmt.Fprintf(w, "BLACKLISTED:\t%t\n", reputation.Blacklisted())
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintf(stdout, "\nATTEMPTS (%d):\n", len(attempts))
+	w = tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "KIND\tCOUNT\tSTARTED AT\tSTOPPED AT\tUSER\tPASSWORD\tCLIENT")
+	for _, v := range attempts {
+		_, _ = fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%q\t%q\t%q\n",
+			v.Kind, v.Count,
+			v.StartedAt.Format(time.RFC3339), v.StoppedAt.Format(time.RFC3339),
+			v.User, v.Password, v.ShortClientVersion())
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintf(stdout, "\nREPORTS (%d):\n", len(reports))
+	w = tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "REPORTED AT\tSCORE")
+	for _, v := range reports {
+		_, _ = fmt.Fprintf(w, "%s\t%d\n", v.ReportedAt.Format(time.RFC3339), v.Score)
+	}
+	return w.Flush()
+}
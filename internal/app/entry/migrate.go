@@ -0,0 +1,108 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package entry
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/funeypot/funeypot/internal/app/config"
+	"github.com/funeypot/funeypot/internal/app/model"
+	"github.com/funeypot/funeypot/internal/pkg/logs"
+)
+
+const migrateUsage = `Usage: funeypot migrate [-c config.yaml] <command>
+
+Commands:
+  status          show the applied and pending migrations
+  up [-to N]      apply the pending migrations, up to version N if specified
+`
+
+// runMigrate runs the migrate subcommand, it's useful when the migration of the database is "manual".
+func runMigrate(ctx context.Context, args []string, stdout io.Writer) error {
+	set := flag.NewFlagSet("funeypot migrate", flag.ContinueOnError)
+	set.Usage = func() {
+		_, _ = fmt.Fprint(set.Output(), migrateUsage)
+	}
+	configFile := set.String("c", "config.yaml", "config file")
+	if err := set.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if set.NArg() == 0 {
+		set.Usage()
+		return fmt.Errorf("missing command")
+	}
+
+	logger := logs.Default()
+
+	cfg, err := config.Load(*configFile, false)
+	if err != nil {
+		logger.Errorf("load config: %v", err)
+		return err
+	}
+	ctx = logs.With(ctx, logs.SetLevel(cfg.Log.Level))
+
+	db, err := model.OpenDatabase(cfg.Database)
+	if err != nil {
+		logger.Errorf("open database: %v", err)
+		return err
+	}
+	defer db.Close() //nolint:errcheck
+
+	switch command, commandArgs := set.Arg(0), set.Args()[1:]; command {
+	case "status":
+		return migrateStatus(ctx, db, stdout)
+	case "up":
+		upSet := flag.NewFlagSet("funeypot migrate up", flag.ContinueOnError)
+		target := upSet.Int("to", 0, "the version to migrate to, 0 means the latest")
+		if err := upSet.Parse(commandArgs); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil
+			}
+			return err
+		}
+		applied, err := db.Migrate(ctx, *target)
+		for _, m := range applied {
+			_, _ = fmt.Fprintf(stdout, "applied %d %s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			logger.Errorf("migrate: %v", err)
+			return err
+		}
+		if len(applied) == 0 {
+			_, _ = fmt.Fprintln(stdout, "no pending migrations")
+		}
+		return nil
+	default:
+		set.Usage()
+		return fmt.Errorf("unknown command %q", command)
+	}
+}
+
+func migrateStatus(ctx context.Context, db *model.Database, stdout io.Writer) error {
+	states, err := db.MigrationStates(ctx)
+	if err != nil {
+		logs.From(ctx).Errorf("get migration states: %v", err)
+		return err
+	}
+
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+	for _, v := range states {
+		appliedAt := "pending"
+		if v.Applied() {
+			appliedAt = v.AppliedAt.Format(time.RFC3339)
+		}
+		_, _ = fmt.Fprintf(w, "%d\t%s\t%s\n", v.Version, v.Name, appliedAt)
+	}
+	return w.Flush()
+}
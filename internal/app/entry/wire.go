@@ -9,6 +9,9 @@ import (
 	"context"
 
 	"github.com/funeypot/funeypot/internal/app/config"
+	"github.com/funeypot/funeypot/internal/app/dashboard"
+	"github.com/funeypot/funeypot/internal/app/server"
+	"github.com/funeypot/funeypot/internal/app/sink"
 
 	"github.com/google/wire"
 )
@@ -18,3 +21,15 @@ import (
 func NewEntrypoint(ctx context.Context, cfg *config.Config) (*Entrypoint, error) {
 	panic(wire.Build(providerSet))
 }
+
+func newServers(cfg *config.Config, handler *server.Handler, dashboardServer *dashboard.Server) ([]server.Server, error) {
+	panic(wire.Build(serverSet))
+}
+
+func newSinks(ctx context.Context, cfg *config.Config) (sink.Sinks, error) {
+	panic(wire.Build(sinkSet))
+}
+
+func newInspector(ctx context.Context, cfg *config.Config) (*inspector, error) {
+	panic(wire.Build(providerSet, wire.Struct(new(inspector), "*")))
+}
@@ -11,23 +11,41 @@ import (
 	"github.com/funeypot/funeypot/internal/app/config"
 	"github.com/funeypot/funeypot/internal/app/dashboard"
 	"github.com/funeypot/funeypot/internal/app/model"
+	"github.com/funeypot/funeypot/internal/app/retention"
 	"github.com/funeypot/funeypot/internal/app/server"
+	"github.com/funeypot/funeypot/internal/app/sink"
 )
 
 // Injectors from wire.go:
 
 func NewEntrypoint(ctx context.Context, cfg *config.Config) (*Entrypoint, error) {
 	ssh := cfg.Ssh
+	aggregation := cfg.Aggregation
+	abuseipdb := cfg.Abuseipdb
+	campaign := cfg.Campaign
 	database := cfg.Database
 	modelDatabase, err := model.NewDatabase(ctx, database)
 	if err != nil {
 		return nil, err
 	}
 	querier := newCachedIpGeoQuerier(modelDatabase)
-	abuseipdb := cfg.Abuseipdb
 	client := newAbuseipdbClient(abuseipdb)
-	handler := server.NewHandler(ctx, modelDatabase, querier, client)
-	sshServer, err := server.NewSshServer(ssh, handler)
+	syslog := cfg.Syslog
+	sinkSyslog, err := sink.NewSyslog(ctx, syslog)
+	if err != nil {
+		return nil, err
+	}
+	chat := cfg.Chat
+	sinkChat, err := sink.NewChat(ctx, chat)
+	if err != nil {
+		return nil, err
+	}
+	sinks := sink.NewSinks(sinkSyslog, sinkChat)
+	handler, err := server.NewHandler(ctx, aggregation, abuseipdb, campaign, modelDatabase, querier, client, sinks)
+	if err != nil {
+		return nil, err
+	}
+	v, err := server.NewSshServers(ssh, handler)
 	if err != nil {
 		return nil, err
 	}
@@ -37,9 +55,83 @@ func NewEntrypoint(ctx context.Context, cfg *config.Config) (*Entrypoint, error)
 	if err != nil {
 		return nil, err
 	}
-	httpServer := server.NewHttpServer(http, handler, dashboardServer)
+	v2 := server.NewHttpServers(http, handler, dashboardServer)
 	ftp := cfg.Ftp
-	ftpServer := server.NewFtpServer(ftp, handler)
-	entrypoint := newEntrypoint(sshServer, httpServer, ftpServer)
+	v3 := server.NewFtpServers(ftp, handler)
+	redis := cfg.Redis
+	redisServer := server.NewRedisServer(redis, handler)
+	rdp := cfg.Rdp
+	rdpServer, err := server.NewRdpServer(rdp, handler)
+	if err != nil {
+		return nil, err
+	}
+	vnc := cfg.Vnc
+	vncServer, err := server.NewVncServer(vnc, handler)
+	if err != nil {
+		return nil, err
+	}
+	v4 := cfg.Tcp
+	v5 := server.NewTcpServers(v4, handler)
+	v6 := collectServers(v, v2, v3, redisServer, rdpServer, vncServer, v5)
+	configRetention := cfg.Retention
+	runner := retention.NewRunner(configRetention, modelDatabase)
+	entrypoint := newEntrypoint(cfg, v6, runner, handler, dashboardServer, client, sinks)
 	return entrypoint, nil
 }
+
+func newServers(cfg *config.Config, handler *server.Handler, dashboardServer *dashboard.Server) ([]server.Server, error) {
+	ssh := cfg.Ssh
+	v, err := server.NewSshServers(ssh, handler)
+	if err != nil {
+		return nil, err
+	}
+	http := cfg.Http
+	v2 := server.NewHttpServers(http, handler, dashboardServer)
+	ftp := cfg.Ftp
+	v3 := server.NewFtpServers(ftp, handler)
+	redis := cfg.Redis
+	redisServer := server.NewRedisServer(redis, handler)
+	rdp := cfg.Rdp
+	rdpServer, err := server.NewRdpServer(rdp, handler)
+	if err != nil {
+		return nil, err
+	}
+	vnc := cfg.Vnc
+	vncServer, err := server.NewVncServer(vnc, handler)
+	if err != nil {
+		return nil, err
+	}
+	v4 := cfg.Tcp
+	v5 := server.NewTcpServers(v4, handler)
+	v6 := collectServers(v, v2, v3, redisServer, rdpServer, vncServer, v5)
+	return v6, nil
+}
+
+func newSinks(ctx context.Context, cfg *config.Config) (sink.Sinks, error) {
+	syslog := cfg.Syslog
+	sinkSyslog, err := sink.NewSyslog(ctx, syslog)
+	if err != nil {
+		return nil, err
+	}
+	chat := cfg.Chat
+	sinkChat, err := sink.NewChat(ctx, chat)
+	if err != nil {
+		return nil, err
+	}
+	sinks := sink.NewSinks(sinkSyslog, sinkChat)
+	return sinks, nil
+}
+
+func newInspector(ctx context.Context, cfg *config.Config) (*inspector, error) {
+	database := cfg.Database
+	modelDatabase, err := model.NewDatabase(ctx, database)
+	if err != nil {
+		return nil, err
+	}
+	querier := newCachedIpGeoQuerier(modelDatabase)
+	entryInspector := &inspector{
+		Db:           modelDatabase,
+		IpGeoQuerier: querier,
+	}
+	return entryInspector, nil
+}
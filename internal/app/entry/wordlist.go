@@ -0,0 +1,167 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package entry
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/funeypot/funeypot/internal/app/config"
+	"github.com/funeypot/funeypot/internal/app/model"
+	"github.com/funeypot/funeypot/internal/pkg/logs"
+)
+
+const wordlistUsage = `Usage: funeypot wordlist [-c config.yaml] [options]
+
+Export the usernames, passwords or user:password combos of the attempts, in descending order of frequency.
+The times of -after and -before are like "2024-03-01", "2024-03-01T08:00:00Z", or "168h" for 168 hours ago.
+
+Options:
+`
+
+// runWordlist runs the wordlist subcommand, it writes the list to the output file, or stdout if it's not specified.
+func runWordlist(ctx context.Context, args []string, stdout io.Writer) error {
+	set := flag.NewFlagSet("funeypot wordlist", flag.ContinueOnError)
+	set.Usage = func() {
+		_, _ = fmt.Fprint(set.Output(), wordlistUsage)
+		set.PrintDefaults()
+	}
+	configFile := set.String("c", "config.yaml", "config file")
+	typ := set.String("type", "password", `the type of the list, "user", "password" or "combo"`)
+	kind := set.String("kind", "", `the kind of the attempts, like "ssh", all kinds if empty`)
+	after := set.String("after", "", "only the attempts after the time")
+	before := set.String("before", "", "only the attempts before the time")
+	minCount := set.Int64("min-count", 1, "only the words attempted at least the times")
+	limit := set.Int("limit", 0, "the max count of the words, 0 means no limit")
+	separator := set.String("separator", ":", "the separator of the user and password of combos")
+	output := set.String("o", "", "the output file, stdout if empty")
+	if err := set.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if set.NArg() > 0 {
+		set.Usage()
+		return fmt.Errorf("unexpected arguments %q", set.Args())
+	}
+
+	var group model.CredentialGroup
+	switch *typ {
+	case "user":
+		group = model.CredentialGroupUser
+	case "password":
+		group = model.CredentialGroupPassword
+	case "combo":
+		group = model.CredentialGroupPair
+	default:
+		return fmt.Errorf("invalid type %q", *typ)
+	}
+	filter := model.CredentialFilter{
+		MinAttempts: *minCount,
+	}
+	if *kind != "" {
+		k, ok := model.ParseBruteAttemptKind(*kind)
+		if !ok {
+			return fmt.Errorf("invalid kind %q", *kind)
+		}
+		filter.Kind = k
+	}
+	now := time.Now()
+	for _, v := range []struct {
+		name  string
+		value string
+		time  *time.Time
+	}{
+		{"after", *after, &filter.After},
+		{"before", *before, &filter.Before},
+	} {
+		if v.value == "" {
+			continue
+		}
+		t, err := parseTimeFlag(v.value, now)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", v.name, err)
+		}
+		*v.time = t
+	}
+
+	logger := logs.Default()
+
+	cfg, err := config.Load(*configFile, false)
+	if err != nil {
+		logger.Errorf("load config: %v", err)
+		return err
+	}
+	ctx = logs.With(ctx, logs.SetLevel(cfg.Log.Level))
+
+	db, err := model.OpenDatabase(cfg.Database)
+	if err != nil {
+		logger.Errorf("open database: %v", err)
+		return err
+	}
+	defer db.Close() //nolint:errcheck
+
+	counts, err := db.TopCredentials(ctx, group, filter, *limit)
+	if err != nil {
+		logger.Errorf("top credentials: %v", err)
+		return err
+	}
+
+	out := stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close() //nolint:errcheck
+		out = f
+	}
+	w := bufio.NewWriter(out)
+	words := 0
+	for _, v := range counts {
+		word := ""
+		switch group {
+		case model.CredentialGroupUser:
+			word = v.User
+		case model.CredentialGroupPassword:
+			word = v.Password
+		case model.CredentialGroupPair:
+			word = v.User + *separator + v.Password
+		}
+		if word == "" {
+			// an empty line is useless in a wordlist
+			continue
+		}
+		_, _ = fmt.Fprintln(w, word)
+		words++
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if *output != "" {
+		logger.Infof("exported %d words to %s", words, *output)
+	}
+	return nil
+}
+
+// parseTimeFlag parses the time like "2024-03-01", "2024-03-01T08:00:00Z", or the duration before now like "168h".
+func parseTimeFlag(s string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation(time.DateOnly, s, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("%q is not a time or a duration", s)
+}
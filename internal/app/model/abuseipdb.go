@@ -9,17 +9,21 @@ import (
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 func init() {
 	registerModel(new(AbuseipdbReport))
+	registerModel(new(AbuseipdbPending))
 }
 
 type AbuseipdbReport struct {
 	Id         int64
 	Ip         string `gorm:"size:39"`
 	ReportedAt time.Time
-	Score      int
+	// Score is the abuse confidence score after reported, 0 means unknown.
+	// The bulk api returns no scores, so a report in bulk keeps the score of the previous one.
+	Score int
 
 	CreatedAt time.Time `gorm:"<-:create"`
 	UpdatedAt time.Time
@@ -36,3 +40,100 @@ func (db *Database) LastAbuseipdbReport(ctx context.Context, ip string) (*Abusei
 	}
 	return report, true, nil
 }
+
+// ListAbuseipdbReports returns the latest reports of the ip, in descending order of the reported time.
+func (db *Database) ListAbuseipdbReports(ctx context.Context, ip string, limit int) ([]*AbuseipdbReport, error) {
+	var ret []*AbuseipdbReport
+	err := db.withContext(ctx).
+		Where("ip = ?", ip).
+		Order("reported_at DESC").
+		Limit(limit).
+		Find(&ret).
+		Error
+	return ret, err
+}
+
+// CountAbuseipdbReports returns the count of the reports since the time.
+func (db *Database) CountAbuseipdbReports(ctx context.Context, since time.Time) (int64, error) {
+	var ret int64
+	err := db.withContext(ctx).
+		Model(&AbuseipdbReport{}).
+		Where("reported_at >= ?", since).
+		Count(&ret).
+		Error
+	return ret, err
+}
+
+// AbuseipdbPending is a report waiting to be sent, since it failed or it was limited.
+// There is at most one of an ip, it's replaced by the latest attempt of the ip.
+type AbuseipdbPending struct {
+	Id          int64
+	Ip          string `gorm:"size:39;uniqueIndex"`
+	Kind        BruteAttemptKind
+	Categories  string    `gorm:"size:255"` // the ids joined by ","
+	Comment     string    `gorm:"size:1024"`
+	AttemptedAt time.Time // the time of the last attempt
+	Attempts    int64     // the count of the attempts, the more active ips are reported first
+	Retries     int
+	NextAt      time.Time `gorm:"index"`
+	LastError   string    `gorm:"size:255"`
+
+	CreatedAt time.Time `gorm:"<-:create"`
+	UpdatedAt time.Time
+}
+
+func (p *AbuseipdbPending) BeforeSave(_ *gorm.DB) error {
+	p.LastError = truncateString(p.LastError, 255)
+	return nil
+}
+
+// SaveAbuseipdbPending inserts the pending report, or replaces the one of the same ip.
+func (db *Database) SaveAbuseipdbPending(ctx context.Context, pending *AbuseipdbPending) error {
+	return db.withContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "ip"}},
+			DoUpdates: clause.AssignmentColumns([]string{
+				"kind", "categories", "comment", "attempted_at", "attempts", "retries", "next_at", "last_error", "updated_at",
+			}),
+		}).
+		Create(pending).
+		Error
+}
+
+// GetAbuseipdbPending returns the pending report of the ip.
+func (db *Database) GetAbuseipdbPending(ctx context.Context, ip string) (*AbuseipdbPending, bool, error) {
+	ret := &AbuseipdbPending{}
+	result := db.withContext(ctx).Where("ip = ?", ip).Limit(1).Find(ret)
+	if result.Error != nil {
+		return nil, false, result.Error
+	}
+	return ret, result.RowsAffected > 0, nil
+}
+
+// ListDueAbuseipdbPendings returns the pending reports to send at the time, the more active ips first.
+func (db *Database) ListDueAbuseipdbPendings(ctx context.Context, now time.Time, limit int) ([]*AbuseipdbPending, error) {
+	var ret []*AbuseipdbPending
+	err := db.withContext(ctx).
+		Where("next_at <= ?", now).
+		Order("attempts DESC").
+		Order("id").
+		Limit(limit).
+		Find(&ret).
+		Error
+	return ret, err
+}
+
+// DeleteAbuseipdbPending deletes the pending report of the id.
+func (db *Database) DeleteAbuseipdbPending(ctx context.Context, id int64) error {
+	return db.withContext(ctx).
+		Delete(&AbuseipdbPending{}, id).
+		Error
+}
+
+// DeleteAbuseipdbPendingsBefore deletes the pending reports of the attempts before the time, and returns the count.
+func (db *Database) DeleteAbuseipdbPendingsBefore(ctx context.Context, before time.Time) (int64, error) {
+	result := db.withContext(ctx).
+		Where("attempted_at < ?", before).
+		Delete(&AbuseipdbPending{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,64 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package model
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDatabase_AbuseipdbPending(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDatabase(t)
+
+	now := time.Now().Truncate(time.Second)
+	save := func(ip string, attempts int64, attemptedAt, nextAt time.Time) {
+		require.NoError(t, db.SaveAbuseipdbPending(ctx, &AbuseipdbPending{
+			Ip:          ip,
+			Kind:        BruteAttemptKindSsh,
+			Categories:  "18,22",
+			Comment:     "comment",
+			AttemptedAt: attemptedAt,
+			Attempts:    attempts,
+			NextAt:      nextAt,
+		}))
+	}
+
+	save("10.0.0.1", 5, now, now.Add(-time.Minute))
+	save("10.0.0.2", 10, now, now.Add(-time.Minute))
+	save("10.0.0.3", 20, now, now.Add(time.Minute))
+	save("10.0.0.4", 1, now.Add(-2*time.Hour), now.Add(-time.Minute))
+
+	// coalesced with the same ip
+	save("10.0.0.1", 30, now, now.Add(-time.Minute))
+	pending, ok, err := db.GetAbuseipdbPending(ctx, "10.0.0.1")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, int64(30), pending.Attempts)
+
+	pendings, err := db.ListDueAbuseipdbPendings(ctx, now, 10)
+	require.NoError(t, err)
+	var ips []string
+	for _, v := range pendings {
+		ips = append(ips, v.Ip)
+	}
+	assert.Equal(t, []string{"10.0.0.1", "10.0.0.2", "10.0.0.4"}, ips, "due ones, more attempts first")
+
+	pendings, err = db.ListDueAbuseipdbPendings(ctx, now, 1)
+	require.NoError(t, err)
+	assert.Len(t, pendings, 1)
+
+	n, err := db.DeleteAbuseipdbPendingsBefore(ctx, now.Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	require.NoError(t, db.DeleteAbuseipdbPending(ctx, pending.Id))
+	_, ok, err = db.GetAbuseipdbPending(ctx, "10.0.0.1")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
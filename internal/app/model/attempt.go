@@ -6,6 +6,7 @@ package model
 import (
 	"context"
 	"errors"
+	"fmt"
 	"strings"
 	"time"
 
@@ -21,19 +22,34 @@ func init() {
 type BruteAttemptKind int
 
 const (
-	_                    BruteAttemptKind = iota // none
-	BruteAttemptKindSsh                          // ssh
-	BruteAttemptKindHttp                         // http
-	BruteAttemptKindFtp                          // ftp
+	_                     BruteAttemptKind = iota // none
+	BruteAttemptKindSsh                           // ssh
+	BruteAttemptKindHttp                          // http
+	BruteAttemptKindFtp                           // ftp
+	BruteAttemptKindRedis                         // redis
+	BruteAttemptKindRdp                           // rdp
+	BruteAttemptKindVnc                           // vnc
 )
 
+// ParseBruteAttemptKind returns the kind of the name, like "ssh".
+func ParseBruteAttemptKind(name string) (BruteAttemptKind, bool) {
+	for i := BruteAttemptKind(1); i < BruteAttemptKind(len(_BruteAttemptKind_index)); i++ {
+		if i.String() == name {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
 type BruteAttempt struct {
 	Id            int64
-	Ip            string           `gorm:"size:39,index:ip_kind"` // max ipv6 length
+	Ip            string           `gorm:"size:39;index:ip_kind"` // max ipv6 length
 	Kind          BruteAttemptKind `gorm:"index:ip_kind"`
+	Seq           int64            // the sequence of the windows of the ip and kind, unique with them, see migration 3
 	User          string           `gorm:"size:255"`
 	Password      string           `gorm:"size:255"`
 	ClientVersion string           `gorm:"size:255"`
+	LocalPort     int              // the port of the last attempt
 	StartedAt     time.Time
 	StoppedAt     time.Time
 	Count         int64
@@ -73,24 +89,73 @@ func (db *Database) IncrBruteAttempt(
 	kind BruteAttemptKind,
 	timestamp time.Time,
 	user, password, clientVersion string,
-	after time.Time,
+	localPort int,
+	window AttemptWindow,
+) (*BruteAttempt, error) {
+	unlock := db.attemptLocks.Lock(fmt.Sprintf("%s/%d", ip, kind))
+	defer unlock()
+
+	var err error
+	for i := 0; i < maxIncrBruteAttemptTries; i++ {
+		var attempt *BruteAttempt
+		attempt, err = db.incrBruteAttempt(ctx, ip, kind, timestamp, user, password, clientVersion, localPort, window)
+		if !errors.Is(err, gorm.ErrDuplicatedKey) {
+			return attempt, err
+		}
+		// another process sharing the database has opened the window, try again to increase it
+	}
+	return nil, err
+}
+
+const maxIncrBruteAttemptTries = 3
+
+// AttemptWindow decides whether an attempt belongs to the current row of its ip and kind, or starts a new one.
+// The zero fields are ignored.
+type AttemptWindow struct {
+	Gap    time.Duration // the attempt is within the gap since the last attempt of the row
+	Length time.Duration // the attempt is within the length since the first attempt of the row, so it's a fixed window
+}
+
+func (w AttemptWindow) contains(attempt *BruteAttempt, timestamp time.Time) bool {
+	if w.Gap > 0 && timestamp.Sub(attempt.StoppedAt) >= w.Gap {
+		return false
+	}
+	if w.Length > 0 && timestamp.Sub(attempt.StartedAt) >= w.Length {
+		return false
+	}
+	return true
+}
+
+func (db *Database) incrBruteAttempt(
+	ctx context.Context,
+	ip string,
+	kind BruteAttemptKind,
+	timestamp time.Time,
+	user, password, clientVersion string,
+	localPort int,
+	window AttemptWindow,
 ) (*BruteAttempt, error) {
 	attempt := &BruteAttempt{}
 	return attempt, db.withContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// the locking doesn't help if there is no row yet, then the unique key of ip, kind and seq does
 		result := tx.
 			Clauses(clause.Locking{Strength: "UPDATE"}).
 			Where("ip = ? AND kind = ?", ip, kind).
-			Last(attempt)
-		if err := result.Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			Order("seq DESC").
+			Limit(1).
+			Find(attempt)
+		if err := result.Error; err != nil {
 			return err
 		}
-		if errors.Is(result.Error, gorm.ErrRecordNotFound) || !attempt.StoppedAt.After(after) {
+		if result.RowsAffected == 0 || !window.contains(attempt, timestamp) {
 			attempt = &BruteAttempt{
 				Ip:            ip,
 				Kind:          kind,
+				Seq:           attempt.Seq + 1,
 				User:          user,
 				Password:      password,
 				ClientVersion: clientVersion,
+				LocalPort:     localPort,
 				StartedAt:     timestamp,
 				StoppedAt:     timestamp,
 				Count:         1,
@@ -101,20 +166,62 @@ func (db *Database) IncrBruteAttempt(
 		attempt.User = user
 		attempt.Password = password
 		attempt.ClientVersion = clientVersion
+		attempt.LocalPort = localPort
 		attempt.StoppedAt = timestamp
-		attempt.Count++
-		return tx.Select("user", "password", "client_version", "stopped_at", "count").
-			Updates(attempt).Error
+		_ = attempt.BeforeSave(tx)
+		if err := tx.Model(attempt).Updates(map[string]any{
+			"user":           attempt.User,
+			"password":       attempt.Password,
+			"client_version": attempt.ClientVersion,
+			"local_port":     attempt.LocalPort,
+			"stopped_at":     attempt.StoppedAt,
+			"count":          gorm.Expr("count + ?", 1), // the read count could be stale if the database doesn't lock it
+		}).Error; err != nil {
+			return err
+		}
+		return tx.First(attempt, attempt.Id).Error
 	})
 }
 
-func (db *Database) ScanBruteAttempt(ctx context.Context, updatedAfter time.Time, f func(attempt *BruteAttempt, geo *IpGeo) bool) error {
+// ListBruteAttempts returns the latest attempts of the ip, in descending order of the stopped time.
+func (db *Database) ListBruteAttempts(ctx context.Context, ip string, limit int) ([]*BruteAttempt, error) {
+	var ret []*BruteAttempt
+	err := db.withContext(ctx).
+		Where("ip = ?", ip).
+		Order("stopped_at DESC").
+		Limit(limit).
+		Find(&ret).
+		Error
+	return ret, err
+}
+
+// ScanBruteAttempt scans the attempts updated after the time.
+// The kind of the filter applies to the attempts, the local port, remote port and variant apply to their records,
+// and the other fields are ignored.
+func (db *Database) ScanBruteAttempt(
+	ctx context.Context,
+	updatedAfter time.Time,
+	filter AttemptRecordFilter,
+	f func(attempt *BruteAttempt, geo *IpGeo) bool,
+) error {
 	sess := db.withContext(ctx)
-	rows, err := sess.
+	tx := sess.
 		Model(&BruteAttempt{}).
 		Select("brute_attempts.*, ip_geos.*").
 		Joins("LEFT JOIN ip_geos ON brute_attempts.ip = ip_geos.ip").
-		Where("brute_attempts.updated_at > ?", updatedAfter).
+		Where("brute_attempts.updated_at > ?", updatedAfter)
+	if filter.Kind != 0 {
+		tx = tx.Where("brute_attempts.kind = ?", filter.Kind)
+	}
+	if recordFilter := (AttemptRecordFilter{
+		LocalPort:  filter.LocalPort,
+		RemotePort: filter.RemotePort,
+		Variant:    filter.Variant,
+	}); !recordFilter.empty() {
+		tx = tx.Where("brute_attempts.id IN (?)",
+			recordFilter.apply(sess.Model(&AttemptRecord{}).Select("attempt_id"), "attempt_records"))
+	}
+	rows, err := tx.
 		Order("brute_attempts.updated_at").
 		Rows()
 	if err != nil {
@@ -11,11 +11,14 @@ func _() {
 	_ = x[BruteAttemptKindSsh-1]
 	_ = x[BruteAttemptKindHttp-2]
 	_ = x[BruteAttemptKindFtp-3]
+	_ = x[BruteAttemptKindRedis-4]
+	_ = x[BruteAttemptKindRdp-5]
+	_ = x[BruteAttemptKindVnc-6]
 }
 
-const _BruteAttemptKind_name = "sshhttpftp"
+const _BruteAttemptKind_name = "sshhttpftpredisrdpvnc"
 
-var _BruteAttemptKind_index = [...]uint8{0, 3, 7, 10}
+var _BruteAttemptKind_index = [...]uint8{0, 3, 7, 10, 15, 18, 21}
 
 func (i BruteAttemptKind) String() string {
 	i -= 1
@@ -4,7 +4,17 @@
 package model
 
 import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/funeypot/funeypot/internal/app/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestBruteAttempt_MaskedPassword(t *testing.T) {
@@ -56,3 +66,84 @@ func TestBruteAttempt_MaskedPassword(t *testing.T) {
 		})
 	}
 }
+
+func TestDatabase_IncrBruteAttempt(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("window", func(t *testing.T) {
+		db := newTestDatabase(t)
+		now := time.Now()
+		incr := func(timestamp time.Time) *BruteAttempt {
+			attempt, err := db.IncrBruteAttempt(ctx, "10.0.0.1", BruteAttemptKindSsh, timestamp, "user", "password", "client", 22, AttemptWindow{Gap: time.Hour})
+			require.NoError(t, err)
+			return attempt
+		}
+
+		first := incr(now)
+		assert.Equal(t, int64(1), first.Seq)
+		assert.Equal(t, int64(1), first.Count)
+		attempt := incr(now.Add(time.Minute))
+		assert.Equal(t, first.Id, attempt.Id)
+		assert.Equal(t, int64(2), attempt.Count)
+		assert.True(t, now.Equal(attempt.StartedAt))
+
+		attempt = incr(now.Add(2 * time.Hour))
+		assert.NotEqual(t, first.Id, attempt.Id)
+		assert.Equal(t, int64(2), attempt.Seq)
+		assert.Equal(t, int64(1), attempt.Count)
+	})
+
+	t.Run("length", func(t *testing.T) {
+		db := newTestDatabase(t)
+		now := time.Now()
+		incr := func(timestamp time.Time) *BruteAttempt {
+			attempt, err := db.IncrBruteAttempt(ctx, "10.0.0.1", BruteAttemptKindSsh, timestamp, "user", "password", "client", 22, AttemptWindow{Length: time.Hour})
+			require.NoError(t, err)
+			return attempt
+		}
+
+		first := incr(now)
+		// still active, but the row has lasted for the length
+		for i := 1; i < 6; i++ {
+			assert.Equal(t, first.Id, incr(now.Add(time.Duration(i)*10*time.Minute)).Id)
+		}
+		attempt := incr(now.Add(time.Hour))
+		assert.Equal(t, int64(2), attempt.Seq)
+	})
+
+	// the workers of a process, or the processes sharing a database
+	for _, processes := range []int{1, 2} {
+		t.Run(fmt.Sprintf("concurrent in %d processes", processes), func(t *testing.T) {
+			cfg := config.Database{
+				Driver: "sqlite",
+				Dsn:    filepath.Join(t.TempDir(), "funeypot.db"),
+			}
+			var dbs []*Database
+			for i := 0; i < processes; i++ {
+				db, err := NewDatabase(ctx, cfg)
+				require.NoError(t, err)
+				dbs = append(dbs, db)
+			}
+
+			const workers, times = 10, 20
+			now := time.Now()
+			wg := sync.WaitGroup{}
+			for i := 0; i < workers; i++ {
+				wg.Add(1)
+				go func(db *Database) {
+					defer wg.Done()
+					for j := 0; j < times; j++ {
+						_, err := db.IncrBruteAttempt(ctx, "10.0.0.1", BruteAttemptKindSsh, now, "user", "password", "client", 22, AttemptWindow{Gap: time.Hour})
+						assert.NoError(t, err)
+					}
+				}(dbs[i%processes])
+			}
+			wg.Wait()
+
+			var attempts []*BruteAttempt
+			require.NoError(t, dbs[0].db.Find(&attempts).Error)
+			require.Len(t, attempts, 1)
+			assert.Equal(t, int64(workers*times), attempts[0].Count)
+		})
+	}
+}
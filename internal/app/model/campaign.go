@@ -0,0 +1,205 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package model
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func init() {
+	registerModel(new(Campaign))
+	registerModel(new(CampaignMember))
+}
+
+const (
+	CampaignTypePrefix = "prefix" // the network is a prefix like "203.0.113.0/24"
+	CampaignTypeAsn    = "asn"    // the network is an AS like "AS3215"
+)
+
+// Campaign is the attempts of a kind from many ips in a same network, like a distributed brute force.
+// It begins as a candidate, and it's detected once there are enough distinct ips.
+type Campaign struct {
+	Id         int64
+	Type       string           `gorm:"size:8"`
+	Network    string           `gorm:"size:43;uniqueIndex:network_kind_seq"` // max "ipv6/prefix" length
+	Kind       BruteAttemptKind `gorm:"uniqueIndex:network_kind_seq"`
+	Seq        int64            `gorm:"uniqueIndex:network_kind_seq"` // the sequence of the campaigns of the network and kind
+	Ips        int64            // the count of distinct ips
+	Attempts   int64
+	StartedAt  time.Time
+	StoppedAt  time.Time  `gorm:"index"`
+	DetectedAt *time.Time `gorm:"index"` // nil if it's still a candidate
+
+	CreatedAt time.Time `gorm:"<-:create"`
+	UpdatedAt time.Time
+}
+
+func (c *Campaign) Detected() bool {
+	return c.DetectedAt != nil
+}
+
+// CampaignMember is a distinct ip of a campaign.
+type CampaignMember struct {
+	Id         int64
+	CampaignId int64  `gorm:"uniqueIndex:campaign_ip"`
+	Ip         string `gorm:"size:39;uniqueIndex:campaign_ip"`
+	Attempts   int64
+
+	CreatedAt time.Time `gorm:"<-:create"`
+	UpdatedAt time.Time
+}
+
+// CampaignRule is when attempts belong to a campaign, and when it's detected.
+type CampaignRule struct {
+	Gap    time.Duration // a new campaign starts after no attempts from the network for the gap
+	MinIps int64         // the campaign is detected once it has the count of distinct ips
+}
+
+// IncrCampaign counts an attempt from the ip into the current campaign of the network and kind,
+// and returns the campaign and whether it's detected by this attempt.
+func (db *Database) IncrCampaign(
+	ctx context.Context,
+	typ, network string,
+	kind BruteAttemptKind,
+	ip string,
+	timestamp time.Time,
+	rule CampaignRule,
+) (*Campaign, bool, error) {
+	unlock := db.campaignLocks.Lock(network + "/" + kind.String())
+	defer unlock()
+
+	var err error
+	for i := 0; i < maxIncrBruteAttemptTries; i++ {
+		var (
+			campaign *Campaign
+			detected bool
+		)
+		campaign, detected, err = db.incrCampaign(ctx, typ, network, kind, ip, timestamp, rule)
+		if !errors.Is(err, gorm.ErrDuplicatedKey) {
+			return campaign, detected, err
+		}
+		// another process sharing the database has started the campaign
+	}
+	return nil, false, err
+}
+
+func (db *Database) incrCampaign(
+	ctx context.Context,
+	typ, network string,
+	kind BruteAttemptKind,
+	ip string,
+	timestamp time.Time,
+	rule CampaignRule,
+) (*Campaign, bool, error) {
+	campaign := &Campaign{}
+	detected := false
+	err := db.withContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("network = ? AND kind = ?", network, kind).
+			Order("seq DESC").
+			Limit(1).
+			Find(campaign)
+		if err := result.Error; err != nil {
+			return err
+		}
+		if result.RowsAffected == 0 || timestamp.Sub(campaign.StoppedAt) >= rule.Gap {
+			*campaign = Campaign{
+				Type:      typ,
+				Network:   network,
+				Kind:      kind,
+				Seq:       campaign.Seq + 1,
+				StartedAt: timestamp,
+				StoppedAt: timestamp,
+			}
+			if err := tx.Create(campaign).Error; err != nil {
+				return err
+			}
+		}
+
+		member := &CampaignMember{
+			CampaignId: campaign.Id,
+			Ip:         ip,
+			Attempts:   1,
+		}
+		result = tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "campaign_id"}, {Name: "ip"}},
+			DoUpdates: clause.Assignments(map[string]any{
+				"attempts":   gorm.Expr("campaign_members.attempts + ?", 1),
+				"updated_at": time.Now(),
+			}),
+		}).Create(member)
+		if result.Error != nil {
+			return result.Error
+		}
+		// insert or update, tell it by the count of distinct ips rather than the affected rows, which vary in drivers
+		var ips int64
+		if err := tx.Model(&CampaignMember{}).Where("campaign_id = ?", campaign.Id).Count(&ips).Error; err != nil {
+			return err
+		}
+
+		updates := map[string]any{
+			"ips":        ips,
+			"attempts":   gorm.Expr("attempts + ?", 1),
+			"stopped_at": timestamp,
+		}
+		if campaign.DetectedAt == nil && ips >= rule.MinIps {
+			updates["detected_at"] = timestamp
+			detected = true
+		}
+		if err := tx.Model(campaign).Updates(updates).Error; err != nil {
+			return err
+		}
+		return tx.First(campaign, campaign.Id).Error
+	})
+	return campaign, detected, err
+}
+
+// CampaignFilter filters the campaigns, the zero fields are ignored.
+type CampaignFilter struct {
+	Type     string
+	Kind     BruteAttemptKind
+	After    time.Time // the campaigns active after the time
+	BeforeId int64     // for pagination, only the campaigns with smaller ids are returned
+}
+
+// ListCampaigns returns the latest detected campaigns matching the filter, in descending order of id.
+func (db *Database) ListCampaigns(ctx context.Context, filter CampaignFilter, limit int) ([]*Campaign, error) {
+	tx := db.withContext(ctx).Where("detected_at IS NOT NULL")
+	if filter.Type != "" {
+		tx = tx.Where("type = ?", filter.Type)
+	}
+	if filter.Kind != 0 {
+		tx = tx.Where("kind = ?", filter.Kind)
+	}
+	if !filter.After.IsZero() {
+		tx = tx.Where("stopped_at > ?", filter.After)
+	}
+	if filter.BeforeId != 0 {
+		tx = tx.Where("id < ?", filter.BeforeId)
+	}
+	var ret []*Campaign
+	err := tx.Order("id DESC").Limit(limit).Find(&ret).Error
+	return ret, err
+}
+
+// GetCampaign returns the campaign with its members in descending order of attempts.
+func (db *Database) GetCampaign(ctx context.Context, id int64) (*Campaign, []*CampaignMember, bool, error) {
+	sess := db.withContext(ctx)
+	campaign := &Campaign{}
+	result := sess.Where("id = ?", id).Limit(1).Find(campaign)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return nil, nil, false, result.Error
+	}
+	var members []*CampaignMember
+	if err := sess.Where("campaign_id = ?", id).Order("attempts DESC").Order("id").Find(&members).Error; err != nil {
+		return nil, nil, false, err
+	}
+	return campaign, members, true, nil
+}
@@ -0,0 +1,75 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package model
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDatabase_IncrCampaign(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDatabase(t)
+
+	rule := CampaignRule{Gap: time.Hour, MinIps: 3}
+	now := time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+	incr := func(ip string, timestamp time.Time) (*Campaign, bool) {
+		campaign, detected, err := db.IncrCampaign(ctx, CampaignTypePrefix, "10.0.0.0/24", BruteAttemptKindSsh, ip, timestamp, rule)
+		require.NoError(t, err)
+		return campaign, detected
+	}
+
+	for i, ip := range []string{"10.0.0.1", "10.0.0.1", "10.0.0.2"} {
+		campaign, detected := incr(ip, now.Add(time.Duration(i)*time.Minute))
+		assert.False(t, detected)
+		assert.False(t, campaign.Detected())
+	}
+	campaigns, err := db.ListCampaigns(ctx, CampaignFilter{}, 10)
+	require.NoError(t, err)
+	assert.Empty(t, campaigns, "candidates are not listed")
+
+	campaign, detected := incr("10.0.0.3", now.Add(3*time.Minute))
+	assert.True(t, detected)
+	assert.True(t, campaign.Detected())
+	assert.Equal(t, int64(1), campaign.Seq)
+	assert.Equal(t, int64(3), campaign.Ips)
+	assert.Equal(t, int64(4), campaign.Attempts)
+
+	// detected only once
+	campaign, detected = incr("10.0.0.4", now.Add(4*time.Minute))
+	assert.False(t, detected)
+	assert.True(t, campaign.Detected())
+	assert.Equal(t, int64(4), campaign.Ips)
+
+	got, members, ok, err := db.GetCampaign(ctx, campaign.Id)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, campaign.Id, got.Id)
+	require.Len(t, members, 4)
+	assert.Equal(t, "10.0.0.1", members[0].Ip)
+	assert.Equal(t, int64(2), members[0].Attempts)
+
+	// a new campaign after the gap
+	campaign, detected = incr("10.0.0.1", now.Add(4*time.Minute+time.Hour))
+	assert.False(t, detected)
+	assert.Equal(t, int64(2), campaign.Seq)
+	assert.Equal(t, int64(1), campaign.Ips)
+
+	campaigns, err = db.ListCampaigns(ctx, CampaignFilter{Kind: BruteAttemptKindSsh}, 10)
+	require.NoError(t, err)
+	require.Len(t, campaigns, 1)
+	assert.Equal(t, got.Id, campaigns[0].Id)
+
+	campaigns, err = db.ListCampaigns(ctx, CampaignFilter{Type: CampaignTypeAsn}, 10)
+	require.NoError(t, err)
+	assert.Empty(t, campaigns)
+
+	_, _, ok, err = db.GetCampaign(ctx, 1000)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
@@ -0,0 +1,198 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package model
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// CredentialGroup is what the credentials of the attempt records are grouped by.
+type CredentialGroup string
+
+const (
+	CredentialGroupUser     CredentialGroup = "user"
+	CredentialGroupPassword CredentialGroup = "password"
+	CredentialGroupPair     CredentialGroup = "pair" // the pair of user and password
+	CredentialGroupIp       CredentialGroup = "ip"   // the breakdown of the ips using the credentials
+)
+
+func ParseCredentialGroup(s string) (CredentialGroup, bool) {
+	switch g := CredentialGroup(s); g {
+	case CredentialGroupUser, CredentialGroupPassword, CredentialGroupPair, CredentialGroupIp:
+		return g, true
+	}
+	return "", false
+}
+
+func (g CredentialGroup) columns() []string {
+	switch g {
+	case CredentialGroupUser:
+		return []string{"user"}
+	case CredentialGroupPassword:
+		return []string{"password"}
+	case CredentialGroupPair:
+		return []string{"user", "password"}
+	case CredentialGroupIp:
+		return []string{"ip"}
+	}
+	return nil
+}
+
+// CredentialFilter filters the attempt records to count the credentials, the zero fields are ignored.
+type CredentialFilter struct {
+	Ip       string
+	Kind     BruteAttemptKind
+	User     string
+	Password string
+	After    time.Time
+	Before   time.Time
+
+	MinAttempts int64 // only the groups with the count of attempts at least
+}
+
+func (f CredentialFilter) apply(tx *gorm.DB) *gorm.DB {
+	tx = AttemptRecordFilter{
+		Ip:     f.Ip,
+		Kind:   f.Kind,
+		After:  f.After,
+		Before: f.Before,
+	}.apply(tx, "attempt_records")
+	if f.User != "" {
+		tx = tx.Where("attempt_records.user = ?", f.User)
+	}
+	if f.Password != "" {
+		tx = tx.Where("attempt_records.password = ?", f.Password)
+	}
+	return tx
+}
+
+// CredentialCount is the count of the attempt records of a group,
+// only the fields of the group are set among User, Password and Ip.
+type CredentialCount struct {
+	User      string
+	Password  string
+	Ip        string
+	Attempts  int64
+	Ips       int64 // the count of distinct ips
+	FirstSeen time.Time
+	LastSeen  time.Time
+}
+
+// TopCredentials returns the groups of the records matching the filter, in descending order of attempts.
+// It returns all the groups if limit is not positive.
+func (db *Database) TopCredentials(ctx context.Context, group CredentialGroup, filter CredentialFilter, limit int) ([]*CredentialCount, error) {
+	tx := db.countCredentials(group, filter, filter.apply(db.withContext(ctx).Model(&AttemptRecord{})))
+	tx = tx.Order("attempts DESC")
+	for _, v := range group.columns() {
+		tx = tx.Order(db.db.Statement.Quote(v))
+	}
+	if limit <= 0 {
+		limit = -1 // no limit
+	}
+	return scanCredentialCounts(tx.Limit(limit))
+}
+
+// NewCredentials returns the groups first seen after the time, in descending order of the first seen time.
+// The groups are counted in all the records matching the filter, so After and Before of the filter are ignored.
+func (db *Database) NewCredentials(ctx context.Context, group CredentialGroup, filter CredentialFilter, after time.Time, limit int) ([]*CredentialCount, error) {
+	filter.After, filter.Before = time.Time{}, time.Time{}
+	tx := db.countCredentials(group, filter, filter.apply(db.withContext(ctx).Model(&AttemptRecord{})))
+	tx = tx.Having("MIN(attempt_records.time) > ?", after).Order("first_seen DESC")
+	for _, v := range group.columns() {
+		tx = tx.Order(db.db.Statement.Quote(v))
+	}
+	return scanCredentialCounts(tx.Limit(limit))
+}
+
+func (db *Database) countCredentials(group CredentialGroup, filter CredentialFilter, tx *gorm.DB) *gorm.DB {
+	columns := group.columns()
+	selects := make([]string, 0, len(columns)+4)
+	groups := make([]string, 0, len(columns))
+	for _, v := range columns {
+		// quote them, since "user" is a keyword of postgres
+		column := db.db.Statement.Quote("attempt_records." + v)
+		selects = append(selects, column+" AS "+db.db.Statement.Quote(v))
+		groups = append(groups, column)
+	}
+	selects = append(selects,
+		"COUNT(*) AS attempts",
+		"COUNT(DISTINCT attempt_records.ip) AS ips",
+		"MIN(attempt_records.time) AS first_seen",
+		"MAX(attempt_records.time) AS last_seen",
+	)
+	tx = tx.Select(selects)
+	for _, v := range groups {
+		tx = tx.Group(v)
+	}
+	if filter.MinAttempts > 0 {
+		tx = tx.Having("COUNT(*) >= ?", filter.MinAttempts)
+	}
+	return tx
+}
+
+func scanCredentialCounts(tx *gorm.DB) ([]*CredentialCount, error) {
+	var rows []*struct {
+		User      string
+		Password  string
+		Ip        string
+		Attempts  int64
+		Ips       int64
+		FirstSeen aggregatedTime
+		LastSeen  aggregatedTime
+	}
+	if err := tx.Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	ret := make([]*CredentialCount, 0, len(rows))
+	for _, v := range rows {
+		ret = append(ret, &CredentialCount{
+			User:      v.User,
+			Password:  v.Password,
+			Ip:        v.Ip,
+			Attempts:  v.Attempts,
+			Ips:       v.Ips,
+			FirstSeen: time.Time(v.FirstSeen),
+			LastSeen:  time.Time(v.LastSeen),
+		})
+	}
+	return ret, nil
+}
+
+// aggregatedTime is the result of MIN or MAX of a time column,
+// which is a string in sqlite since the type of the column is lost in the aggregation.
+type aggregatedTime time.Time
+
+func (t *aggregatedTime) Scan(src any) error {
+	switch v := src.(type) {
+	case time.Time:
+		*t = aggregatedTime(v)
+		return nil
+	case []byte:
+		return t.parse(string(v))
+	case string:
+		return t.parse(v)
+	case nil:
+		*t = aggregatedTime{}
+		return nil
+	}
+	return fmt.Errorf("unsupported type %T of time", src)
+}
+
+func (t *aggregatedTime) parse(s string) error {
+	for _, layout := range []string{
+		"2006-01-02 15:04:05.999999999-07:00", // the format of sqlite
+		time.RFC3339Nano,
+		time.DateTime,
+	} {
+		if v, err := time.Parse(layout, s); err == nil {
+			*t = aggregatedTime(v)
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid time %q", s)
+}
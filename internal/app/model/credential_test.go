@@ -0,0 +1,82 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package model
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDatabase_TopCredentials(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDatabase(t)
+
+	now := time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+	for i, v := range []struct {
+		ip       string
+		kind     BruteAttemptKind
+		user     string
+		password string
+	}{
+		{"10.0.0.1", BruteAttemptKindSsh, "root", "123456"},
+		{"10.0.0.1", BruteAttemptKindSsh, "root", "password"},
+		{"10.0.0.2", BruteAttemptKindSsh, "root", "123456"},
+		{"10.0.0.2", BruteAttemptKindSsh, "admin", "123456"},
+		{"10.0.0.3", BruteAttemptKindHttp, "admin", "admin"},
+	} {
+		require.NoError(t, db.Create(ctx, &AttemptRecord{
+			Ip:       v.ip,
+			Kind:     v.kind,
+			User:     v.user,
+			Password: v.password,
+			Time:     now.Add(time.Duration(i) * time.Hour),
+		}))
+	}
+
+	t.Run("pair", func(t *testing.T) {
+		counts, err := db.TopCredentials(ctx, CredentialGroupPair, CredentialFilter{}, 2)
+		require.NoError(t, err)
+		require.Len(t, counts, 2)
+		assert.Equal(t, "root", counts[0].User)
+		assert.Equal(t, "123456", counts[0].Password)
+		assert.Equal(t, int64(2), counts[0].Attempts)
+		assert.Equal(t, int64(2), counts[0].Ips)
+		assert.True(t, now.Equal(counts[0].FirstSeen))
+		assert.True(t, now.Add(2*time.Hour).Equal(counts[0].LastSeen))
+		assert.Equal(t, "admin", counts[1].User)
+		assert.Equal(t, "123456", counts[1].Password)
+	})
+
+	t.Run("filter", func(t *testing.T) {
+		counts, err := db.TopCredentials(ctx, CredentialGroupUser, CredentialFilter{Kind: BruteAttemptKindSsh, After: now}, 10)
+		require.NoError(t, err)
+		require.Len(t, counts, 2)
+		assert.Equal(t, "root", counts[0].User)
+		assert.Equal(t, int64(2), counts[0].Attempts)
+		assert.Equal(t, &CredentialCount{User: "admin", Attempts: 1, Ips: 1, FirstSeen: counts[1].FirstSeen, LastSeen: counts[1].LastSeen}, counts[1])
+	})
+
+	t.Run("ip", func(t *testing.T) {
+		counts, err := db.TopCredentials(ctx, CredentialGroupIp, CredentialFilter{Password: "123456"}, 10)
+		require.NoError(t, err)
+		require.Len(t, counts, 2)
+		assert.Equal(t, "10.0.0.2", counts[0].Ip)
+		assert.Equal(t, int64(2), counts[0].Attempts)
+		assert.Equal(t, "10.0.0.1", counts[1].Ip)
+		assert.Equal(t, int64(1), counts[1].Attempts)
+	})
+
+	t.Run("new", func(t *testing.T) {
+		counts, err := db.NewCredentials(ctx, CredentialGroupPassword, CredentialFilter{}, now.Add(30*time.Minute), 10)
+		require.NoError(t, err)
+		require.Len(t, counts, 2)
+		// "123456" was seen before
+		assert.Equal(t, "admin", counts[0].Password)
+		assert.Equal(t, "password", counts[1].Password)
+	})
+}
@@ -20,10 +20,12 @@ func init() {
 }
 
 type IpGeo struct {
-	Ip        string `gorm:"primaryKey; size:39"`
-	Location  string `gorm:"size:255"`
-	Latitude  float64
-	Longitude float64
+	Ip          string `gorm:"primaryKey; size:39"`
+	Location    string `gorm:"size:255"`
+	Latitude    float64
+	Longitude   float64
+	CountryCode string `gorm:"size:2"`
+	Asn         int
 
 	CreatedAt time.Time `gorm:"<-:create"`
 	UpdatedAt time.Time
@@ -34,16 +36,20 @@ func (m *IpGeo) FillInfo(r *ipgeo.Info) *IpGeo {
 	m.Location = r.Location
 	m.Latitude = r.Latitude
 	m.Longitude = r.Longitude
+	m.CountryCode = r.CountryCode
+	m.Asn = r.Asn
 
 	return m
 }
 
 func (m *IpGeo) Info() *ipgeo.Info {
 	return &ipgeo.Info{
-		Ip:        net.ParseIP(m.Ip),
-		Location:  m.Location,
-		Latitude:  m.Latitude,
-		Longitude: m.Longitude,
+		Ip:          net.ParseIP(m.Ip),
+		Location:    m.Location,
+		Latitude:    m.Latitude,
+		Longitude:   m.Longitude,
+		CountryCode: m.CountryCode,
+		Asn:         m.Asn,
 	}
 }
 
@@ -0,0 +1,218 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package model
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/funeypot/funeypot/internal/pkg/logs"
+
+	"gorm.io/gorm"
+)
+
+// SchemaMigration records an applied migration.
+// It's not a registered model, since it has to exist before any migration.
+type SchemaMigration struct {
+	Version   int    `gorm:"primaryKey;autoIncrement:false"`
+	Name      string `gorm:"size:255"`
+	AppliedAt time.Time
+}
+
+// Migration is a versioned change of the schema or the data.
+//
+// The baseline creates the latest schema of the models on a new database, and the following migrations are applied
+// after it, so they must tolerate the changes being there already, like checking the columns before renaming them.
+type Migration struct {
+	Version int
+	Name    string
+	up      func(tx *gorm.DB) error
+}
+
+// migrations are in ascending order of versions, never modify or remove the applied ones, add new ones instead.
+var migrations = []*Migration{
+	{
+		Version: 1,
+		Name:    "baseline",
+		up: func(tx *gorm.DB) error {
+			// it also upgrades the databases created by AutoMigrate before the migrations were introduced
+			return tx.AutoMigrate(models...)
+		},
+	},
+	{
+		Version: 2,
+		Name:    "fix ip_kind index of brute_attempts",
+		up: func(tx *gorm.DB) error {
+			// the tag was "size:39,index:ip_kind", so the ip had no size and the index had only the kind,
+			// it made the locking of IncrBruteAttempt scan and lock much more rows than needed
+			migrator := tx.Migrator()
+			if migrator.HasIndex(&BruteAttempt{}, "ip_kind") {
+				if err := migrator.DropIndex(&BruteAttempt{}, "ip_kind"); err != nil {
+					return err
+				}
+			}
+			if err := migrator.AlterColumn(&BruteAttempt{}, "Ip"); err != nil {
+				return err
+			}
+			return migrator.CreateIndex(&BruteAttempt{}, "ip_kind")
+		},
+	},
+	{
+		Version: 3,
+		Name:    "add unique seq of brute_attempts",
+		up: func(tx *gorm.DB) error {
+			// the index is not in the tags of the model, or the baseline would fail to create it on existing rows
+			migrator := tx.Migrator()
+			if !migrator.HasColumn(&BruteAttempt{}, "Seq") {
+				if err := migrator.AddColumn(&BruteAttempt{}, "Seq"); err != nil {
+					return err
+				}
+			}
+
+			// number the existing windows of each ip and kind in the order of creation
+			type key struct {
+				ip   string
+				kind BruteAttemptKind
+			}
+			seqs := map[key]int64{}
+			var attempts []*BruteAttempt
+			if err := tx.Select("id", "ip", "kind").Order("id").FindInBatches(&attempts, 1000, func(batch *gorm.DB, _ int) error {
+				for _, v := range attempts {
+					k := key{v.Ip, v.Kind}
+					seqs[k]++
+					if err := batch.Model(v).UpdateColumn("seq", seqs[k]).Error; err != nil {
+						return err
+					}
+				}
+				return nil
+			}).Error; err != nil {
+				return err
+			}
+
+			if migrator.HasIndex(&BruteAttempt{}, "ip_kind_seq") {
+				return nil
+			}
+			return tx.Exec("CREATE UNIQUE INDEX ip_kind_seq ON brute_attempts (ip, kind, seq)").Error
+		},
+	},
+	{
+		Version: 4,
+		Name:    "add campaigns",
+		up: func(tx *gorm.DB) error {
+			// the baseline has created them on the new databases, and it does nothing then
+			return tx.AutoMigrate(&Campaign{}, &CampaignMember{})
+		},
+	},
+	{
+		Version: 5,
+		Name:    "add abuseipdb queue",
+		up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&AbuseipdbPending{})
+		},
+	},
+	{
+		Version: 6,
+		Name:    "add ip reputations",
+		up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&IpReputation{})
+		},
+	},
+}
+
+// Migrations returns all the migrations in ascending order of versions.
+func Migrations() []*Migration {
+	return migrations
+}
+
+// MigrationState is a migration with whether it has been applied.
+type MigrationState struct {
+	*Migration
+	AppliedAt time.Time // zero if it's pending
+}
+
+func (s *MigrationState) Applied() bool {
+	return !s.AppliedAt.IsZero()
+}
+
+// MigrationStates returns the states of all the migrations in ascending order of versions.
+func (db *Database) MigrationStates(ctx context.Context) ([]*MigrationState, error) {
+	applied, err := db.appliedMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]*MigrationState, 0, len(migrations))
+	for _, m := range migrations {
+		state := &MigrationState{Migration: m}
+		if v, ok := applied[m.Version]; ok {
+			state.AppliedAt = v.AppliedAt
+		}
+		ret = append(ret, state)
+	}
+	return ret, nil
+}
+
+// PendingMigrations returns the migrations which have not been applied, in ascending order of versions.
+func (db *Database) PendingMigrations(ctx context.Context) ([]*Migration, error) {
+	states, err := db.MigrationStates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var ret []*Migration
+	for _, v := range states {
+		if !v.Applied() {
+			ret = append(ret, v.Migration)
+		}
+	}
+	return ret, nil
+}
+
+// Migrate applies the pending migrations up to the target version in order, or all of them if target is 0.
+// Each migration is applied in its own transaction, and it returns the applied ones.
+func (db *Database) Migrate(ctx context.Context, target int) ([]*Migration, error) {
+	logger := logs.From(ctx)
+
+	pending, err := db.PendingMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []*Migration
+	for _, m := range pending {
+		if target > 0 && m.Version > target {
+			break
+		}
+		logger.Infof("apply migration %d %s", m.Version, m.Name)
+		if err := db.withContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := m.up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&SchemaMigration{
+				Version:   m.Version,
+				Name:      m.Name,
+				AppliedAt: time.Now(),
+			}).Error
+		}); err != nil {
+			return ret, fmt.Errorf("apply migration %d %s: %w", m.Version, m.Name, err)
+		}
+		ret = append(ret, m)
+	}
+	return ret, nil
+}
+
+func (db *Database) appliedMigrations(ctx context.Context) (map[int]*SchemaMigration, error) {
+	sess := db.withContext(ctx)
+	if err := sess.AutoMigrate(&SchemaMigration{}); err != nil {
+		return nil, fmt.Errorf("create schema_migrations: %w", err)
+	}
+	var applied []*SchemaMigration
+	if err := sess.Find(&applied).Error; err != nil {
+		return nil, err
+	}
+	ret := make(map[int]*SchemaMigration, len(applied))
+	for _, v := range applied {
+		ret[v.Version] = v
+	}
+	return ret, nil
+}
@@ -0,0 +1,180 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package model
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/funeypot/funeypot/internal/app/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestMigrations(t *testing.T) {
+	for i, m := range migrations {
+		assert.Equal(t, i+1, m.Version, "versions must be consecutive")
+		assert.NotEmpty(t, m.Name)
+		assert.NotNil(t, m.up)
+	}
+}
+
+func TestDatabase_Migrate(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("new", func(t *testing.T) {
+		db := newTestDatabase(t)
+		pending, err := db.PendingMigrations(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, pending)
+
+		applied, err := db.Migrate(ctx, 0)
+		require.NoError(t, err)
+		assert.Empty(t, applied)
+	})
+
+	t.Run("upgrade from auto migrate", func(t *testing.T) {
+		cfg := config.Database{
+			Driver: "sqlite",
+			Dsn:    filepath.Join(t.TempDir(), "funeypot.db"),
+		}
+		db, err := OpenDatabase(cfg)
+		require.NoError(t, err)
+		// the schema created by the versions before migrations, with a row to keep
+		require.NoError(t, db.db.AutoMigrate(models...))
+		now := time.Now()
+		_, err = db.IncrBruteAttempt(ctx, "10.0.0.1", BruteAttemptKindSsh, now, "user", "password", "client", 22, AttemptWindow{Gap: time.Hour})
+		require.NoError(t, err)
+
+		states, err := db.MigrationStates(ctx)
+		require.NoError(t, err)
+		require.Len(t, states, len(migrations))
+		for _, v := range states {
+			assert.False(t, v.Applied())
+		}
+
+		applied, err := db.Migrate(ctx, 1)
+		require.NoError(t, err)
+		require.Len(t, applied, 1)
+		assert.Equal(t, "baseline", applied[0].Name)
+
+		applied, err = db.Migrate(ctx, 0)
+		require.NoError(t, err)
+		assert.Len(t, applied, len(migrations)-1)
+
+		states, err = db.MigrationStates(ctx)
+		require.NoError(t, err)
+		for _, v := range states {
+			assert.True(t, v.Applied())
+		}
+		attempt := &BruteAttempt{}
+		require.NoError(t, db.db.First(attempt).Error)
+		assert.Equal(t, "10.0.0.1", attempt.Ip)
+	})
+
+	t.Run("fix ip_kind index", func(t *testing.T) {
+		db, err := OpenDatabase(config.Database{
+			Driver: "sqlite",
+			Dsn:    filepath.Join(t.TempDir(), "funeypot.db"),
+		})
+		require.NoError(t, err)
+		type legacyBruteAttempt struct {
+			Id   int64
+			Ip   string           `gorm:"size:39,index:ip_kind"`
+			Kind BruteAttemptKind `gorm:"index:ip_kind"`
+		}
+		require.NoError(t, db.db.Table("brute_attempts").AutoMigrate(&legacyBruteAttempt{}))
+		require.NoError(t, db.db.Table("brute_attempts").Create(&legacyBruteAttempt{Ip: "10.0.0.1", Kind: BruteAttemptKindSsh}).Error)
+
+		_, err = db.Migrate(ctx, 0)
+		require.NoError(t, err)
+
+		indexes, err := db.db.Migrator().GetIndexes(&BruteAttempt{})
+		require.NoError(t, err)
+		var columns []string
+		for _, v := range indexes {
+			if v.Name() == "ip_kind" {
+				columns = v.Columns()
+			}
+		}
+		assert.Equal(t, []string{"ip", "kind"}, columns)
+		attempt := &BruteAttempt{}
+		require.NoError(t, db.db.First(attempt).Error)
+		assert.Equal(t, "10.0.0.1", attempt.Ip)
+	})
+
+	t.Run("number windows", func(t *testing.T) {
+		db, err := OpenDatabase(config.Database{
+			Driver: "sqlite",
+			Dsn:    filepath.Join(t.TempDir(), "funeypot.db"),
+		})
+		require.NoError(t, err)
+		_, err = db.Migrate(ctx, 2)
+		require.NoError(t, err)
+		// the windows created before the seq
+		for _, ip := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.1"} {
+			require.NoError(t, db.db.Omit("seq").Create(&BruteAttempt{Ip: ip, Kind: BruteAttemptKindSsh}).Error)
+		}
+
+		_, err = db.Migrate(ctx, 3)
+		require.NoError(t, err)
+
+		var attempts []*BruteAttempt
+		require.NoError(t, db.db.Order("id").Find(&attempts).Error)
+		require.Len(t, attempts, 3)
+		assert.Equal(t, []int64{1, 1, 2}, []int64{attempts[0].Seq, attempts[1].Seq, attempts[2].Seq})
+		err = db.db.Create(&BruteAttempt{Ip: "10.0.0.1", Kind: BruteAttemptKindSsh, Seq: 2}).Error
+		assert.ErrorIs(t, err, gorm.ErrDuplicatedKey)
+	})
+
+	t.Run("manual", func(t *testing.T) {
+		cfg := config.Database{
+			Driver:    "sqlite",
+			Dsn:       filepath.Join(t.TempDir(), "funeypot.db"),
+			Migration: config.MigrationManual,
+		}
+		_, err := NewDatabase(ctx, cfg)
+		assert.ErrorContains(t, err, "pending migrations")
+
+		db, err := OpenDatabase(cfg)
+		require.NoError(t, err)
+		_, err = db.Migrate(ctx, 0)
+		require.NoError(t, err)
+		require.NoError(t, db.Close())
+
+		db, err = NewDatabase(ctx, cfg)
+		require.NoError(t, err)
+		require.NoError(t, db.Close())
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		db := newTestDatabase(t)
+		defer func(v []*Migration) {
+			migrations = v
+		}(migrations)
+		migrations = append(migrations[:len(migrations):len(migrations)], &Migration{
+			Version: len(migrations) + 1,
+			Name:    "failed",
+			up: func(tx *gorm.DB) error {
+				if err := tx.Exec("CREATE TABLE test_failed (id INTEGER)").Error; err != nil {
+					return err
+				}
+				return tx.Exec("SELECT * FROM not_existing").Error
+			},
+		})
+
+		_, err := db.Migrate(ctx, 0)
+		assert.ErrorContains(t, err, "apply migration")
+
+		pending, err := db.PendingMigrations(ctx)
+		require.NoError(t, err)
+		require.Len(t, pending, 1)
+		// rolled back with the transaction
+		assert.False(t, db.db.Migrator().HasTable("test_failed"))
+	})
+}
@@ -6,51 +6,151 @@ package model
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/funeypot/funeypot/internal/app/config"
 	"github.com/funeypot/funeypot/internal/pkg/logs"
 
 	"github.com/gin-gonic/gin"
 	"github.com/glebarez/sqlite"
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"gorm.io/driver/mysql"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
 type Database struct {
 	db *gorm.DB
+
+	attemptLocks  keyMutex // serializes the increments of the same ip and kind in the process
+	campaignLocks keyMutex // serializes the increments of the same network and kind in the process
 }
 
+// NewDatabase opens the database and migrates the schema according to the config.
 func NewDatabase(ctx context.Context, cfg config.Database) (*Database, error) {
-	var (
-		db  *gorm.DB
-		err error
-	)
+	ret, err := OpenDatabase(cfg)
+	if err != nil {
+		return nil, err
+	}
 
+	if cfg.Migration == config.MigrationManual {
+		pending, err := ret.PendingMigrations(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("check migrations: %w", err)
+		}
+		if len(pending) > 0 {
+			return nil, fmt.Errorf("%d pending migrations, run \"funeypot migrate up\" to apply them", len(pending))
+		}
+		return ret, nil
+	}
+
+	if _, err := ret.Migrate(ctx, 0); err != nil {
+		return nil, fmt.Errorf("migrate: %w", err)
+	}
+	return ret, nil
+}
+
+// OpenDatabase opens the database without migrating the schema.
+func OpenDatabase(cfg config.Database) (*Database, error) {
+	var dialector gorm.Dialector
 	switch cfg.Driver {
 	case "sqlite", "sqlite3":
-		db, err = gorm.Open(sqlite.Open(cfg.Dsn), &gorm.Config{
-			Logger: logs.GormLogger{},
-		})
+		dialector = sqlite.Open(sqliteDsn(cfg.Dsn))
 	case "postgres", "postgresql":
-		db, err = gorm.Open(postgres.Open(cfg.Dsn), &gorm.Config{
-			Logger: logs.GormLogger{},
-		})
+		dialector = postgres.Open(cfg.Dsn)
+	case "mysql", "mariadb":
+		dsn, err := mysqlDsn(cfg.Dsn)
+		if err != nil {
+			return nil, fmt.Errorf("parse dsn: %w", err)
+		}
+		dialector = mysql.Open(dsn)
 	default:
 		return nil, fmt.Errorf("unsupported database driver: %s", cfg.Driver)
 	}
 
+	db, err := gorm.Open(dialector, &gorm.Config{
+		Logger:         logs.GormLogger{},
+		TranslateError: true, // to tell gorm.ErrDuplicatedKey
+	})
 	if err != nil {
 		return nil, fmt.Errorf("open database: %w", err)
 	}
 
-	ret := &Database{
+	return &Database{
 		db: db,
+	}, nil
+}
+
+// sqliteDsn waits for the locks instead of failing immediately, and begins transactions with the write lock,
+// or a transaction reading before writing could fail when another one is writing.
+func sqliteDsn(dsn string) string {
+	var params []string
+	if !strings.Contains(dsn, "busy_timeout") {
+		params = append(params, "_pragma=busy_timeout(5000)")
+	}
+	if !strings.Contains(dsn, "_txlock") {
+		params = append(params, "_txlock=immediate")
+	}
+	if len(params) == 0 {
+		return dsn
+	}
+	if strings.Contains(dsn, "?") {
+		return dsn + "&" + strings.Join(params, "&")
 	}
+	return dsn + "?" + strings.Join(params, "&")
+}
+
+// mysqlDsn makes sure the times are parsed, or they cannot be scanned into time.Time.
+func mysqlDsn(dsn string) (string, error) {
+	cfg, err := mysqldriver.ParseDSN(dsn)
+	if err != nil {
+		return "", err
+	}
+	cfg.ParseTime = true
+	return cfg.FormatDSN(), nil
+}
+
+// excluded returns the expression of the value proposed for insertion, for the updates of upserts.
+func (db *Database) excluded(column string) string {
+	if db.db.Dialector.Name() == "mysql" {
+		return "VALUES(" + column + ")"
+	}
+	return "excluded." + column
+}
 
-	if err := ret.withContext(ctx).AutoMigrate(models...); err != nil {
-		return nil, fmt.Errorf("auto migrate: %w", err)
+func (db *Database) Close() error {
+	sqlDb, err := db.db.DB()
+	if err != nil {
+		return err
 	}
+	return sqlDb.Close()
+}
+
+// TableStat is the statistics of a table of the models.
+type TableStat struct {
+	Name string
+	Rows int64
+}
 
+// TableStats returns the statistics of the tables of all the models, in the order of names.
+func (db *Database) TableStats(ctx context.Context) ([]*TableStat, error) {
+	sess := db.withContext(ctx)
+	ret := make([]*TableStat, 0, len(models))
+	for _, m := range models {
+		stmt := &gorm.Statement{DB: sess}
+		if err := stmt.Parse(m); err != nil {
+			return nil, fmt.Errorf("parse %T: %w", m, err)
+		}
+		stat := &TableStat{Name: stmt.Schema.Table}
+		if err := sess.Model(m).Count(&stat.Rows).Error; err != nil {
+			return nil, fmt.Errorf("count %s: %w", stat.Name, err)
+		}
+		ret = append(ret, stat)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Name < ret[j].Name
+	})
 	return ret, nil
 }
 
@@ -4,9 +4,15 @@
 package model
 
 import (
+	"context"
+	"path/filepath"
 	"testing"
+	"time"
+
+	"github.com/funeypot/funeypot/internal/app/config"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func Test_truncateString(t *testing.T) {
@@ -50,3 +56,42 @@ func Test_truncateString(t *testing.T) {
 		})
 	}
 }
+
+func Test_sqliteDsn(t *testing.T) {
+	assert.Equal(t, "funeypot.db?_pragma=busy_timeout(5000)&_txlock=immediate", sqliteDsn("funeypot.db"))
+	assert.Equal(t, "file:funeypot.db?mode=rwc&_pragma=busy_timeout(5000)&_txlock=immediate", sqliteDsn("file:funeypot.db?mode=rwc"))
+	assert.Equal(t, "funeypot.db?_txlock=deferred&_pragma=busy_timeout(1000)", sqliteDsn("funeypot.db?_txlock=deferred&_pragma=busy_timeout(1000)"))
+}
+
+func Test_mysqlDsn(t *testing.T) {
+	dsn, err := mysqlDsn("funeypot:secret@tcp(localhost:3306)/funeypot?charset=utf8mb4")
+	require.NoError(t, err)
+	assert.Equal(t, "funeypot:secret@tcp(localhost:3306)/funeypot?parseTime=true&charset=utf8mb4", dsn)
+
+	_, err = mysqlDsn("localhost:3306")
+	assert.Error(t, err)
+}
+
+func TestDatabase_TableStats(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDatabase(t)
+
+	require.NoError(t, db.Create(ctx, &AbuseipdbReport{Ip: "10.0.0.1", ReportedAt: time.Now()}))
+	require.NoError(t, db.Create(ctx, &AbuseipdbReport{Ip: "10.0.0.2", ReportedAt: time.Now()}))
+
+	stats, err := db.TableStats(ctx)
+	require.NoError(t, err)
+	require.Len(t, stats, len(models))
+	assert.Equal(t, &TableStat{Name: "abuseipdb_pendings", Rows: 0}, stats[0])
+	assert.Equal(t, &TableStat{Name: "abuseipdb_reports", Rows: 2}, stats[1])
+}
+
+func newTestDatabase(t *testing.T) *Database {
+	t.Helper()
+	db, err := NewDatabase(context.Background(), config.Database{
+		Driver: "sqlite",
+		Dsn:    filepath.Join(t.TempDir(), "funeypot.db"),
+	})
+	require.NoError(t, err)
+	return db
+}
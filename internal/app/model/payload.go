@@ -0,0 +1,35 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	registerModel(new(TcpPayload))
+}
+
+// TcpPayload is the first bytes sent by a client to a generic TCP listener.
+type TcpPayload struct {
+	Id        int64
+	Ip        string    `gorm:"size:39;index"`
+	Port      int       `gorm:"index"`
+	Protocol  string    `gorm:"size:16;index"` // the detected protocol, see sniff.Protocol
+	Size      int       // the number of bytes received, it may be less than the real payload if it's truncated
+	Hex       string    `gorm:"size:8192"`
+	Printable string    `gorm:"size:4096"`
+	Time      time.Time `gorm:"index"`
+
+	CreatedAt time.Time `gorm:"<-:create"`
+}
+
+func (p *TcpPayload) BeforeSave(_ *gorm.DB) error {
+	p.Protocol = truncateString(p.Protocol, 16)
+	p.Hex = truncateString(p.Hex, 8192)
+	p.Printable = truncateString(p.Printable, 4096)
+	return nil
+}
@@ -0,0 +1,132 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	registerModel(new(AttemptRecord))
+}
+
+// AttemptRecord is a single attempt with the metadata of its connection,
+// while BruteAttempt aggregates the attempts of an ip.
+type AttemptRecord struct {
+	Id            int64
+	AttemptId     int64            `gorm:"index"` // the id of the BruteAttempt it's aggregated into
+	Ip            string           `gorm:"size:39;index"`
+	Kind          BruteAttemptKind `gorm:"index"`
+	LocalAddr     string           `gorm:"size:47"` // max "[ipv6]:port" length
+	LocalPort     int              `gorm:"index"`
+	RemotePort    int
+	Variant       string            `gorm:"size:32;index"` // the variant of the protocol, like "tls" or "plain"
+	User          string            `gorm:"size:255"`
+	Password      string            `gorm:"size:255"`
+	ClientVersion string            `gorm:"size:255"`
+	Extra         map[string]string `gorm:"serializer:json;size:2048"` // the extra information of the kind, like the path of HTTP
+	Time          time.Time         `gorm:"index"`
+
+	CreatedAt time.Time `gorm:"<-:create"`
+}
+
+func (r *AttemptRecord) BeforeSave(_ *gorm.DB) error {
+	r.LocalAddr = truncateString(r.LocalAddr, 47)
+	r.Variant = truncateString(r.Variant, 32)
+	r.User = truncateString(r.User, 255)
+	r.Password = truncateString(r.Password, 255)
+	r.ClientVersion = truncateString(r.ClientVersion, 255)
+	for k, v := range r.Extra {
+		r.Extra[k] = truncateString(v, 255)
+	}
+	truncateExtra(r.Extra, 2048)
+	return nil
+}
+
+// truncateExtra halves the longest values until the JSON fits the column,
+// since the encoder escapes characters like "<" to 6 bytes.
+func truncateExtra(extra map[string]string, max int) {
+	for {
+		data, err := json.Marshal(extra)
+		if err != nil || len(data) <= max {
+			return
+		}
+		longest := ""
+		for _, k := range slices.Sorted(maps.Keys(extra)) {
+			if longest == "" || len(extra[k]) > len(extra[longest]) {
+				longest = k
+			}
+		}
+		if extra[longest] == "" {
+			// only the keys are left, nothing more can be truncated
+			return
+		}
+		extra[longest] = truncateString(extra[longest], len(extra[longest])/2)
+	}
+}
+
+// AttemptRecordFilter filters the records, the zero fields are ignored.
+type AttemptRecordFilter struct {
+	Ip         string
+	Kind       BruteAttemptKind
+	LocalPort  int
+	RemotePort int
+	Variant    string
+	After      time.Time
+	Before     time.Time
+	BeforeId   int64 // for pagination, only the records with smaller ids are returned
+}
+
+func (f AttemptRecordFilter) apply(tx *gorm.DB, table string) *gorm.DB {
+	column := func(name string) string {
+		return table + "." + name
+	}
+	if f.Ip != "" {
+		tx = tx.Where(column("ip")+" = ?", f.Ip)
+	}
+	if f.Kind != 0 {
+		tx = tx.Where(column("kind")+" = ?", f.Kind)
+	}
+	if f.LocalPort != 0 {
+		tx = tx.Where(column("local_port")+" = ?", f.LocalPort)
+	}
+	if f.RemotePort != 0 {
+		tx = tx.Where(column("remote_port")+" = ?", f.RemotePort)
+	}
+	if f.Variant != "" {
+		tx = tx.Where(column("variant")+" = ?", f.Variant)
+	}
+	if !f.After.IsZero() {
+		tx = tx.Where(column("time")+" > ?", f.After)
+	}
+	if !f.Before.IsZero() {
+		tx = tx.Where(column("time")+" < ?", f.Before)
+	}
+	if f.BeforeId != 0 {
+		tx = tx.Where(column("id")+" < ?", f.BeforeId)
+	}
+	return tx
+}
+
+// empty reports whether the filter has no condition on the records.
+func (f AttemptRecordFilter) empty() bool {
+	return f == AttemptRecordFilter{}
+}
+
+// ListAttemptRecords returns the latest records matching the filter, in descending order of id.
+func (db *Database) ListAttemptRecords(ctx context.Context, filter AttemptRecordFilter, limit int) ([]*AttemptRecord, error) {
+	var ret []*AttemptRecord
+	err := filter.apply(db.withContext(ctx).Model(&AttemptRecord{}), "attempt_records").
+		Order("id DESC").
+		Limit(limit).
+		Find(&ret).
+		Error
+	return ret, err
+}
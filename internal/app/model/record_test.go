@@ -0,0 +1,132 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDatabase_ListAttemptRecords(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDatabase(t)
+
+	now := time.Now()
+	for i, v := range []struct {
+		ip        string
+		kind      BruteAttemptKind
+		localPort int
+		variant   string
+	}{
+		{"10.0.0.1", BruteAttemptKindSsh, 22, "password"},
+		{"10.0.0.1", BruteAttemptKindSsh, 2222, "password"},
+		{"10.0.0.2", BruteAttemptKindHttp, 80, "plain"},
+		{"10.0.0.2", BruteAttemptKindHttp, 443, "tls"},
+	} {
+		timestamp := now.Add(time.Duration(i) * time.Second)
+		attempt, err := db.IncrBruteAttempt(ctx, v.ip, v.kind, timestamp, "user", "password", "client", v.localPort, AttemptWindow{Gap: time.Hour})
+		require.NoError(t, err)
+		require.NoError(t, db.Create(ctx, &AttemptRecord{
+			AttemptId: attempt.Id,
+			Ip:        v.ip,
+			Kind:      v.kind,
+			LocalPort: v.localPort,
+			Variant:   v.variant,
+			Extra:     map[string]string{"index": string(rune('0' + i))},
+			Time:      timestamp,
+		}))
+	}
+
+	t.Run("all", func(t *testing.T) {
+		records, err := db.ListAttemptRecords(ctx, AttemptRecordFilter{}, 10)
+		require.NoError(t, err)
+		require.Len(t, records, 4)
+		assert.Equal(t, 443, records[0].LocalPort)
+		assert.Equal(t, map[string]string{"index": "3"}, records[0].Extra)
+	})
+
+	t.Run("filter", func(t *testing.T) {
+		records, err := db.ListAttemptRecords(ctx, AttemptRecordFilter{Kind: BruteAttemptKindSsh, LocalPort: 2222}, 10)
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+		assert.Equal(t, "10.0.0.1", records[0].Ip)
+
+		records, err = db.ListAttemptRecords(ctx, AttemptRecordFilter{Variant: "tls"}, 10)
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+		assert.Equal(t, BruteAttemptKindHttp, records[0].Kind)
+	})
+
+	t.Run("pagination", func(t *testing.T) {
+		records, err := db.ListAttemptRecords(ctx, AttemptRecordFilter{}, 3)
+		require.NoError(t, err)
+		require.Len(t, records, 3)
+		records, err = db.ListAttemptRecords(ctx, AttemptRecordFilter{BeforeId: records[2].Id}, 3)
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+		assert.Equal(t, 22, records[0].LocalPort)
+	})
+
+	t.Run("scan attempts", func(t *testing.T) {
+		var ips []string
+		require.NoError(t, db.ScanBruteAttempt(ctx, time.Time{}, AttemptRecordFilter{Variant: "tls"}, func(attempt *BruteAttempt, _ *IpGeo) bool {
+			ips = append(ips, attempt.Ip)
+			return true
+		}))
+		assert.Equal(t, []string{"10.0.0.2"}, ips)
+
+		ips = nil
+		require.NoError(t, db.ScanBruteAttempt(ctx, time.Time{}, AttemptRecordFilter{Kind: BruteAttemptKindSsh}, func(attempt *BruteAttempt, _ *IpGeo) bool {
+			ips = append(ips, attempt.Ip)
+			return true
+		}))
+		assert.Equal(t, []string{"10.0.0.1"}, ips)
+	})
+}
+
+func TestAttemptRecord_BeforeSave(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDatabase(t)
+
+	record := &AttemptRecord{
+		Ip:   "10.0.0.1",
+		Kind: BruteAttemptKindHttp,
+		Extra: map[string]string{
+			"method": "GET",
+			"host":   strings.Repeat("<", 300),
+			"path":   strings.Repeat("<>&", 100),
+		},
+		Time: time.Now(),
+	}
+	require.NoError(t, db.Create(ctx, record))
+
+	data, err := json.Marshal(record.Extra)
+	require.NoError(t, err)
+	assert.LessOrEqual(t, len(data), 2048)
+	assert.Equal(t, "GET", record.Extra["method"])
+	assert.True(t, strings.HasPrefix(record.Extra["host"], "<<<"))
+	assert.True(t, strings.HasSuffix(record.Extra["path"], "..."))
+
+	records, err := db.ListAttemptRecords(ctx, AttemptRecordFilter{}, 10)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, record.Extra, records[0].Extra)
+}
+
+func TestParseBruteAttemptKind(t *testing.T) {
+	kind, ok := ParseBruteAttemptKind("ftp")
+	assert.True(t, ok)
+	assert.Equal(t, BruteAttemptKindFtp, kind)
+
+	_, ok = ParseBruteAttemptKind("none")
+	assert.False(t, ok)
+	_, ok = ParseBruteAttemptKind("telnet")
+	assert.False(t, ok)
+}
@@ -0,0 +1,129 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package model
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func init() {
+	registerModel(new(IpReputation))
+}
+
+// IpReputation is the reputation of an ip on AbuseIPDB, by checking it or from the blacklist.
+type IpReputation struct {
+	Ip string `gorm:"primaryKey; size:39"`
+	// Score is the abuse confidence score, 0 to 100.
+	Score        int
+	UsageType    string `gorm:"size:64"`
+	Isp          string `gorm:"size:255"`
+	TotalReports int
+	// CheckedAt is the time of the last check, zero if it's only from the blacklist.
+	CheckedAt time.Time
+	// BlacklistedAt is the time of the last blacklist containing it, zero if it's not in the last blacklist.
+	BlacklistedAt time.Time `gorm:"index"`
+
+	CreatedAt time.Time `gorm:"<-:create"`
+	UpdatedAt time.Time
+}
+
+func (m *IpReputation) BeforeSave(_ *gorm.DB) error {
+	m.UsageType = truncateString(m.UsageType, 64)
+	m.Isp = truncateString(m.Isp, 255)
+	return nil
+}
+
+// Blacklisted returns whether it is in the last blacklist.
+func (m *IpReputation) Blacklisted() bool {
+	return !m.BlacklistedAt.IsZero()
+}
+
+func (db *Database) TakeIpReputation(ctx context.Context, ip string) (*IpReputation, bool, error) {
+	ret := &IpReputation{}
+	result := db.withContext(ctx).
+		Take(ret, "ip = ?", ip)
+	if err := result.Error; errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+	return ret, true, nil
+}
+
+// SaveCheckedIpReputation saves the result of a check, and keeps BlacklistedAt.
+func (db *Database) SaveCheckedIpReputation(ctx context.Context, reputation *IpReputation) error {
+	return db.withContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "ip"}},
+			DoUpdates: clause.AssignmentColumns([]string{
+				"score", "usage_type", "isp", "total_reports", "checked_at", "updated_at",
+			}),
+		}).
+		Create(reputation).
+		Error
+}
+
+// SaveBlacklistedIpReputations saves the ips of a blacklist fetched at the time, and keeps the other fields of the checked ones.
+// The ips of the previous blacklists which are not in this one are no longer blacklisted,
+// they are deleted if they have never been checked.
+func (db *Database) SaveBlacklistedIpReputations(ctx context.Context, reputations []*IpReputation, fetchedAt time.Time) error {
+	return db.withContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if len(reputations) > 0 {
+			if err := tx.
+				Clauses(clause.OnConflict{
+					Columns:   []clause.Column{{Name: "ip"}},
+					DoUpdates: clause.AssignmentColumns([]string{"score", "blacklisted_at", "updated_at"}),
+				}).
+				CreateInBatches(reputations, 500).
+				Error; err != nil {
+				return err
+			}
+		}
+
+		stale := func() *gorm.DB {
+			return tx.Model(&IpReputation{}).Where("blacklisted_at > ? AND blacklisted_at < ?", time.Time{}, fetchedAt)
+		}
+		if err := stale().Where("checked_at = ?", time.Time{}).Delete(&IpReputation{}).Error; err != nil {
+			return err
+		}
+		return stale().Update("blacklisted_at", time.Time{}).Error
+	})
+}
+
+// LastBlacklistedAt returns the time of the last blacklist saved, zero if there isn't any.
+func (db *Database) LastBlacklistedAt(ctx context.Context) (time.Time, error) {
+	ret := &IpReputation{}
+	result := db.withContext(ctx).
+		Order("blacklisted_at DESC").
+		Take(ret)
+	if err := result.Error; errors.Is(err, gorm.ErrRecordNotFound) {
+		return time.Time{}, nil
+	} else if err != nil {
+		return time.Time{}, err
+	}
+	return ret.BlacklistedAt, nil
+}
+
+// ListIpReputations returns the reputations of the ips by ips, the unknown ones are absent.
+func (db *Database) ListIpReputations(ctx context.Context, ips []string) (map[string]*IpReputation, error) {
+	ret := make(map[string]*IpReputation, len(ips))
+	// in batches, since the databases limit the count of the parameters
+	for i := 0; i < len(ips); i += 500 {
+		var reputations []*IpReputation
+		if err := db.withContext(ctx).
+			Where("ip IN ?", ips[i:min(i+500, len(ips))]).
+			Find(&reputations).Error; err != nil {
+			return nil, err
+		}
+		for _, v := range reputations {
+			ret[v.Ip] = v
+		}
+	}
+	return ret, nil
+}
@@ -0,0 +1,90 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package model
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDatabase_IpReputation(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDatabase(t)
+
+	now := time.Now().Truncate(time.Second)
+	require.NoError(t, db.SaveCheckedIpReputation(ctx, &IpReputation{
+		Ip:           "10.0.0.1",
+		Score:        50,
+		UsageType:    "Data Center/Web Hosting/Transit",
+		Isp:          "Example Inc.",
+		TotalReports: 10,
+		CheckedAt:    now,
+	}))
+	last, err := db.LastBlacklistedAt(ctx)
+	require.NoError(t, err)
+	assert.True(t, last.IsZero())
+	require.NoError(t, db.SaveBlacklistedIpReputations(ctx, []*IpReputation{
+		{Ip: "10.0.0.1", Score: 100, BlacklistedAt: now},
+		{Ip: "10.0.0.2", Score: 90, BlacklistedAt: now},
+	}, now))
+	last, err = db.LastBlacklistedAt(ctx)
+	require.NoError(t, err)
+	assert.True(t, now.Equal(last))
+
+	got, ok, err := db.TakeIpReputation(ctx, "10.0.0.1")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, 100, got.Score)
+	assert.Equal(t, "Example Inc.", got.Isp, "the checked fields are kept")
+	assert.True(t, got.Blacklisted())
+	assert.True(t, now.Equal(got.CheckedAt))
+
+	// checked again
+	require.NoError(t, db.SaveCheckedIpReputation(ctx, &IpReputation{
+		Ip:        "10.0.0.2",
+		Score:     80,
+		Isp:       "Example Inc.",
+		CheckedAt: now,
+	}))
+	got, ok, err = db.TakeIpReputation(ctx, "10.0.0.2")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, 80, got.Score)
+	assert.True(t, got.Blacklisted(), "the blacklisted time is kept")
+
+	reputations, err := db.ListIpReputations(ctx, []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"})
+	require.NoError(t, err)
+	assert.Len(t, reputations, 2)
+	assert.Contains(t, reputations, "10.0.0.1")
+	assert.NotContains(t, reputations, "10.0.0.3")
+
+	_, ok, err = db.TakeIpReputation(ctx, "10.0.0.3")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// a later blacklist without 10.0.0.1 and 10.0.0.2
+	later := now.Add(time.Hour)
+	require.NoError(t, db.SaveBlacklistedIpReputations(ctx, []*IpReputation{
+		{Ip: "10.0.0.3", Score: 100, BlacklistedAt: later},
+	}, later))
+	got, ok, err = db.TakeIpReputation(ctx, "10.0.0.1")
+	require.NoError(t, err)
+	require.True(t, ok, "the checked one is kept")
+	assert.False(t, got.Blacklisted())
+	assert.Equal(t, "Example Inc.", got.Isp)
+	got, ok, err = db.TakeIpReputation(ctx, "10.0.0.3")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.True(t, got.Blacklisted())
+
+	// an empty blacklist, the one never checked is deleted
+	require.NoError(t, db.SaveBlacklistedIpReputations(ctx, nil, later.Add(time.Hour)))
+	_, ok, err = db.TakeIpReputation(ctx, "10.0.0.3")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
@@ -0,0 +1,131 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package model
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func init() {
+	registerModel(new(AttemptDaily))
+}
+
+// AttemptDaily is the daily count of the attempt records of an ip, it keeps the history after the records are pruned.
+type AttemptDaily struct {
+	Id    int64
+	Day   string           `gorm:"size:10;uniqueIndex:day_ip_kind"` // in the format of "2006-01-02", UTC
+	Ip    string           `gorm:"size:39;uniqueIndex:day_ip_kind"`
+	Kind  BruteAttemptKind `gorm:"uniqueIndex:day_ip_kind"`
+	Count int64
+
+	CreatedAt time.Time `gorm:"<-:create"`
+	UpdatedAt time.Time
+}
+
+// RetentionTable is a table which can be pruned.
+type RetentionTable struct {
+	Name       string
+	model      any
+	primaryKey string
+	column     string // the time column to compare with
+}
+
+var (
+	RetentionBruteAttempts      = &RetentionTable{"brute_attempts", new(BruteAttempt), "id", "stopped_at"}
+	RetentionAttemptRecords     = &RetentionTable{"attempt_records", new(AttemptRecord), "id", "time"}
+	RetentionSessionCommands    = &RetentionTable{"session_commands", new(SessionCommand), "id", "time"}
+	RetentionTcpPayloads        = &RetentionTable{"tcp_payloads", new(TcpPayload), "id", "time"}
+	RetentionIpGeos             = &RetentionTable{"ip_geos", new(IpGeo), "ip", "updated_at"}
+	RetentionIpReputations      = &RetentionTable{"ip_reputations", new(IpReputation), "ip", "updated_at"}
+	RetentionAbuseipdbReports   = &RetentionTable{"abuseipdb_reports", new(AbuseipdbReport), "id", "reported_at"}
+	RetentionAbuseipdbPendings  = &RetentionTable{"abuseipdb_pendings", new(AbuseipdbPending), "id", "attempted_at"}
+	RetentionCampaigns          = &RetentionTable{"campaigns", new(Campaign), "id", "stopped_at"}
+	RetentionCampaignMembers    = &RetentionTable{"campaign_members", new(CampaignMember), "id", "updated_at"}
+	RetentionAttemptDailies     = &RetentionTable{"attempt_dailies", new(AttemptDaily), "id", "updated_at"}
+	RetentionAttemptStats       = &RetentionTable{"attempt_stats", new(AttemptStat), "id", "start"}
+	RetentionAttemptStatMembers = &RetentionTable{"attempt_stat_members", new(AttemptStatMember), "id", "start"}
+)
+
+// PruneBatch deletes at most limit rows of the table older than the time, and returns the number of deleted rows.
+// It deletes by primary keys after selecting them, so the rows are locked only for a short time.
+func (db *Database) PruneBatch(ctx context.Context, table *RetentionTable, before time.Time, limit int) (int64, error) {
+	primaryKey := table.primaryKey
+	sess := db.withContext(ctx)
+	var keys []any
+	if err := sess.Model(table.model).
+		Where(table.column+" < ?", before).
+		Order(table.column).
+		Limit(limit).
+		Pluck(primaryKey, &keys).Error; err != nil {
+		return 0, err
+	}
+	if len(keys) == 0 {
+		return 0, nil
+	}
+
+	// check the time again, since the rows could be updated after being selected, like a continuing brute attempt
+	result := sess.
+		Where(primaryKey+" IN ?", keys).
+		Where(table.column+" < ?", before).
+		Delete(table.model)
+	return result.RowsAffected, result.Error
+}
+
+// DownsampleAttemptRecords adds at most limit attempt records older than the time to the daily counts,
+// then deletes them. It returns the number of deleted records.
+func (db *Database) DownsampleAttemptRecords(ctx context.Context, before time.Time, limit int) (int64, error) {
+	var deleted int64
+	return deleted, db.withContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var records []*AttemptRecord
+		if err := tx.
+			Select("id", "ip", "kind", "time").
+			Where("time < ?", before).
+			Order("time").
+			Limit(limit).
+			Find(&records).Error; err != nil {
+			return err
+		}
+		if len(records) == 0 {
+			return nil
+		}
+
+		type key struct {
+			day  string
+			ip   string
+			kind BruteAttemptKind
+		}
+		var dailies []*AttemptDaily
+		index := map[key]*AttemptDaily{}
+		ids := make([]int64, 0, len(records))
+		for _, r := range records {
+			k := key{r.Time.UTC().Format(time.DateOnly), r.Ip, r.Kind}
+			daily, ok := index[k]
+			if !ok {
+				daily = &AttemptDaily{Day: k.day, Ip: k.ip, Kind: k.kind}
+				index[k] = daily
+				dailies = append(dailies, daily)
+			}
+			daily.Count++
+			ids = append(ids, r.Id)
+		}
+
+		if err := tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "day"}, {Name: "ip"}, {Name: "kind"}},
+			DoUpdates: clause.Assignments(map[string]any{
+				"count":      gorm.Expr("attempt_dailies.count + " + db.excluded("count")),
+				"updated_at": gorm.Expr(db.excluded("updated_at")),
+			}),
+		}).Create(&dailies).Error; err != nil {
+			return err
+		}
+
+		result := tx.Where("id IN ?", ids).Delete(&AttemptRecord{})
+		deleted = result.RowsAffected
+		return result.Error
+	})
+}
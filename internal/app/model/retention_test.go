@@ -0,0 +1,102 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package model
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDatabase_PruneBatch(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDatabase(t)
+
+	now := time.Now()
+	for i := 0; i < 5; i++ {
+		require.NoError(t, db.Create(ctx, &BruteAttempt{
+			Ip:        "10.0.0.1",
+			Kind:      BruteAttemptKindSsh,
+			Seq:       int64(5 - i),
+			StartedAt: now.Add(-time.Duration(i) * time.Hour),
+			StoppedAt: now.Add(-time.Duration(i) * time.Hour),
+			Count:     1,
+		}))
+		require.NoError(t, db.Save(ctx, &IpGeo{Ip: "10.0.0." + string(rune('1'+i))}))
+	}
+
+	deleted, err := db.PruneBatch(ctx, RetentionBruteAttempts, now.Add(-90*time.Minute), 2)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), deleted)
+	deleted, err = db.PruneBatch(ctx, RetentionBruteAttempts, now.Add(-90*time.Minute), 2)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+
+	var count int64
+	require.NoError(t, db.db.Model(&BruteAttempt{}).Count(&count).Error)
+	assert.Equal(t, int64(2), count)
+
+	deleted, err = db.PruneBatch(ctx, RetentionIpGeos, time.Now().Add(time.Second), 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), deleted)
+
+	require.NoError(t, db.Create(ctx, &Campaign{Network: "10.0.0.0/24", StartedAt: now.Add(-3 * time.Hour), StoppedAt: now.Add(-2 * time.Hour)}))
+	require.NoError(t, db.Create(ctx, &Campaign{Network: "10.0.1.0/24", StartedAt: now, StoppedAt: now}))
+	deleted, err = db.PruneBatch(ctx, RetentionCampaigns, now.Add(-time.Hour), 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+}
+
+func TestDatabase_DownsampleAttemptRecords(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDatabase(t)
+
+	day := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	for _, v := range []struct {
+		ip   string
+		kind BruteAttemptKind
+		time time.Time
+	}{
+		{"10.0.0.1", BruteAttemptKindSsh, day},
+		{"10.0.0.1", BruteAttemptKindSsh, day.Add(time.Hour)},
+		{"10.0.0.1", BruteAttemptKindSsh, day.Add(24 * time.Hour)},
+		{"10.0.0.1", BruteAttemptKindHttp, day.Add(2 * time.Hour)},
+		{"10.0.0.2", BruteAttemptKindSsh, day.Add(3 * time.Hour)},
+		{"10.0.0.2", BruteAttemptKindSsh, day.Add(72 * time.Hour)}, // not old enough
+	} {
+		require.NoError(t, db.Create(ctx, &AttemptRecord{Ip: v.ip, Kind: v.kind, Time: v.time}))
+	}
+
+	before := day.Add(48 * time.Hour)
+	// the batches split the records of the same day, they are still counted correctly
+	deleted, err := db.DownsampleAttemptRecords(ctx, before, 3)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), deleted)
+	deleted, err = db.DownsampleAttemptRecords(ctx, before, 3)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), deleted)
+	deleted, err = db.DownsampleAttemptRecords(ctx, before, 3)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), deleted)
+
+	var dailies []*AttemptDaily
+	require.NoError(t, db.db.Order("day, ip, kind").Find(&dailies).Error)
+	var got []string
+	for _, v := range dailies {
+		got = append(got, v.Day+" "+v.Ip+" "+v.Kind.String()+" "+string(rune('0'+v.Count)))
+	}
+	assert.Equal(t, []string{
+		"2024-03-01 10.0.0.1 ssh 2",
+		"2024-03-01 10.0.0.1 http 1",
+		"2024-03-01 10.0.0.2 ssh 1",
+		"2024-03-02 10.0.0.1 ssh 1",
+	}, got)
+
+	var count int64
+	require.NoError(t, db.db.Model(&AttemptRecord{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+}
@@ -0,0 +1,33 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	registerModel(new(SessionCommand))
+}
+
+// SessionCommand is a command sent by a client in a session, for protocols which allow interaction.
+type SessionCommand struct {
+	Id        int64
+	SessionId string           `gorm:"size:64;index"`
+	Ip        string           `gorm:"size:39;index"`
+	Kind      BruteAttemptKind `gorm:"index"`
+	Command   string           `gorm:"size:1024"`
+	Flag      string           `gorm:"size:64"` // the known exploitation detected, empty if none
+	Time      time.Time        `gorm:"index"`
+
+	CreatedAt time.Time `gorm:"<-:create"`
+}
+
+func (c *SessionCommand) BeforeSave(_ *gorm.DB) error {
+	c.Command = truncateString(c.Command, 1024)
+	c.Flag = truncateString(c.Flag, 64)
+	return nil
+}
@@ -0,0 +1,183 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package model
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func init() {
+	registerModel(new(AttemptStat))
+	registerModel(new(AttemptStatMember))
+}
+
+// StatPeriod is the granularity of the rollup statistics.
+type StatPeriod string
+
+const (
+	StatPeriodHour StatPeriod = "hour"
+	StatPeriodDay  StatPeriod = "day"
+)
+
+// StatPeriods are the periods maintained for every attempt.
+var StatPeriods = []StatPeriod{StatPeriodHour, StatPeriodDay}
+
+// ParseStatPeriod returns the period of the name.
+func ParseStatPeriod(name string) (StatPeriod, bool) {
+	for _, v := range StatPeriods {
+		if string(v) == name {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// Truncate returns the start of the period which the time is in, in UTC.
+func (p StatPeriod) Truncate(t time.Time) time.Time {
+	t = t.UTC()
+	if p == StatPeriodDay {
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	return t.Truncate(time.Hour)
+}
+
+// AttemptStat is the rollup of the attempts in a period, of a kind, from a country and an AS.
+// It's updated incrementally when handling attempts, so the charts don't have to scan the raw records.
+type AttemptStat struct {
+	Id                int64
+	Period            StatPeriod       `gorm:"size:8;uniqueIndex:attempt_stat_key"`
+	Start             time.Time        `gorm:"uniqueIndex:attempt_stat_key"`
+	Kind              BruteAttemptKind `gorm:"uniqueIndex:attempt_stat_key"`
+	CountryCode       string           `gorm:"size:2;uniqueIndex:attempt_stat_key"` // empty if unknown
+	Asn               int              `gorm:"uniqueIndex:attempt_stat_key"`        // 0 if unknown
+	Attempts          int64
+	UniqueIps         int64
+	UniqueCredentials int64
+
+	CreatedAt time.Time `gorm:"<-:create"`
+	UpdatedAt time.Time
+}
+
+// AttemptStatMember is a distinct value which has been counted in an AttemptStat,
+// it's only useful until the period ends, so it can be pruned soon.
+type AttemptStatMember struct {
+	Id          int64
+	Period      StatPeriod       `gorm:"size:8;uniqueIndex:attempt_stat_member_key"`
+	Start       time.Time        `gorm:"uniqueIndex:attempt_stat_member_key;index"`
+	Kind        BruteAttemptKind `gorm:"uniqueIndex:attempt_stat_member_key"`
+	CountryCode string           `gorm:"size:2;uniqueIndex:attempt_stat_member_key"`
+	Asn         int              `gorm:"uniqueIndex:attempt_stat_member_key"`
+	Type        string           `gorm:"size:16;uniqueIndex:attempt_stat_member_key"` // "ip" or "credential"
+	Value       string           `gorm:"size:64;uniqueIndex:attempt_stat_member_key"` // the ip, or the sha256 of the credential
+}
+
+// IncrAttemptStats counts an attempt into the stats of all periods.
+func (db *Database) IncrAttemptStats(ctx context.Context, timestamp time.Time, kind BruteAttemptKind, countryCode string, asn int, ip, user, password string) error {
+	credential := sha256.Sum256([]byte(fmt.Sprintf("%q:%q", user, password)))
+	for _, period := range StatPeriods {
+		if err := db.incrAttemptStat(ctx, &AttemptStat{
+			Period:      period,
+			Start:       period.Truncate(timestamp),
+			Kind:        kind,
+			CountryCode: countryCode,
+			Asn:         asn,
+		}, ip, hex.EncodeToString(credential[:])); err != nil {
+			return fmt.Errorf("incr %s stat: %w", period, err)
+		}
+	}
+	return nil
+}
+
+func (db *Database) incrAttemptStat(ctx context.Context, stat *AttemptStat, ip, credential string) error {
+	return db.withContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// a member is new only if it's inserted
+		isNew := func(typ, value string) (int64, error) {
+			result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&AttemptStatMember{
+				Period:      stat.Period,
+				Start:       stat.Start,
+				Kind:        stat.Kind,
+				CountryCode: stat.CountryCode,
+				Asn:         stat.Asn,
+				Type:        typ,
+				Value:       value,
+			})
+			return result.RowsAffected, result.Error
+		}
+		var err error
+		if stat.UniqueIps, err = isNew("ip", ip); err != nil {
+			return err
+		}
+		if stat.UniqueCredentials, err = isNew("credential", credential); err != nil {
+			return err
+		}
+		stat.Attempts = 1
+
+		return tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "period"}, {Name: "start"}, {Name: "kind"}, {Name: "country_code"}, {Name: "asn"}},
+			DoUpdates: clause.Assignments(map[string]any{
+				// qualified, or they are ambiguous with the excluded values in postgres
+				"attempts":           gorm.Expr("attempt_stats.attempts + ?", stat.Attempts),
+				"unique_ips":         gorm.Expr("attempt_stats.unique_ips + ?", stat.UniqueIps),
+				"unique_credentials": gorm.Expr("attempt_stats.unique_credentials + ?", stat.UniqueCredentials),
+				"updated_at":         time.Now(),
+			}),
+		}).Create(stat).Error
+	})
+}
+
+// AttemptStatFilter filters the stats, the zero fields are ignored except Period.
+type AttemptStatFilter struct {
+	Period      StatPeriod
+	Kind        BruteAttemptKind
+	CountryCode string
+	Asn         int
+	After       time.Time // inclusive, compared with the start of periods
+	Before      time.Time // exclusive
+}
+
+// AttemptStatPoint is a point of the time series of stats.
+type AttemptStatPoint struct {
+	Start             time.Time
+	Attempts          int64
+	UniqueIps         int64
+	UniqueCredentials int64
+}
+
+// ListAttemptStats returns the time series of the stats matching the filter, in ascending order of start.
+// The stats of different kinds, countries or ASes in a period are summed up,
+// so the unique counts are the upper bounds when the filter doesn't pin them down.
+func (db *Database) ListAttemptStats(ctx context.Context, filter AttemptStatFilter) ([]*AttemptStatPoint, error) {
+	tx := db.withContext(ctx).Model(&AttemptStat{}).Where("period = ?", filter.Period)
+	if filter.Kind != 0 {
+		tx = tx.Where("kind = ?", filter.Kind)
+	}
+	if filter.CountryCode != "" {
+		tx = tx.Where("country_code = ?", filter.CountryCode)
+	}
+	if filter.Asn != 0 {
+		tx = tx.Where("asn = ?", filter.Asn)
+	}
+	if !filter.After.IsZero() {
+		tx = tx.Where("start >= ?", filter.Period.Truncate(filter.After))
+	}
+	if !filter.Before.IsZero() {
+		tx = tx.Where("start < ?", filter.Before.UTC())
+	}
+
+	var ret []*AttemptStatPoint
+	err := tx.
+		Select("start, SUM(attempts) AS attempts, SUM(unique_ips) AS unique_ips, SUM(unique_credentials) AS unique_credentials").
+		Group("start").
+		Order("start").
+		Find(&ret).
+		Error
+	return ret, err
+}
@@ -0,0 +1,80 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package model
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatPeriod_Truncate(t *testing.T) {
+	timestamp := time.Date(2024, 3, 4, 5, 6, 7, 8, time.FixedZone("UTC+8", 8*3600))
+	assert.Equal(t, time.Date(2024, 3, 3, 21, 0, 0, 0, time.UTC), StatPeriodHour.Truncate(timestamp))
+	assert.Equal(t, time.Date(2024, 3, 3, 0, 0, 0, 0, time.UTC), StatPeriodDay.Truncate(timestamp))
+}
+
+func TestDatabase_IncrAttemptStats(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDatabase(t)
+
+	day := time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+	for _, v := range []struct {
+		time        time.Time
+		kind        BruteAttemptKind
+		countryCode string
+		ip          string
+		password    string
+	}{
+		{day.Add(1 * time.Hour), BruteAttemptKindSsh, "FR", "10.0.0.1", "a"},
+		{day.Add(1 * time.Hour), BruteAttemptKindSsh, "FR", "10.0.0.1", "a"},
+		{day.Add(1 * time.Hour), BruteAttemptKindSsh, "FR", "10.0.0.1", "b"},
+		{day.Add(1 * time.Hour), BruteAttemptKindSsh, "FR", "10.0.0.2", "b"},
+		{day.Add(2 * time.Hour), BruteAttemptKindSsh, "FR", "10.0.0.1", "a"},
+		{day.Add(2 * time.Hour), BruteAttemptKindHttp, "US", "10.0.0.3", "a"},
+	} {
+		require.NoError(t, db.IncrAttemptStats(ctx, v.time, v.kind, v.countryCode, 3215, v.ip, "user", v.password))
+	}
+
+	t.Run("hour", func(t *testing.T) {
+		points, err := db.ListAttemptStats(ctx, AttemptStatFilter{Period: StatPeriodHour, Kind: BruteAttemptKindSsh})
+		require.NoError(t, err)
+		require.Len(t, points, 2)
+		assert.True(t, day.Add(time.Hour).Equal(points[0].Start))
+		assert.Equal(t, &AttemptStatPoint{Start: points[0].Start, Attempts: 4, UniqueIps: 2, UniqueCredentials: 2}, points[0])
+		assert.Equal(t, &AttemptStatPoint{Start: points[1].Start, Attempts: 1, UniqueIps: 1, UniqueCredentials: 1}, points[1])
+	})
+
+	t.Run("day", func(t *testing.T) {
+		points, err := db.ListAttemptStats(ctx, AttemptStatFilter{Period: StatPeriodDay})
+		require.NoError(t, err)
+		require.Len(t, points, 1)
+		assert.True(t, day.Equal(points[0].Start))
+		// the unique counts of different kinds are summed up
+		assert.Equal(t, &AttemptStatPoint{Start: points[0].Start, Attempts: 6, UniqueIps: 3, UniqueCredentials: 3}, points[0])
+
+		points, err = db.ListAttemptStats(ctx, AttemptStatFilter{Period: StatPeriodDay, CountryCode: "US"})
+		require.NoError(t, err)
+		require.Len(t, points, 1)
+		assert.Equal(t, int64(1), points[0].Attempts)
+	})
+
+	t.Run("range", func(t *testing.T) {
+		points, err := db.ListAttemptStats(ctx, AttemptStatFilter{
+			Period: StatPeriodHour,
+			After:  day.Add(150 * time.Minute), // the period it's in is included
+			Before: day.Add(3 * time.Hour),
+		})
+		require.NoError(t, err)
+		require.Len(t, points, 1)
+		assert.Equal(t, int64(2), points[0].Attempts)
+
+		points, err = db.ListAttemptStats(ctx, AttemptStatFilter{Period: StatPeriodHour, Asn: 1})
+		require.NoError(t, err)
+		assert.Empty(t, points)
+	})
+}
@@ -0,0 +1,210 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+// Package retention prunes the old data in the background.
+package retention
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/funeypot/funeypot/internal/app/config"
+	"github.com/funeypot/funeypot/internal/app/model"
+	"github.com/funeypot/funeypot/internal/pkg/logs"
+)
+
+type tableRetention struct {
+	table     *model.RetentionTable
+	retention time.Duration
+}
+
+type Runner struct {
+	db         *model.Database
+	interval   time.Duration
+	batchSize  int
+	batchPause time.Duration
+	downsample bool
+	tables     []tableRetention
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+func NewRunner(cfg config.Retention, db *model.Database) *Runner {
+	if !cfg.Enabled {
+		return nil
+	}
+
+	ret := &Runner{
+		db:         db,
+		interval:   cfg.Interval,
+		batchSize:  cfg.BatchSize,
+		batchPause: cfg.BatchPause,
+		downsample: cfg.Downsample,
+		stop:       make(chan struct{}),
+	}
+	for _, v := range []tableRetention{
+		{model.RetentionAttemptRecords, cfg.Tables.AttemptRecords},
+		{model.RetentionBruteAttempts, cfg.Tables.BruteAttempts},
+		{model.RetentionSessionCommands, cfg.Tables.SessionCommands},
+		{model.RetentionTcpPayloads, cfg.Tables.TcpPayloads},
+		{model.RetentionIpGeos, cfg.Tables.IpGeos},
+		{model.RetentionIpReputations, cfg.Tables.IpReputations},
+		{model.RetentionAbuseipdbReports, cfg.Tables.AbuseipdbReports},
+		{model.RetentionAbuseipdbPendings, cfg.Tables.AbuseipdbPendings},
+		{model.RetentionCampaigns, cfg.Tables.Campaigns},
+		{model.RetentionCampaignMembers, cfg.Tables.CampaignMembers},
+		{model.RetentionAttemptDailies, cfg.Tables.AttemptDailies},
+		{model.RetentionAttemptStats, cfg.Tables.AttemptStats},
+		{model.RetentionAttemptStatMembers, cfg.Tables.AttemptStatMembers},
+	} {
+		if v.retention > 0 {
+			ret.tables = append(ret.tables, v)
+		}
+	}
+	return ret
+}
+
+func (r *Runner) Enabled() bool {
+	return r != nil
+}
+
+func (r *Runner) Startup(ctx context.Context, _ context.CancelFunc) {
+	logger := logs.From(ctx)
+
+	if !r.Enabled() {
+		logger.Infof("skip starting retention since it is not enabled")
+		return
+	}
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		logger.Infof("start retention, run every %v for %d tables", r.interval, len(r.tables))
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		go func() {
+			select {
+			case <-r.stop:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+		for {
+			r.Run(ctx)
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+func (r *Runner) Shutdown(ctx context.Context) error {
+	if !r.Enabled() {
+		return nil
+	}
+	logs.From(ctx).Infof("shutdown retention")
+	close(r.stop)
+	r.wg.Wait()
+	return nil
+}
+
+// TableSummary is what has been done to a table in a run.
+type TableSummary struct {
+	Table       string
+	Before      time.Time
+	Deleted     int64
+	Downsampled bool
+	Err         error
+}
+
+type Summary struct {
+	Tables   []*TableSummary
+	Duration time.Duration
+}
+
+func (s *Summary) Deleted() int64 {
+	var ret int64
+	for _, v := range s.Tables {
+		ret += v.Deleted
+	}
+	return ret
+}
+
+// Run prunes the tables once, and logs the summary.
+func (r *Runner) Run(ctx context.Context) *Summary {
+	logger := logs.From(ctx)
+
+	start := time.Now()
+	summary := &Summary{}
+	for _, v := range r.tables {
+		table := r.runTable(ctx, v, start.Add(-v.retention))
+		summary.Tables = append(summary.Tables, table)
+
+		tableLogger := logger.With(
+			"table", table.Table,
+			"before", table.Before.Format(time.RFC3339),
+			"deleted", table.Deleted,
+			"downsampled", table.Downsampled,
+		)
+		if table.Err != nil {
+			tableLogger.Errorf("prune table: %v", table.Err)
+		} else if table.Deleted > 0 {
+			tableLogger.Infof("pruned table")
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	summary.Duration = time.Since(start)
+
+	logger.With(
+		"deleted", summary.Deleted(),
+		"duration", summary.Duration.String(),
+	).Infof("retention done")
+	return summary
+}
+
+func (r *Runner) runTable(ctx context.Context, v tableRetention, before time.Time) *TableSummary {
+	ret := &TableSummary{
+		Table:       v.table.Name,
+		Before:      before,
+		Downsampled: r.downsample && v.table == model.RetentionAttemptRecords,
+	}
+
+	for {
+		var (
+			deleted int64
+			err     error
+		)
+		if ret.Downsampled {
+			deleted, err = r.db.DownsampleAttemptRecords(ctx, before, r.batchSize)
+		} else {
+			deleted, err = r.db.PruneBatch(ctx, v.table, before, r.batchSize)
+		}
+		ret.Deleted += deleted
+		if err != nil {
+			ret.Err = fmt.Errorf("delete batch: %w", err)
+			return ret
+		}
+		if deleted < int64(r.batchSize) {
+			return ret
+		}
+
+		// give way to the other transactions, like IncrBruteAttempt of the handler
+		select {
+		case <-ctx.Done():
+			ret.Err = ctx.Err()
+			return ret
+		case <-time.After(r.batchPause):
+		}
+	}
+}
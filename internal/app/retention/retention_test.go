@@ -0,0 +1,64 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package retention
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/funeypot/funeypot/internal/app/config"
+	"github.com/funeypot/funeypot/internal/app/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunner_Run(t *testing.T) {
+	ctx := context.Background()
+	db, err := model.NewDatabase(ctx, config.Database{
+		Driver: "sqlite",
+		Dsn:    filepath.Join(t.TempDir(), "funeypot.db"),
+	})
+	require.NoError(t, err)
+
+	now := time.Now()
+	for i := 0; i < 10; i++ {
+		timestamp := now.Add(-time.Duration(i) * 24 * time.Hour)
+		require.NoError(t, db.Create(ctx, &model.AttemptRecord{Ip: "10.0.0.1", Kind: model.BruteAttemptKindSsh, Time: timestamp}))
+		require.NoError(t, db.Create(ctx, &model.TcpPayload{Ip: "10.0.0.1", Port: 23, Time: timestamp}))
+	}
+
+	cfg := config.Retention{
+		Enabled:    true,
+		Interval:   time.Hour,
+		BatchSize:  2,
+		Downsample: true,
+	}
+	cfg.Tables.AttemptRecords = 72 * time.Hour
+	cfg.Tables.TcpPayloads = 120 * time.Hour
+	runner := NewRunner(cfg, db)
+
+	summary := runner.Run(ctx)
+	require.Len(t, summary.Tables, 2)
+	assert.Equal(t, "attempt_records", summary.Tables[0].Table)
+	assert.Equal(t, int64(7), summary.Tables[0].Deleted)
+	assert.True(t, summary.Tables[0].Downsampled)
+	assert.NoError(t, summary.Tables[0].Err)
+	assert.Equal(t, "tcp_payloads", summary.Tables[1].Table)
+	assert.Equal(t, int64(5), summary.Tables[1].Deleted)
+	assert.False(t, summary.Tables[1].Downsampled)
+	assert.Equal(t, int64(12), summary.Deleted())
+
+	summary = runner.Run(ctx)
+	assert.Equal(t, int64(0), summary.Deleted())
+}
+
+func TestRunner_Disabled(t *testing.T) {
+	runner := NewRunner(config.Retention{}, nil)
+	assert.False(t, runner.Enabled())
+	runner.Startup(context.Background(), nil)
+	assert.NoError(t, runner.Shutdown(context.Background()))
+}
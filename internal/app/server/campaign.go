@@ -0,0 +1,91 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+
+	"github.com/funeypot/funeypot/internal/app/model"
+	"github.com/funeypot/funeypot/internal/app/sink"
+	"github.com/funeypot/funeypot/internal/pkg/ipgeo"
+	"github.com/funeypot/funeypot/internal/pkg/logs"
+)
+
+// incrCampaigns counts the request into the campaigns of its networks,
+// and returns the detected campaign with the most ips, or nil if there isn't.
+func (h *Handler) incrCampaigns(ctx context.Context, request *Request, geo *ipgeo.Info) *model.Campaign {
+	logger := logs.From(ctx)
+
+	if !h.campaign.Enabled {
+		return nil
+	}
+
+	type network struct {
+		typ  string
+		name string
+	}
+	var networks []network
+	if prefix, ok := networkPrefix(request.Ip, h.campaign.Ipv4Prefix, h.campaign.Ipv6Prefix); ok {
+		networks = append(networks, network{model.CampaignTypePrefix, prefix})
+	}
+	if h.campaign.Asn && geo.Asn != 0 {
+		networks = append(networks, network{model.CampaignTypeAsn, fmt.Sprintf("AS%d", geo.Asn)})
+	}
+
+	rule := model.CampaignRule{
+		Gap:    h.campaign.Gap,
+		MinIps: int64(h.campaign.MinIps),
+	}
+	var ret *model.Campaign
+	for _, v := range networks {
+		campaign, detected, err := h.db.IncrCampaign(ctx, v.typ, v.name, request.Kind, request.Ip, request.Time, rule)
+		if err != nil {
+			logger.Errorf("incr campaign of %s: %v", v.name, err)
+			continue
+		}
+		if detected {
+			logger.With(
+				"network", campaign.Network,
+				"ips", campaign.Ips,
+				"attempts", campaign.Attempts,
+				"duration", campaign.StoppedAt.Sub(campaign.StartedAt).String(),
+			).Warnf("campaign detected")
+			h.currentSinks().Send(ctx, &sink.Event{
+				Type:     sink.EventTypeCampaign,
+				Time:     request.Time,
+				Kind:     request.Kind,
+				Ip:       request.Ip,
+				Campaign: campaign,
+			})
+		}
+		if campaign.Detected() && (ret == nil || campaign.Ips > ret.Ips) {
+			ret = campaign
+		}
+	}
+	return ret
+}
+
+// networkPrefix returns the network of the ip with the prefix length of its version, like "203.0.113.0/24".
+// It returns false if the prefix length of the version is 0.
+func networkPrefix(ip string, ipv4Bits, ipv6Bits int) (string, bool) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return "", false
+	}
+	addr = addr.Unmap()
+	bits := ipv6Bits
+	if addr.Is4() {
+		bits = ipv4Bits
+	}
+	if bits == 0 {
+		return "", false
+	}
+	prefix, err := addr.Prefix(bits)
+	if err != nil {
+		return "", false
+	}
+	return prefix.String(), true
+}
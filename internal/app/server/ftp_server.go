@@ -19,21 +19,34 @@ import (
 )
 
 type FtpServer struct {
-	server *ftpserver.FtpServer
-	addr   string
+	server    *ftpserver.FtpServer
+	addr      string
+	banner    string
+	listening bool
 
 	handler *Handler
 }
 
 var _ Server = (*FtpServer)(nil)
 
-func NewFtpServer(cfg config.Ftp, handler *Handler) *FtpServer {
+// NewFtpServers returns a server for each listener, or nil if it is not enabled.
+func NewFtpServers(cfg config.Ftp, handler *Handler) []*FtpServer {
 	if !cfg.Enabled {
 		return nil
 	}
 
+	listeners := cfg.AllListeners()
+	ret := make([]*FtpServer, 0, len(listeners))
+	for _, v := range listeners {
+		ret = append(ret, newFtpServer(v, handler))
+	}
+	return ret
+}
+
+func newFtpServer(cfg config.FtpListener, handler *Handler) *FtpServer {
 	ret := &FtpServer{
 		addr:    cfg.Address,
+		banner:  cfg.Banner,
 		handler: handler,
 	}
 
@@ -53,24 +66,49 @@ func (s *FtpServer) Startup(ctx context.Context, cancel context.CancelFunc) {
 		logger.Infof("skip starting ftp server since it is not enabled")
 		return
 	}
+	logger.Infof("start ftp server, listen on %s", s.addr)
+	if !s.listening {
+		if err := s.Listen(); err != nil {
+			logger.Errorf("listen: %v", err)
+			cancel()
+			return
+		}
+	}
 	go func() {
-		logger.Infof("start ftp server, listen on %s", s.addr)
-		if err := s.server.ListenAndServe(); err != nil {
-			logger.Errorf("listen and serve: %v", err)
+		if err := s.server.Serve(); err != nil {
+			logger.Errorf("serve: %v", err)
+			cancel()
 		}
-		cancel()
 	}()
 }
 
+func (s *FtpServer) Listen() error {
+	if !s.Enabled() {
+		return nil
+	}
+	if err := s.server.Listen(); err != nil {
+		return err
+	}
+	s.listening = true
+	return nil
+}
+
 func (s *FtpServer) Shutdown(ctx context.Context) error {
 	if !s.Enabled() {
 		return nil
 	}
 
-	logs.From(ctx).Infof("shutdown ftp server")
+	logs.From(ctx).Infof("shutdown ftp server on %s", s.addr)
 	return s.server.Stop()
 }
 
+func (s *FtpServer) Addr() string {
+	if !s.Enabled() {
+		return ""
+	}
+	return s.addr
+}
+
 var _ ftpserver.MainDriver = (*FtpServer)(nil)
 
 func (s *FtpServer) GetSettings() (*ftpserver.Settings, error) {
@@ -81,7 +119,7 @@ func (s *FtpServer) GetSettings() (*ftpserver.Settings, error) {
 
 func (s *FtpServer) ClientConnected(cc ftpserver.ClientContext) (string, error) {
 	logs.Default().Debugf("ftp client connected: %s", cc.RemoteAddr().String())
-	return "", nil
+	return s.banner, nil
 }
 
 func (s *FtpServer) ClientDisconnected(cc ftpserver.ClientContext) {
@@ -98,6 +136,10 @@ func (s *FtpServer) AuthUser(cc ftpserver.ClientContext, user, pass string) (ftp
 	if err != nil || net.ParseIP(ip) == nil {
 		logger.Warnf("invalid remote addr %q: %v", remoteAddr, err)
 	} else {
+		variant := "plain"
+		if cc.HasTLSForControl() {
+			variant = "tls"
+		}
 		s.handler.Handle(ctx, &Request{
 			Kind:          model.BruteAttemptKindFtp,
 			Ip:            ip,
@@ -106,6 +148,13 @@ func (s *FtpServer) AuthUser(cc ftpserver.ClientContext, user, pass string) (ftp
 			Password:      pass,
 			SessionId:     uuid.New().String(),
 			ClientVersion: cc.GetClientVersion(),
+			LocalAddr:     addrString(cc.LocalAddr()),
+			LocalPort:     addrPort(cc.LocalAddr()),
+			RemotePort:    addrPort(cc.RemoteAddr()),
+			Variant:       variant,
+			Extra: map[string]string{
+				"command": "PASS",
+			},
 		})
 	}
 
@@ -8,6 +8,7 @@ import (
 	"errors"
 	"net"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -20,7 +21,8 @@ import (
 )
 
 type HttpServer struct {
-	server *http.Server
+	server   *http.Server
+	listener net.Listener // nil until it listens
 
 	handler         *Handler
 	dashboardServer *dashboard.Server
@@ -28,11 +30,21 @@ type HttpServer struct {
 
 var _ Server = (*HttpServer)(nil)
 
-func NewHttpServer(cfg config.Http, handler *Handler, dashboardServer *dashboard.Server) *HttpServer {
+// NewHttpServers returns a server for each listener, or nil if it is not enabled.
+func NewHttpServers(cfg config.Http, handler *Handler, dashboardServer *dashboard.Server) []*HttpServer {
 	if !cfg.Enabled {
 		return nil
 	}
 
+	listeners := cfg.AllListeners()
+	ret := make([]*HttpServer, 0, len(listeners))
+	for _, v := range listeners {
+		ret = append(ret, newHttpServer(v, handler, dashboardServer))
+	}
+	return ret
+}
+
+func newHttpServer(cfg config.HttpListener, handler *Handler, dashboardServer *dashboard.Server) *HttpServer {
 	ret := &HttpServer{
 		dashboardServer: dashboardServer,
 		handler:         handler,
@@ -59,10 +71,11 @@ func (s *HttpServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if ok {
-		ip, _, err := net.SplitHostPort(r.RemoteAddr)
+		ip, port, err := net.SplitHostPort(r.RemoteAddr)
 		if err != nil || net.ParseIP(ip) == nil {
 			logger.Warnf("invalid remote addr %q: %v", r.RemoteAddr, err)
 		} else {
+			remotePort, _ := strconv.Atoi(port)
 			if forwardedFor := r.Header.Get("X-Forwarded-For"); forwardedFor != "" {
 				netIp := net.ParseIP(ip)
 				if !netIp.IsGlobalUnicast() || netIp.IsPrivate() {
@@ -72,10 +85,16 @@ func (s *HttpServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 						logger.Warnf("invalid X-Forwarded-For %q", forwardedFor)
 					} else {
 						ip = forwardedIp
+						remotePort = 0 // the port of the proxy is meaningless
 					}
 				}
 			}
 
+			variant := "plain"
+			if r.TLS != nil {
+				variant = "tls"
+			}
+			localAddr := r.Context().Value(http.LocalAddrContextKey)
 			s.handler.Handle(r.Context(), &Request{
 				Kind:          model.BruteAttemptKindHttp,
 				Time:          time.Now(),
@@ -84,6 +103,15 @@ func (s *HttpServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 				Password:      password,
 				SessionId:     uuid.New().String(),
 				ClientVersion: r.UserAgent(),
+				LocalAddr:     addrString(localAddr),
+				LocalPort:     addrPort(localAddr),
+				RemotePort:    remotePort,
+				Variant:       variant,
+				Extra: map[string]string{
+					"method": r.Method,
+					"host":   r.Host,
+					"path":   r.URL.Path,
+				},
 			})
 		}
 	}
@@ -96,22 +124,53 @@ func (s *HttpServer) Startup(ctx context.Context, cancel context.CancelFunc) {
 	logger := logs.From(ctx)
 
 	if !s.Enabled() {
-		logger.Infof("skip starting ftp server since it is not enabled")
+		logger.Infof("skip starting http server since it is not enabled")
 		return
 	}
+	logger.Infof("start http server, listen on %s", s.server.Addr)
+	if s.listener == nil {
+		if err := s.Listen(); err != nil {
+			logger.Errorf("listen: %v", err)
+			cancel()
+			return
+		}
+	}
 	go func() {
-		logger.Infof("start http server, listen on %s", s.server.Addr)
-		if err := s.server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
-			logger.Errorf("listen and serve: %v", err)
+		if err := s.server.Serve(s.listener); !errors.Is(err, http.ErrServerClosed) {
+			logger.Errorf("serve: %v", err)
+			cancel()
 		}
-		cancel()
 	}()
 }
 
+func (s *HttpServer) Listen() error {
+	if !s.Enabled() {
+		return nil
+	}
+	listener, err := net.Listen("tcp", s.server.Addr)
+	if err != nil {
+		return err
+	}
+	s.listener = listener
+	return nil
+}
+
 func (s *HttpServer) Shutdown(ctx context.Context) error {
 	if !s.Enabled() {
 		return nil
 	}
-	logs.From(ctx).Infof("shutdown http server")
-	return s.server.Shutdown(ctx)
+	logs.From(ctx).Infof("shutdown http server on %s", s.server.Addr)
+	err := s.server.Shutdown(ctx)
+	if s.listener != nil {
+		// it's not closed by the server if it has never been served
+		_ = s.listener.Close()
+	}
+	return err
+}
+
+func (s *HttpServer) Addr() string {
+	if !s.Enabled() {
+		return ""
+	}
+	return s.server.Addr
 }
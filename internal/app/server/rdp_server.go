@@ -0,0 +1,278 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"time"
+
+	"github.com/funeypot/funeypot/internal/app/config"
+	"github.com/funeypot/funeypot/internal/app/model"
+	"github.com/funeypot/funeypot/internal/pkg/logs"
+	"github.com/funeypot/funeypot/internal/pkg/ntlm"
+	"github.com/funeypot/funeypot/internal/pkg/rdp"
+	"github.com/funeypot/funeypot/internal/pkg/sshkey"
+
+	"github.com/google/uuid"
+)
+
+const rdpTimeout = 30 * time.Second
+
+type RdpServer struct {
+	listener     *tcpListener
+	tlsConfig    *tls.Config
+	computerName string
+
+	handler *Handler
+}
+
+var _ Server = (*RdpServer)(nil)
+
+func NewRdpServer(cfg config.Rdp, handler *Handler) (*RdpServer, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	ret := &RdpServer{
+		computerName: rdpComputerName(cfg.KeySeed),
+		handler:      handler,
+	}
+
+	cert, err := rdpCertificate(cfg.KeySeed, ret.computerName)
+	if err != nil {
+		return nil, fmt.Errorf("generate certificate: %w", err)
+	}
+	ret.tlsConfig = &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS10,
+	}
+	ret.listener = newTcpListener(cfg.Address, ret.handleConn)
+
+	return ret, nil
+}
+
+func (s *RdpServer) Enabled() bool {
+	return s != nil
+}
+
+func (s *RdpServer) Startup(ctx context.Context, cancel context.CancelFunc) {
+	logger := logs.From(ctx)
+
+	if !s.Enabled() {
+		logger.Infof("skip starting rdp server since it is not enabled")
+		return
+	}
+	go func() {
+		logger.Infof("start rdp server, listen on %s", s.listener.addr)
+		if err := s.listener.ListenAndServe(ctx); !errors.Is(err, errListenerClosed) {
+			logger.Errorf("listen and serve: %v", err)
+			cancel()
+		}
+	}()
+}
+
+func (s *RdpServer) Listen() error {
+	if !s.Enabled() {
+		return nil
+	}
+	return s.listener.Listen()
+}
+
+func (s *RdpServer) Shutdown(ctx context.Context) error {
+	if !s.Enabled() {
+		return nil
+	}
+	logs.From(ctx).Infof("shutdown rdp server")
+	return s.listener.Shutdown(ctx)
+}
+
+func (s *RdpServer) Addr() string {
+	if !s.Enabled() {
+		return ""
+	}
+	return s.listener.addr
+}
+
+func (s *RdpServer) handleConn(ctx context.Context, conn net.Conn) {
+	logger := logs.From(ctx)
+
+	ip, ok := remoteIp(conn.RemoteAddr())
+	if !ok {
+		logger.Warnf("invalid remote addr %q", conn.RemoteAddr().String())
+		return
+	}
+	_ = conn.SetDeadline(time.Now().Add(rdpTimeout))
+
+	payload, err := rdp.ReadTpkt(conn)
+	if err != nil {
+		logger.Debugf("read connection request: %v", err)
+		return
+	}
+	req, err := rdp.ParseConnectionRequest(payload)
+	if err != nil {
+		logger.Debugf("parse connection request: %v", err)
+		return
+	}
+
+	request := &Request{
+		Kind:          model.BruteAttemptKindRdp,
+		Ip:            ip,
+		Time:          time.Now(),
+		User:          req.Cookie,
+		SessionId:     uuid.New().String(),
+		ClientVersion: rdp.ProtocolNames(req.RequestedProtocols),
+		LocalAddr:     addrString(conn.LocalAddr()),
+		LocalPort:     addrPort(conn.LocalAddr()),
+		RemotePort:    addrPort(conn.RemoteAddr()),
+		Variant:       rdp.ProtocolNames(rdp.ProtocolRdp),
+		Extra: map[string]string{
+			"cookie": req.Cookie,
+		},
+	}
+	defer func() {
+		s.handler.Handle(ctx, request)
+	}()
+
+	confirm := &rdp.ConnectionConfirm{}
+	switch {
+	case req.RequestedProtocols&(rdp.ProtocolHybrid|rdp.ProtocolHybridEx) != 0:
+		confirm.SelectedProtocol = rdp.ProtocolHybrid
+	case req.RequestedProtocols&rdp.ProtocolSsl != 0:
+		confirm.SelectedProtocol = rdp.ProtocolSsl
+	case req.Negotiation:
+		confirm.Failure = rdp.FailureHybridRequired
+	default:
+		// legacy clients which don't negotiate, nothing more can be captured
+		return
+	}
+	if err := rdp.WriteTpkt(conn, confirm.Marshal()); err != nil {
+		logger.Debugf("write connection confirm: %v", err)
+		return
+	}
+	if confirm.Failure != 0 {
+		return
+	}
+	request.Variant = rdp.ProtocolNames(confirm.SelectedProtocol)
+
+	tlsConn := tls.Server(conn, s.tlsConfig)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		logger.Debugf("tls handshake: %v", err)
+		return
+	}
+	if confirm.SelectedProtocol != rdp.ProtocolHybrid {
+		// the credentials of SSL are sent after the MCS connection, which is not supported
+		return
+	}
+
+	authenticate, err := s.handleCredssp(tlsConn)
+	if err != nil {
+		logger.Debugf("credssp: %v", err)
+		return
+	}
+	request.User = authenticate.Identity()
+	request.Extra["domain"] = authenticate.Domain
+	request.Extra["workstation"] = authenticate.Workstation
+	if authenticate.Workstation != "" {
+		request.ClientVersion = fmt.Sprintf("%s (%s)", request.ClientVersion, authenticate.Workstation)
+	}
+}
+
+// handleCredssp does the NTLM authentication over CredSSP, and returns the authenticate message sent by the client.
+func (s *RdpServer) handleCredssp(conn net.Conn) (*ntlm.Authenticate, error) {
+	req, err := rdp.ReadTsRequest(conn)
+	if err != nil {
+		return nil, fmt.Errorf("read negotiate: %w", err)
+	}
+	negotiate, err := ntlm.ParseNegotiate(req.Token())
+	if err != nil {
+		return nil, fmt.Errorf("parse negotiate: %w", err)
+	}
+
+	version := min(req.Version, 6)
+	challenge := &ntlm.Challenge{
+		Flags: negotiate.Flags&(ntlm.FlagNegotiateUnicode|ntlm.FlagNegotiateSign|ntlm.FlagNegotiateSeal|
+			ntlm.FlagNegotiateAlwaysSign|ntlm.FlagNegotiateExtendedSession|ntlm.FlagNegotiate128|
+			ntlm.FlagNegotiateKeyExchange|ntlm.FlagNegotiate56) |
+			ntlm.FlagRequestTarget | ntlm.FlagNegotiateNtlm | ntlm.FlagTargetTypeServer |
+			ntlm.FlagNegotiateTargetInfo | ntlm.FlagNegotiateVersion,
+		ComputerName: s.computerName,
+		Timestamp:    time.Now(),
+	}
+	_, _ = rand.Read(challenge.ServerChallenge[:])
+	if err := rdp.WriteTsRequest(conn, &rdp.TsRequest{
+		Version:    version,
+		NegoTokens: []rdp.NegoToken{{Token: challenge.Marshal()}},
+	}); err != nil {
+		return nil, fmt.Errorf("write challenge: %w", err)
+	}
+
+	req, err = rdp.ReadTsRequest(conn)
+	if err != nil {
+		return nil, fmt.Errorf("read authenticate: %w", err)
+	}
+	authenticate, err := ntlm.ParseAuthenticate(req.Token())
+	if err != nil {
+		return nil, fmt.Errorf("parse authenticate: %w", err)
+	}
+
+	if version >= 3 {
+		_ = rdp.WriteTsRequest(conn, &rdp.TsRequest{
+			Version:   version,
+			ErrorCode: rdp.StatusLogonFailure,
+		})
+	}
+	return authenticate, nil
+}
+
+// rdpComputerName returns a name like the default one of Windows, it's stable for the same seed.
+func rdpComputerName(seed string) string {
+	const chars = "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	b := make([]byte, 11)
+	if seed == "" {
+		_, _ = rand.Read(b)
+	} else {
+		sum := sha256.Sum256([]byte("rdp:" + seed))
+		copy(b, sum[:])
+	}
+	for i := range b {
+		b[i] = chars[int(b[i])%len(chars)]
+	}
+	return "WIN-" + string(b)
+}
+
+func rdpCertificate(seed, computerName string) (tls.Certificate, error) {
+	key, err := sshkey.GenerateKey(seed)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("generate key: %w", err)
+	}
+
+	notBefore := time.Now().Add(-24 * time.Hour).Truncate(24 * time.Hour)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(notBefore.Unix()),
+		Subject: pkix.Name{
+			CommonName: computerName,
+		},
+		NotBefore:   notBefore,
+		NotAfter:    notBefore.AddDate(0, 6, 0),
+		KeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment | x509.KeyUsageDataEncipherment,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("create certificate: %w", err)
+	}
+	return tls.Certificate{
+		Certificate: [][]byte{der},
+		PrivateKey:  key,
+	}, nil
+}
@@ -0,0 +1,601 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/funeypot/funeypot/internal/app/config"
+	"github.com/funeypot/funeypot/internal/app/model"
+	"github.com/funeypot/funeypot/internal/pkg/logs"
+	"github.com/funeypot/funeypot/internal/pkg/resp"
+
+	"github.com/google/uuid"
+)
+
+const (
+	redisVersion        = "7.2.4"
+	redisIdleTimeout    = 5 * time.Minute
+	redisMaxCommands    = 1000
+	redisMaxKeys        = 10000
+	redisMaxKeyLength   = 1024
+	redisMaxValueLength = 64 * 1024
+	redisMaxMemory      = 64 * 1024 * 1024 // bytes of all keys and values in the keyspace
+	redisMaxArgLength   = 256
+	redisMaxLineLength  = 1024
+)
+
+type RedisServer struct {
+	listener *tcpListener
+	auth     bool
+	delay    atomic.Int64 // time.Duration
+	keyspace *redisKeyspace
+
+	handler *Handler
+}
+
+var _ Reloadable = (*RedisServer)(nil)
+
+func NewRedisServer(cfg config.Redis, handler *Handler) *RedisServer {
+	if !cfg.Enabled {
+		return nil
+	}
+
+	ret := &RedisServer{
+		auth:     cfg.Auth,
+		keyspace: newRedisKeyspace(),
+		handler:  handler,
+	}
+	ret.delay.Store(int64(cfg.Delay))
+	ret.listener = newTcpListener(cfg.Address, ret.handleConn)
+
+	return ret
+}
+
+func (s *RedisServer) Enabled() bool {
+	return s != nil
+}
+
+func (s *RedisServer) Startup(ctx context.Context, cancel context.CancelFunc) {
+	logger := logs.From(ctx)
+
+	if !s.Enabled() {
+		logger.Infof("skip starting redis server since it is not enabled")
+		return
+	}
+	go func() {
+		logger.Infof("start redis server, listen on %s", s.listener.addr)
+		if err := s.listener.ListenAndServe(ctx); !errors.Is(err, errListenerClosed) {
+			logger.Errorf("listen and serve: %v", err)
+			cancel()
+		}
+	}()
+}
+
+func (s *RedisServer) Listen() error {
+	if !s.Enabled() {
+		return nil
+	}
+	return s.listener.Listen()
+}
+
+func (s *RedisServer) Shutdown(ctx context.Context) error {
+	if !s.Enabled() {
+		return nil
+	}
+	logs.From(ctx).Infof("shutdown redis server")
+	return s.listener.Shutdown(ctx)
+}
+
+func (s *RedisServer) Addr() string {
+	if !s.Enabled() {
+		return ""
+	}
+	return s.listener.addr
+}
+
+// Reload applies the delay of the next server.
+func (s *RedisServer) Reload(ctx context.Context, next Server) {
+	if n, ok := next.(*RedisServer); ok && n.Enabled() && n.delay.Load() != s.delay.Load() {
+		logs.From(ctx).Infof("change delay of redis server to %v", time.Duration(n.delay.Load()))
+		s.delay.Store(n.delay.Load())
+	}
+}
+
+// redisConn is the state of a client connection.
+type redisConn struct {
+	ip         string
+	localAddr  net.Addr
+	remoteAddr net.Addr
+	sessionId  string
+	startedAt  time.Time
+	clientName string
+	detector   redisExploitDetector
+	commands   []*Command
+}
+
+func (s *RedisServer) handleConn(ctx context.Context, conn net.Conn) {
+	logger := logs.From(ctx)
+
+	ip, ok := remoteIp(conn.RemoteAddr())
+	if !ok {
+		logger.Warnf("invalid remote addr %q", conn.RemoteAddr().String())
+		return
+	}
+
+	c := &redisConn{
+		ip:         ip,
+		localAddr:  conn.LocalAddr(),
+		remoteAddr: conn.RemoteAddr(),
+		sessionId:  uuid.New().String(),
+		startedAt:  time.Now(),
+	}
+	logger = logger.With("ip", ip, "session_id", shortSessionId(c.sessionId))
+	ctx = logs.With(ctx, logger)
+
+	defer func() {
+		if len(c.commands) == 0 {
+			return
+		}
+		if !s.auth {
+			// every connection to an open instance is an attempt, since no credential is needed
+			s.handler.Handle(ctx, &Request{
+				Kind:          model.BruteAttemptKindRedis,
+				Ip:            c.ip,
+				Time:          c.startedAt,
+				SessionId:     c.sessionId,
+				ClientVersion: c.clientName,
+				LocalAddr:     addrString(c.localAddr),
+				LocalPort:     addrPort(c.localAddr),
+				RemotePort:    addrPort(c.remoteAddr),
+				Variant:       "open",
+				Extra: map[string]string{
+					"commands": strconv.Itoa(len(c.commands)),
+				},
+			})
+		}
+		s.handler.HandleSession(ctx, &Session{
+			Kind:      model.BruteAttemptKindRedis,
+			Ip:        c.ip,
+			SessionId: c.sessionId,
+			StartedAt: c.startedAt,
+			Commands:  c.commands,
+		})
+	}()
+
+	reader := resp.NewReader(conn)
+	writer := resp.NewWriter(conn)
+	for {
+		_ = conn.SetReadDeadline(time.Now().Add(redisIdleTimeout))
+		args, err := reader.ReadCommand()
+		if err != nil {
+			if errors.Is(err, resp.ErrProtocol) {
+				writer.WriteError("ERR Protocol error: " + err.Error())
+				_ = writer.Flush()
+			}
+			logger.Debugf("read command: %v", err)
+			return
+		}
+		if len(args) == 0 {
+			continue
+		}
+
+		if len(c.commands) < redisMaxCommands {
+			c.commands = append(c.commands, &Command{
+				Time: time.Now(),
+				Line: redisCommandLine(args),
+				Flag: c.detector.Detect(args),
+			})
+		}
+
+		quit := s.handleCommand(ctx, c, args, writer)
+		if err := writer.Flush(); err != nil {
+			logger.Debugf("write reply: %v", err)
+			return
+		}
+		if quit {
+			return
+		}
+	}
+}
+
+// handleCommand writes the reply of the command, and returns true if the connection should be closed.
+func (s *RedisServer) handleCommand(ctx context.Context, c *redisConn, args []string, w *resp.Writer) bool {
+	name := strings.ToUpper(args[0])
+
+	switch name {
+	case "QUIT":
+		w.WriteSimple("OK")
+		return true
+	case "AUTH":
+		s.handleAuth(ctx, c, "AUTH", args[1:], w)
+		return false
+	case "HELLO":
+		// HELLO [protover [AUTH username password] [SETNAME clientname]]
+		for i := 2; i < len(args); i++ {
+			switch strings.ToUpper(args[i]) {
+			case "AUTH":
+				if i+2 < len(args) {
+					if !s.handleAuth(ctx, c, "HELLO", args[i+1:i+3], w) {
+						return false
+					}
+					i += 2
+				}
+			case "SETNAME":
+				if i+1 < len(args) {
+					c.clientName = args[i+1]
+					i++
+				}
+			}
+		}
+		if s.auth {
+			w.WriteError("NOAUTH HELLO must be called with the client already authenticated, otherwise the HELLO <proto> AUTH <user> <pass> option can be used to authenticate the client and select the RESP protocol version at the same time")
+			return false
+		}
+		w.WriteArray([]string{"server", "redis", "version", redisVersion, "proto", "2", "mode", "standalone", "role", "master"})
+		return false
+	}
+
+	if s.auth {
+		w.WriteError("NOAUTH Authentication required.")
+		return false
+	}
+
+	s.keyspace.Execute(c, name, args[1:], w)
+	return false
+}
+
+// handleAuth captures the credentials and writes an error reply, it returns false if a reply has been written.
+func (s *RedisServer) handleAuth(ctx context.Context, c *redisConn, command string, args []string, w *resp.Writer) bool {
+	if len(args) == 0 || len(args) > 2 {
+		w.WriteError("ERR wrong number of arguments for 'auth' command")
+		return false
+	}
+	if !s.auth {
+		if len(args) == 1 {
+			w.WriteError("ERR AUTH <password> called without any password configured for the default user. Are you sure your configuration is correct?")
+		} else {
+			w.WriteError("WRONGPASS invalid username-password pair or user is disabled.")
+		}
+		return false
+	}
+
+	user, password := "default", args[0]
+	if len(args) == 2 {
+		user, password = args[0], args[1]
+	}
+	s.handler.Handle(ctx, &Request{
+		Kind:          model.BruteAttemptKindRedis,
+		Ip:            c.ip,
+		Time:          time.Now(),
+		User:          user,
+		Password:      password,
+		SessionId:     c.sessionId,
+		ClientVersion: c.clientName,
+		LocalAddr:     addrString(c.localAddr),
+		LocalPort:     addrPort(c.localAddr),
+		RemotePort:    addrPort(c.remoteAddr),
+		Variant:       "auth",
+		Extra: map[string]string{
+			"command": command,
+		},
+	})
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Duration(s.delay.Load())):
+	}
+
+	w.WriteError("WRONGPASS invalid username-password pair or user is disabled.")
+	return false
+}
+
+// redisCommandLine formats the captured command, it's truncated since up to redisMaxCommands are kept per connection.
+func redisCommandLine(args []string) string {
+	quoted := make([]string, 0, len(args))
+	length := 0
+	for _, arg := range args {
+		if len(arg) > redisMaxArgLength {
+			arg = arg[:redisMaxArgLength-3] + "..."
+		}
+		if arg == "" || strings.ContainsAny(arg, " \t\r\n\"\\") {
+			arg = fmt.Sprintf("%q", arg)
+		}
+		quoted = append(quoted, arg)
+		length += len(arg) + 1
+		if length > redisMaxLineLength {
+			break
+		}
+	}
+	line := strings.Join(quoted, " ")
+	if len(line) > redisMaxLineLength {
+		line = line[:redisMaxLineLength-3] + "..."
+	}
+	return line
+}
+
+// redisKeyspace is the in-memory data of an emulated open instance, it's shared by all connections.
+type redisKeyspace struct {
+	mu     sync.Mutex
+	data   map[string]string
+	used   int // bytes of all keys and values in data
+	config map[string]string
+}
+
+func newRedisKeyspace() *redisKeyspace {
+	return &redisKeyspace{
+		data: map[string]string{},
+		config: map[string]string{
+			"dir":            "/var/lib/redis",
+			"dbfilename":     "dump.rdb",
+			"appendonly":     "no",
+			"protected-mode": "no",
+			"requirepass":    "",
+			"maxmemory":      "0",
+		},
+	}
+}
+
+func (k *redisKeyspace) Execute(c *redisConn, name string, args []string, w *resp.Writer) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	wrongArgs := func() {
+		w.WriteError(fmt.Sprintf("ERR wrong number of arguments for '%s' command", strings.ToLower(name)))
+	}
+
+	switch name {
+	case "PING":
+		if len(args) > 0 {
+			w.WriteBulk(args[0])
+		} else {
+			w.WriteSimple("PONG")
+		}
+	case "ECHO":
+		if len(args) != 1 {
+			wrongArgs()
+			return
+		}
+		w.WriteBulk(args[0])
+	case "SELECT", "FLUSHDB", "FLUSHALL":
+		if name != "SELECT" {
+			k.data = map[string]string{}
+			k.used = 0
+		}
+		w.WriteSimple("OK")
+	case "SET":
+		if len(args) < 2 {
+			wrongArgs()
+			return
+		}
+		key, value := args[0], args[1]
+		if len(value) > redisMaxValueLength {
+			value = value[:redisMaxValueLength]
+		}
+		old, exists := k.data[key]
+		used := k.used + len(value)
+		if exists {
+			used -= len(old)
+		} else {
+			used += len(key)
+		}
+		if len(key) > redisMaxKeyLength || !exists && len(k.data) >= redisMaxKeys || used > redisMaxMemory {
+			w.WriteError("OOM command not allowed when used memory > 'maxmemory'.")
+			return
+		}
+		k.data[key] = value
+		k.used = used
+		w.WriteSimple("OK")
+	case "GET":
+		if len(args) != 1 {
+			wrongArgs()
+			return
+		}
+		if v, ok := k.data[args[0]]; ok {
+			w.WriteBulk(v)
+		} else {
+			w.WriteNull()
+		}
+	case "DEL", "UNLINK", "EXISTS":
+		if len(args) == 0 {
+			wrongArgs()
+			return
+		}
+		var n int64
+		for _, key := range args {
+			if v, ok := k.data[key]; ok {
+				n++
+				if name != "EXISTS" {
+					delete(k.data, key)
+					k.used -= len(key) + len(v)
+				}
+			}
+		}
+		w.WriteInteger(n)
+	case "KEYS":
+		if len(args) != 1 {
+			wrongArgs()
+			return
+		}
+		keys := make([]string, 0, len(k.data))
+		for key := range k.data {
+			if ok, _ := path.Match(args[0], key); ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		w.WriteArray(keys)
+	case "TYPE":
+		if len(args) != 1 {
+			wrongArgs()
+			return
+		}
+		if _, ok := k.data[args[0]]; ok {
+			w.WriteSimple("string")
+		} else {
+			w.WriteSimple("none")
+		}
+	case "DBSIZE":
+		w.WriteInteger(int64(len(k.data)))
+	case "INFO":
+		w.WriteBulk(k.info())
+	case "CONFIG":
+		k.executeConfig(args, w)
+	case "CLIENT":
+		if len(args) >= 2 && strings.ToUpper(args[0]) == "SETNAME" {
+			c.clientName = args[1]
+		}
+		if len(args) >= 3 && strings.ToUpper(args[0]) == "SETINFO" && strings.ToUpper(args[1]) == "LIB-NAME" && c.clientName == "" {
+			c.clientName = args[2]
+		}
+		w.WriteSimple("OK")
+	case "SAVE":
+		w.WriteSimple("OK")
+	case "BGSAVE":
+		w.WriteSimple("Background saving started")
+	case "SLAVEOF", "REPLICAOF":
+		if len(args) != 2 {
+			wrongArgs()
+			return
+		}
+		w.WriteSimple("OK")
+	case "MODULE":
+		w.WriteError("ERR Error loading the extension. Please check the server logs.")
+	case "COMMAND":
+		w.WriteArray(nil)
+	default:
+		var preview []string
+		for _, arg := range args {
+			preview = append(preview, "'"+arg+"'")
+		}
+		w.WriteError(fmt.Sprintf("ERR unknown command '%s', with args beginning with: %s", strings.ToLower(name), strings.Join(preview, " ")))
+	}
+}
+
+func (k *redisKeyspace) executeConfig(args []string, w *resp.Writer) {
+	if len(args) == 0 {
+		w.WriteError("ERR wrong number of arguments for 'config' command")
+		return
+	}
+	switch strings.ToUpper(args[0]) {
+	case "GET":
+		if len(args) < 2 {
+			w.WriteError("ERR wrong number of arguments for 'config|get' command")
+			return
+		}
+		var items []string
+		for _, pattern := range args[1:] {
+			for key, value := range k.config {
+				if ok, _ := path.Match(strings.ToLower(pattern), key); ok {
+					items = append(items, key, value)
+				}
+			}
+		}
+		w.WriteArray(items)
+	case "SET":
+		if len(args) < 3 || len(args)%2 == 0 {
+			w.WriteError("ERR wrong number of arguments for 'config|set' command")
+			return
+		}
+		for i := 1; i+1 < len(args); i += 2 {
+			k.config[strings.ToLower(args[i])] = args[i+1]
+		}
+		w.WriteSimple("OK")
+	case "RESETSTAT", "REWRITE":
+		w.WriteSimple("OK")
+	default:
+		w.WriteError(fmt.Sprintf("ERR unknown subcommand '%s'. Try CONFIG HELP.", args[0]))
+	}
+}
+
+func (k *redisKeyspace) info() string {
+	lines := []string{
+		"# Server",
+		"redis_version:" + redisVersion,
+		"redis_mode:standalone",
+		"os:Linux 5.15.0-105-generic x86_64",
+		"arch_bits:64",
+		"tcp_port:6379",
+		"",
+		"# Replication",
+		"role:master",
+		"connected_slaves:0",
+		"",
+		"# Keyspace",
+	}
+	if len(k.data) > 0 {
+		lines = append(lines, fmt.Sprintf("db0:keys=%d,expires=0,avg_ttl=0", len(k.data)))
+	}
+	return strings.Join(lines, "\r\n") + "\r\n"
+}
+
+// redisExploitDetector flags commands of well-known exploitations of unprotected instances,
+// some of them are only flagged when they appear in sequence within the same connection.
+type redisExploitDetector struct {
+	dir        bool
+	dbfilename bool
+	replicaOf  bool
+}
+
+func (d *redisExploitDetector) Detect(args []string) string {
+	name := strings.ToUpper(args[0])
+	sub := ""
+	if len(args) > 1 {
+		sub = strings.ToUpper(args[1])
+	}
+
+	switch {
+	case name == "CONFIG" && sub == "SET" && len(args) > 2:
+		switch strings.ToLower(args[2]) {
+		case "dir":
+			d.dir = true
+			return "config_set_dir"
+		case "dbfilename":
+			d.dbfilename = true
+			return "config_set_dbfilename"
+		}
+	case name == "SAVE" || name == "BGSAVE":
+		if d.dir && d.dbfilename {
+			// the data is written to an arbitrary file, like crontab or authorized_keys
+			return "file_write"
+		}
+	case (name == "SLAVEOF" || name == "REPLICAOF") && len(args) > 2 && !strings.EqualFold(args[1], "NO"):
+		d.replicaOf = true
+		return "rogue_replication"
+	case name == "MODULE" && sub == "LOAD":
+		if d.replicaOf {
+			// the module has been synced from a rogue master
+			return "rogue_module_load"
+		}
+		return "module_load"
+	case name == "SYSTEM.EXEC" || name == "SYSTEM.REV" || name == "SYSTEM.RUN":
+		return "module_exec"
+	case name == "EVAL" || name == "EVALSHA":
+		script := strings.ToLower(strings.Join(args[1:], " "))
+		for _, s := range []string{"io.popen", "os.execute", "package.loadlib", "luaopen_", "loadstring"} {
+			if strings.Contains(script, s) {
+				return "lua_escape"
+			}
+		}
+	case name == "SET" && len(args) > 2:
+		value := args[2]
+		switch {
+		case strings.Contains(value, "ssh-rsa ") || strings.Contains(value, "ssh-ed25519 ") || strings.Contains(value, "ecdsa-sha2-"):
+			return "ssh_key_payload"
+		case strings.Contains(value, "* * * *") || strings.Contains(value, "*/"):
+			return "cron_payload"
+		}
+	}
+	return ""
+}
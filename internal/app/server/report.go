@@ -0,0 +1,355 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/funeypot/funeypot/internal/app/config"
+	"github.com/funeypot/funeypot/internal/app/model"
+	"github.com/funeypot/funeypot/internal/pkg/abuseipdb"
+	"github.com/funeypot/funeypot/internal/pkg/logs"
+)
+
+// maxCommentLength is the maximum length of the comments accepted by abuseipdb.
+const maxCommentLength = 1024
+
+// reportRule is the reporting of a kind, parsed from config.AbuseipdbKind.
+type reportRule struct {
+	minCount    int64
+	minDuration time.Duration
+	categories  []int
+	comment     *template.Template
+}
+
+// reportData is the data of the comment templates.
+type reportData struct {
+	Kind            string
+	Ip              string
+	User            string
+	Password        string // masked
+	PasswordOmitted bool
+	ClientVersion   string
+	Count           int64
+	Duration        string
+	Campaign        *model.Campaign // nil if it's not a part of a detected campaign
+}
+
+// newReportRules returns the rules of all the kinds.
+func newReportRules(reporting config.Abuseipdb) (map[model.BruteAttemptKind]*reportRule, error) {
+	ret := map[model.BruteAttemptKind]*reportRule{}
+	for kind := model.BruteAttemptKindSsh; kind <= model.BruteAttemptKindVnc; kind++ {
+		cfg := reporting.KindOf(kind.String())
+
+		categories := make([]int, 0, len(cfg.Categories))
+		for _, v := range cfg.Categories {
+			id, ok := abuseipdb.ParseCategory(v)
+			if !ok {
+				return nil, fmt.Errorf("unknown category %q of %s", v, kind)
+			}
+			categories = append(categories, id)
+		}
+		if len(categories) == 0 {
+			return nil, fmt.Errorf("no categories of %s", kind)
+		}
+
+		comment, err := template.New(kind.String()).Parse(cfg.Comment)
+		if err != nil {
+			return nil, fmt.Errorf("parse comment of %s: %w", kind, err)
+		}
+
+		ret[kind] = &reportRule{
+			minCount:    int64(cfg.MinCount),
+			minDuration: cfg.MinDuration,
+			categories:  categories,
+			comment:     comment,
+		}
+	}
+	return ret, nil
+}
+
+// reportComment returns the comment of the attempt by the template.
+func (h *Handler) reportComment(rule *reportRule, attempt *model.BruteAttempt, campaign *model.Campaign) (string, error) {
+	data := &reportData{
+		Kind:            attempt.Kind.String(),
+		Ip:              attempt.Ip,
+		User:            attempt.User,
+		Password:        attempt.MaskedPassword(),
+		PasswordOmitted: h.reporting.OmitPassword,
+		ClientVersion:   attempt.ShortClientVersion(),
+		Count:           attempt.Count,
+		Duration:        attempt.Duration().Truncate(time.Second).String(),
+		Campaign:        campaign,
+	}
+	if data.PasswordOmitted {
+		data.Password = ""
+	}
+
+	b := &strings.Builder{}
+	if err := rule.comment.Execute(b, data); err != nil {
+		return "", err
+	}
+	ret := b.String()
+	if r := []rune(ret); len(r) > maxCommentLength {
+		ret = string(r[:maxCommentLength])
+	}
+	return ret, nil
+}
+
+// sendReport reports the pending one, and records the report.
+func (h *Handler) sendReport(ctx context.Context, pending *model.AbuseipdbPending, categories []int, last *model.AbuseipdbReport) error {
+	logger := logs.From(ctx)
+
+	score, err := h.abuseipdbClient.Report(ctx, pending.Ip, categories, pending.AttemptedAt, pending.Comment)
+	if err != nil {
+		return err
+	}
+
+	logger.Infof("reported, score: %d", score)
+	if last != nil && last.Score > 0 && last.Score != score {
+		logger.Infof("score changed, %d -> %d", last.Score, score)
+	}
+	newReport := &model.AbuseipdbReport{
+		Ip:         pending.Ip,
+		ReportedAt: time.Now(),
+		Score:      score,
+	}
+	if err := h.db.Create(ctx, newReport); err != nil {
+		logger.Errorf("create report: %v", err)
+	}
+	return nil
+}
+
+// deferReport saves the report to the queue to send it after the time, or drops it if the queue is not enabled.
+func (h *Handler) deferReport(ctx context.Context, pending *model.AbuseipdbPending, at time.Time, reason string) {
+	logger := logs.From(ctx)
+
+	if !h.reporting.Queue.Enabled {
+		return
+	}
+	pending.NextAt = at
+	pending.LastError = reason
+	if err := h.db.SaveAbuseipdbPending(ctx, pending); err != nil {
+		logger.Errorf("save pending report: %v", err)
+		return
+	}
+	logger.Infof("defer report until %v: %s", at.Format(time.RFC3339), reason)
+}
+
+// drainReports sends the due pending reports, the more active ips first.
+func (h *Handler) drainReports(ctx context.Context) {
+	logger := logs.From(ctx)
+	queue := h.reporting.Queue
+
+	if _, ok := h.abuseipdbClient.Cooldown(); ok {
+		return
+	}
+
+	now := time.Now()
+	h.dropExpiredReports(ctx, now)
+
+	remaining, err := h.remainingQuota(ctx)
+	if err != nil {
+		logger.Errorf("get remaining quota: %v", err)
+		return
+	}
+	pendings, err := h.db.ListDueAbuseipdbPendings(ctx, now, min(queue.BatchSize, remaining))
+	if err != nil {
+		logger.Errorf("list pending reports: %v", err)
+		return
+	}
+
+	for _, pending := range pendings {
+		if _, ok := h.abuseipdbClient.Cooldown(); ok {
+			return
+		}
+		subCtx, cancel := context.WithTimeout(ctx, 15*time.Second)
+		h.drainReport(logs.With(subCtx, logger.With("kind", pending.Kind.String(), "ip", pending.Ip)), pending)
+		cancel()
+	}
+}
+
+func (h *Handler) drainReport(ctx context.Context, pending *model.AbuseipdbPending) {
+	logger := logs.From(ctx)
+
+	report, ok, err := h.db.LastAbuseipdbReport(ctx, pending.Ip)
+	if err != nil {
+		logger.Errorf("get last report: %v", err)
+		return
+	}
+	if interval := h.abuseipdbClient.Interval(); ok && time.Since(report.ReportedAt) < interval {
+		// it has been reported by a previous row of attempts, wait for the interval
+		pending.NextAt = report.ReportedAt.Add(interval)
+		if err := h.db.SaveAbuseipdbPending(ctx, pending); err != nil {
+			logger.Errorf("save pending report: %v", err)
+		}
+		return
+	}
+
+	categories, err := abuseipdb.SplitCategories(pending.Categories)
+	if err != nil {
+		logger.Errorf("invalid categories %q, drop it: %v", pending.Categories, err)
+	} else if err := h.sendReport(ctx, pending, categories, report); err != nil {
+		pending.Retries++
+		logger.Warnf("report pending one, failed %d times: %v", pending.Retries, err)
+		h.deferReport(ctx, pending, h.retryAt(pending.Retries), err.Error())
+		return
+	}
+	if err := h.db.DeleteAbuseipdbPending(ctx, pending.Id); err != nil {
+		logger.Errorf("delete pending report: %v", err)
+	}
+}
+
+// bulkReports sends the due pending reports in a bulk.
+func (h *Handler) bulkReports(ctx context.Context) {
+	logger := logs.From(ctx)
+
+	if _, ok := h.abuseipdbClient.Cooldown(); ok {
+		return
+	}
+
+	now := time.Now()
+	h.dropExpiredReports(ctx, now)
+
+	remaining, err := h.remainingQuota(ctx)
+	if err != nil {
+		logger.Errorf("get remaining quota: %v", err)
+		return
+	}
+	if remaining <= 0 {
+		return
+	}
+	pendings, err := h.db.ListDueAbuseipdbPendings(ctx, now, min(h.reporting.Bulk.BatchSize, remaining))
+	if err != nil {
+		logger.Errorf("list pending reports: %v", err)
+		return
+	}
+
+	interval := h.abuseipdbClient.Interval()
+	var reports []abuseipdb.BulkReport
+	batch := make(map[string]*model.AbuseipdbPending, len(pendings))
+	scores := make(map[string]int, len(pendings)) // the scores of the last reports, since the bulk api returns none
+	for _, pending := range pendings {
+		report, ok, err := h.db.LastAbuseipdbReport(ctx, pending.Ip)
+		if err != nil {
+			logger.Errorf("get last report of %s: %v", pending.Ip, err)
+			continue
+		}
+		if ok && time.Since(report.ReportedAt) < interval {
+			pending.NextAt = report.ReportedAt.Add(interval)
+			if err := h.db.SaveAbuseipdbPending(ctx, pending); err != nil {
+				logger.Errorf("save pending report of %s: %v", pending.Ip, err)
+			}
+			continue
+		}
+		if ok {
+			scores[pending.Ip] = report.Score
+		}
+		categories, err := abuseipdb.SplitCategories(pending.Categories)
+		if err != nil {
+			logger.Errorf("invalid categories %q of %s, drop it: %v", pending.Categories, pending.Ip, err)
+			if err := h.db.DeleteAbuseipdbPending(ctx, pending.Id); err != nil {
+				logger.Errorf("delete pending report of %s: %v", pending.Ip, err)
+			}
+			continue
+		}
+		reports = append(reports, abuseipdb.BulkReport{
+			Ip:         pending.Ip,
+			Categories: categories,
+			Timestamp:  pending.AttemptedAt,
+			Comment:    pending.Comment,
+		})
+		batch[pending.Ip] = pending
+	}
+	if len(reports) == 0 {
+		return
+	}
+
+	subCtx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	result, err := h.abuseipdbClient.BulkReport(subCtx, reports)
+	if err != nil {
+		logger.Errorf("bulk report %d ips: %v", len(reports), err)
+		for _, pending := range batch {
+			pending.Retries++
+			pending.NextAt = h.retryAt(pending.Retries)
+			pending.LastError = err.Error()
+			if err := h.db.SaveAbuseipdbPending(ctx, pending); err != nil {
+				logger.Errorf("save pending report of %s: %v", pending.Ip, err)
+			}
+		}
+		return
+	}
+
+	logger.Infof("bulk reported, saved: %d, invalid: %d", result.Saved, len(result.Invalid))
+	invalid := make(map[string]bool, len(result.Invalid))
+	for _, v := range result.Invalid {
+		// retrying doesn't help, so they're dropped
+		logger.Warnf("invalid report of %s at row %d: %s", v.Input, v.RowNumber, v.Error)
+		invalid[v.Input] = true
+	}
+	for ip, pending := range batch {
+		if !invalid[ip] {
+			if err := h.db.Create(ctx, &model.AbuseipdbReport{
+				Ip:         ip,
+				ReportedAt: now,
+				Score:      scores[ip],
+			}); err != nil {
+				logger.Errorf("create report of %s: %v", ip, err)
+			}
+		}
+		if err := h.db.DeleteAbuseipdbPending(ctx, pending.Id); err != nil {
+			logger.Errorf("delete pending report of %s: %v", ip, err)
+		}
+	}
+}
+
+// dropExpiredReports deletes the pending reports of the attempts older than the max age.
+func (h *Handler) dropExpiredReports(ctx context.Context, now time.Time) {
+	logger := logs.From(ctx)
+
+	maxAge := h.reporting.Queue.MaxAge
+	if n, err := h.db.DeleteAbuseipdbPendingsBefore(ctx, now.Add(-maxAge)); err != nil {
+		logger.Errorf("delete expired pending reports: %v", err)
+	} else if n > 0 {
+		logger.Warnf("drop %d pending reports of the attempts before %v", n, maxAge)
+	}
+}
+
+// remainingQuota returns the count of the reports which can be sent today.
+func (h *Handler) remainingQuota(ctx context.Context) (int, error) {
+	quota := h.reporting.Queue.DailyQuota
+	if !h.reporting.Queue.Enabled || quota <= 0 {
+		return math.MaxInt, nil
+	}
+	count, err := h.db.CountAbuseipdbReports(ctx, time.Now().UTC().Truncate(24*time.Hour))
+	if err != nil {
+		return 0, err
+	}
+	return quota - int(count), nil
+}
+
+// retryAt returns the time to retry after the failures, with the delay doubled after each failure.
+func (h *Handler) retryAt(retries int) time.Time {
+	queue := h.reporting.Queue
+	delay := queue.RetryDelay
+	for i := 1; i < retries && delay < queue.MaxRetryDelay; i++ {
+		delay *= 2
+	}
+	ret := time.Now().Add(min(delay, queue.MaxRetryDelay))
+	if until, ok := h.abuseipdbClient.Cooldown(); ok && until.After(ret) {
+		ret = until
+	}
+	return ret
+}
+
+// nextUtcDay returns the start of the next day of UTC, when the daily quota resets.
+func nextUtcDay(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+}
@@ -0,0 +1,122 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/funeypot/funeypot/internal/app/model"
+	"github.com/funeypot/funeypot/internal/pkg/logs"
+)
+
+// checkReputation returns the reputation of the ip on AbuseIPDB, from the cache or by checking it.
+// It returns nil if it's unknown.
+func (h *Handler) checkReputation(ctx context.Context, ip string) *model.IpReputation {
+	logger := logs.From(ctx)
+
+	check := h.reporting.Check
+	if !h.abuseipdbClient.Enabled() || !check.Enabled && !h.reporting.Blacklist.Enabled {
+		return nil
+	}
+
+	cached, ok, err := h.db.TakeIpReputation(ctx, ip)
+	if err != nil {
+		logger.Errorf("get ip reputation: %v", err)
+		return nil
+	}
+	if !ok {
+		cached = nil
+	}
+	if !check.Enabled || ok && time.Since(cached.CheckedAt) < check.Ttl {
+		return cached
+	}
+	if _, ok := h.abuseipdbClient.CheckCooldown(); ok {
+		// a stale one is better than nothing
+		return cached
+	}
+
+	result, err := h.abuseipdbClient.Check(ctx, ip, check.MaxAgeInDays)
+	if err != nil {
+		logger.Errorf("check ip: %v", err)
+		return cached
+	}
+	reputation := &model.IpReputation{
+		Ip:           ip,
+		Score:        result.AbuseConfidenceScore,
+		UsageType:    result.UsageType,
+		Isp:          result.Isp,
+		TotalReports: result.TotalReports,
+		CheckedAt:    time.Now(),
+	}
+	if cached != nil {
+		reputation.BlacklistedAt = cached.BlacklistedAt
+	}
+	if err := h.db.SaveCheckedIpReputation(ctx, reputation); err != nil {
+		logger.Errorf("save ip reputation: %v", err)
+	}
+	return reputation
+}
+
+// fetchBlacklists fetches the blacklist periodically until the context is done.
+// The first fetch waits for the interval since the last blacklist saved, so restarting doesn't fetch it again.
+func (h *Handler) fetchBlacklists(ctx context.Context) {
+	logger := logs.From(ctx).With("job", "blacklist")
+	ctx = logs.With(ctx, logger)
+
+	interval := h.reporting.Blacklist.Interval
+	var delay time.Duration
+	if last, err := h.db.LastBlacklistedAt(ctx); err != nil {
+		logger.Errorf("get last blacklisted time: %v", err)
+	} else if !last.IsZero() {
+		delay = max(time.Until(last.Add(interval)), 0)
+		logger.Infof("last blacklist fetched at %v, next in %v", last, delay)
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return
+		}
+		if until, ok := h.abuseipdbClient.BlacklistCooldown(); ok {
+			logger.Infof("fetching blacklist is limited until %v", until)
+			timer.Reset(time.Until(until))
+			continue
+		}
+		h.fetchBlacklist(ctx)
+		timer.Reset(interval)
+	}
+}
+
+func (h *Handler) fetchBlacklist(ctx context.Context) {
+	logger := logs.From(ctx)
+	blacklist := h.reporting.Blacklist
+
+	subCtx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	ips, err := h.abuseipdbClient.Blacklist(subCtx, blacklist.ConfidenceMinimum, blacklist.Limit)
+	if err != nil {
+		logger.Errorf("fetch blacklist: %v", err)
+		return
+	}
+
+	// in seconds, or the databases storing less precision would take the saved ones as older
+	now := time.Now().Truncate(time.Second)
+	reputations := make([]*model.IpReputation, 0, len(ips))
+	for _, v := range ips {
+		reputations = append(reputations, &model.IpReputation{
+			Ip:            v.IpAddress,
+			Score:         v.AbuseConfidenceScore,
+			BlacklistedAt: now,
+		})
+	}
+	if err := h.db.SaveBlacklistedIpReputations(ctx, reputations, now); err != nil {
+		logger.Errorf("save blacklisted ips: %v", err)
+		return
+	}
+	logger.Infof("fetched blacklist, ips: %d", len(reputations))
+}
@@ -5,18 +5,38 @@ package server
 
 import (
 	"context"
+	"encoding/hex"
 	"fmt"
+	"net"
+	"strconv"
+	"sync/atomic"
 	"time"
 
+	"github.com/funeypot/funeypot/internal/app/config"
 	"github.com/funeypot/funeypot/internal/app/model"
+	"github.com/funeypot/funeypot/internal/app/sink"
 	"github.com/funeypot/funeypot/internal/pkg/abuseipdb"
 	"github.com/funeypot/funeypot/internal/pkg/ipgeo"
 	"github.com/funeypot/funeypot/internal/pkg/logs"
+	"github.com/funeypot/funeypot/internal/pkg/sniff"
 )
 
 type Server interface {
+	// Startup starts the server in the background, it cancels the context if the server stops unexpectedly.
+	// A server stopped by Shutdown doesn't cancel it, so it can be replaced by a new one when reloading.
 	Startup(ctx context.Context, cancel context.CancelFunc)
+	// Listen binds the address without serving, so a busy or privileged address fails before anything else changes.
+	// Startup listens by itself if it hasn't been called.
+	Listen() error
 	Shutdown(ctx context.Context) error
+	// Addr returns the address to listen on, or "" if it's not enabled.
+	Addr() string
+}
+
+// Reloadable is a server which can apply the settings of a new server with the same address, without restarting.
+type Reloadable interface {
+	Server
+	Reload(ctx context.Context, next Server)
 }
 
 type Request struct {
@@ -27,32 +47,172 @@ type Request struct {
 	Password      string
 	SessionId     string
 	ClientVersion string
+	LocalAddr     string // the address of the server which the client connected to
+	LocalPort     int
+	RemotePort    int
+	Variant       string            // the variant of the protocol, see model.AttemptRecord
+	Extra         map[string]string // the extra information of the kind, see model.AttemptRecord
 }
 
 func (r Request) ShortSessionId() string {
-	if len(r.SessionId) > 8 {
-		return r.SessionId[:8]
+	return shortSessionId(r.SessionId)
+}
+
+// Session is the interaction of a client which has been let in, like an unauthenticated Redis connection.
+type Session struct {
+	Kind      model.BruteAttemptKind
+	Ip        string
+	SessionId string
+	StartedAt time.Time
+	Commands  []*Command
+}
+
+func (s Session) ShortSessionId() string {
+	return shortSessionId(s.SessionId)
+}
+
+// Flags returns the distinct exploitation flags of the commands, in order of appearance.
+func (s Session) Flags() []string {
+	var ret []string
+	seen := map[string]struct{}{}
+	for _, c := range s.Commands {
+		if c.Flag == "" {
+			continue
+		}
+		if _, ok := seen[c.Flag]; ok {
+			continue
+		}
+		seen[c.Flag] = struct{}{}
+		ret = append(ret, c.Flag)
 	}
-	return r.SessionId
+	return ret
+}
+
+type Command struct {
+	Time time.Time
+	Line string
+	Flag string
+}
+
+// Payload is the first bytes sent by a client to a generic TCP listener.
+type Payload struct {
+	Ip   string
+	Port int
+	Time time.Time
+	Data []byte
+}
+
+// addrPort returns the port of an address, or 0 if it's unknown.
+// It accepts any so it can be used with the values of contexts directly.
+func addrPort(addr any) int {
+	if v, ok := addr.(*net.TCPAddr); ok {
+		return v.Port
+	}
+	if v, ok := addr.(net.Addr); ok {
+		if _, port, err := net.SplitHostPort(v.String()); err == nil {
+			ret, _ := strconv.Atoi(port)
+			return ret
+		}
+	}
+	return 0
+}
+
+// addrString returns the string of an address, or "" if it's unknown.
+func addrString(addr any) string {
+	if v, ok := addr.(net.Addr); ok && v != nil {
+		return v.String()
+	}
+	return ""
+}
+
+func shortSessionId(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
 }
 
 type Handler struct {
 	db              *model.Database
 	abuseipdbClient *abuseipdb.Client
 	ipgeoQuerier    ipgeo.Querier
+	aggregation     config.Aggregation
+	reporting       config.Abuseipdb
+	reportRules     map[model.BruteAttemptKind]*reportRule
+	campaign        config.Campaign
+	sinks           atomic.Pointer[sink.Sinks] // replaced on reload
+
+	queue chan any // *Request, *Session or *Payload
+}
 
-	queue chan *Request
+// CheckKinds returns an error if the overrides have unknown kinds,
+// the config doesn't know the kinds, so they're checked here.
+func CheckKinds(aggregation config.Aggregation, reporting config.Abuseipdb) error {
+	for kind := range aggregation.Kinds {
+		if _, ok := model.ParseBruteAttemptKind(kind); !ok {
+			return fmt.Errorf("unknown kind %q in aggregation.kinds", kind)
+		}
+	}
+	for kind := range reporting.Kinds {
+		if _, ok := model.ParseBruteAttemptKind(kind); !ok {
+			return fmt.Errorf("unknown kind %q in abuseipdb.kinds", kind)
+		}
+	}
+	return nil
 }
 
-func NewHandler(ctx context.Context, db *model.Database, ipgeoQuerier ipgeo.Querier, abuseipdbClient *abuseipdb.Client) *Handler {
+func NewHandler(
+	ctx context.Context,
+	aggregation config.Aggregation,
+	reporting config.Abuseipdb,
+	campaign config.Campaign,
+	db *model.Database,
+	ipgeoQuerier ipgeo.Querier,
+	abuseipdbClient *abuseipdb.Client,
+	sinks sink.Sinks,
+) (*Handler, error) {
+	if err := CheckKinds(aggregation, reporting); err != nil {
+		return nil, err
+	}
+	reportRules, err := newReportRules(reporting)
+	if err != nil {
+		return nil, fmt.Errorf("abuseipdb: %w", err)
+	}
+
 	ret := &Handler{
 		db:              db,
 		ipgeoQuerier:    ipgeoQuerier,
 		abuseipdbClient: abuseipdbClient,
-		queue:           make(chan *Request, 1000),
+		aggregation:     aggregation,
+		reporting:       reporting,
+		reportRules:     reportRules,
+		campaign:        campaign,
+		queue:           make(chan any, 1000),
 	}
+	ret.SetSinks(sinks)
 	go ret.handleQueue(ctx)
-	return ret
+	if abuseipdbClient.Enabled() && reporting.Blacklist.Enabled {
+		go ret.fetchBlacklists(ctx)
+	}
+	return ret, nil
+}
+
+// SetSinks replaces the sinks of the events, it's safe to call concurrently.
+func (h *Handler) SetSinks(sinks sink.Sinks) {
+	h.sinks.Store(&sinks)
+}
+
+func (h *Handler) currentSinks() sink.Sinks {
+	return *h.sinks.Load()
+}
+
+// attemptWindow returns the window to aggregate the attempts of the kind.
+func (h *Handler) attemptWindow(kind model.BruteAttemptKind) model.AttemptWindow {
+	rule := h.aggregation.RuleOf(kind.String())
+	if rule.Mode == config.AggregationModeFixed {
+		return model.AttemptWindow{Length: rule.Window}
+	}
+	return model.AttemptWindow{Gap: rule.Gap}
 }
 
 func (h *Handler) Handle(ctx context.Context, request *Request) {
@@ -60,26 +220,77 @@ func (h *Handler) Handle(ctx context.Context, request *Request) {
 	select {
 	case h.queue <- request:
 	default:
-		logger.Warnf("ssh queue full, drop requests, please increase queue size")
+		logger.Warnf("queue full, drop requests, please increase queue size")
+	}
+}
+
+func (h *Handler) HandleSession(ctx context.Context, session *Session) {
+	logger := logs.From(ctx)
+	select {
+	case h.queue <- session:
+	default:
+		logger.Warnf("queue full, drop sessions, please increase queue size")
+	}
+}
+
+func (h *Handler) HandlePayload(ctx context.Context, payload *Payload) {
+	logger := logs.From(ctx)
+	select {
+	case h.queue <- payload:
+	default:
+		logger.Warnf("queue full, drop payloads, please increase queue size")
 	}
 }
 
 func (h *Handler) handleQueue(ctx context.Context) {
 	logger := logs.From(ctx)
+
+	// the pending reports are sent in the same goroutine, so they never race with the new ones
+	var drain <-chan time.Time
+	drainReports := h.drainReports
+	if h.abuseipdbClient.Enabled() && h.reporting.Queue.Enabled {
+		interval := h.reporting.Queue.RetryDelay
+		if h.reporting.Bulk.Enabled {
+			// the failed ones are retried with the next bulk
+			interval, drainReports = h.reporting.Bulk.Interval, h.bulkReports
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		drain = ticker.C
+	}
+
 	for {
 		if l := len(h.queue); l > 0 {
 			logger.Debugf("queue lag: %d", l)
 		}
 		select {
-		case request := <-h.queue:
+		case item := <-h.queue:
 			subCtx, cancel := context.WithTimeout(ctx, 15*time.Second)
-			subCtx = logs.With(subCtx, logger.With(
-				"kind", request.Kind.String(),
-				"ip", request.Ip,
-				"session_id", request.ShortSessionId(),
-			))
-			h.handleRequest(subCtx, request)
+			switch item := item.(type) {
+			case *Request:
+				subCtx = logs.With(subCtx, logger.With(
+					"kind", item.Kind.String(),
+					"ip", item.Ip,
+					"session_id", item.ShortSessionId(),
+				))
+				h.handleRequest(subCtx, item)
+			case *Session:
+				subCtx = logs.With(subCtx, logger.With(
+					"kind", item.Kind.String(),
+					"ip", item.Ip,
+					"session_id", item.ShortSessionId(),
+				))
+				h.handleSession(subCtx, item)
+			case *Payload:
+				subCtx = logs.With(subCtx, logger.With(
+					"ip", item.Ip,
+					"port", item.Port,
+				))
+				h.handlePayload(subCtx, item)
+			}
 			cancel()
+		case <-drain:
+			drainReports(logs.With(ctx, logger.With("queue", "abuseipdb")))
 		case <-ctx.Done():
 			logger.Infof("handle queue done")
 			if len(h.queue) > 0 {
@@ -101,13 +312,31 @@ func (h *Handler) handleRequest(ctx context.Context, request *Request) {
 		request.Kind,
 		request.Time,
 		request.User, request.Password, request.ClientVersion,
-		request.Time.Add(-24*time.Hour),
+		request.LocalPort,
+		h.attemptWindow(request.Kind),
 	)
 	if err != nil {
 		logger.Errorf("incr attempt: %v", err)
 		return
 	}
 
+	if err := h.db.Create(ctx, &model.AttemptRecord{
+		AttemptId:     attempt.Id,
+		Ip:            request.Ip,
+		Kind:          request.Kind,
+		LocalAddr:     request.LocalAddr,
+		LocalPort:     request.LocalPort,
+		RemotePort:    request.RemotePort,
+		Variant:       request.Variant,
+		User:          request.User,
+		Password:      request.Password,
+		ClientVersion: request.ClientVersion,
+		Extra:         request.Extra,
+		Time:          request.Time,
+	}); err != nil {
+		logger.Errorf("create attempt record: %v", err)
+	}
+
 	loginLogger := logger.With(
 		"count", attempt.Count,
 		"duration", attempt.Duration().String(),
@@ -115,33 +344,206 @@ func (h *Handler) handleRequest(ctx context.Context, request *Request) {
 		"user", request.User,
 		"password", request.Password,
 		"client_version", request.ClientVersion,
+		"local_port", request.LocalPort,
+		"variant", request.Variant,
 	)
 
 	geo, err := h.ipgeoQuerier.Query(ctx, request.Ip)
 	if err != nil {
 		loginLogger.Errorf("get ip geo: %v", err)
+		geo = &ipgeo.Info{} // count it into the unknown country and AS
 	} else {
 		loginLogger = loginLogger.With(
 			"location", geo.Location,
 		)
 	}
 
+	reputation := h.checkReputation(ctx, request.Ip)
+	if reputation != nil {
+		loginLogger = loginLogger.With(
+			"abuse_score", reputation.Score,
+			"blacklisted", reputation.Blacklisted(),
+		)
+	}
+
+	if err := h.db.IncrAttemptStats(
+		ctx,
+		request.Time,
+		request.Kind,
+		geo.CountryCode, geo.Asn,
+		request.Ip, request.User, request.Password,
+	); err != nil {
+		logger.Errorf("incr attempt stats: %v", err)
+	}
+
 	loginLogger.Infof("login")
 
-	h.reportAttempt(ctx, attempt)
+	event := &sink.Event{
+		Type:      sink.EventTypeAttempt,
+		Time:      request.Time,
+		Kind:      request.Kind,
+		Ip:        request.Ip,
+		Attempt:   attempt,
+		LocalPort: request.LocalPort,
+	}
+	if geo.Location != "" {
+		event.Geo = geo
+	}
+	event.Reputation = reputation
+	if attempt.Count == 1 && len(h.currentSinks()) > 0 {
+		// it's the first one of a new row, check whether there are the previous rows
+		attempts, err := h.db.ListBruteAttempts(ctx, request.Ip, 2)
+		if err != nil {
+			logger.Errorf("list attempts: %v", err)
+		}
+		event.NewIp = err == nil && len(attempts) == 1
+	}
+	h.currentSinks().Send(ctx, event)
+
+	campaign := h.incrCampaigns(ctx, request, geo)
+
+	h.reportAttempt(ctx, attempt, campaign)
 }
 
-func (h *Handler) reportAttempt(ctx context.Context, attempt *model.BruteAttempt) {
+func (h *Handler) handleSession(ctx context.Context, session *Session) {
+	logger := logs.From(ctx)
+
+	if len(session.Commands) == 0 {
+		return
+	}
+
+	commands := make([]*model.SessionCommand, 0, len(session.Commands))
+	for _, c := range session.Commands {
+		commands = append(commands, &model.SessionCommand{
+			SessionId: session.SessionId,
+			Ip:        session.Ip,
+			Kind:      session.Kind,
+			Command:   c.Line,
+			Flag:      c.Flag,
+			Time:      c.Time,
+		})
+	}
+	if err := h.db.Create(ctx, commands); err != nil {
+		logger.Errorf("create session commands: %v", err)
+	}
+
+	lines := make([]string, 0, len(session.Commands))
+	for _, c := range session.Commands {
+		lines = append(lines, c.Line)
+	}
+	h.currentSinks().Send(ctx, &sink.Event{
+		Type:      sink.EventTypeSession,
+		Time:      session.StartedAt,
+		Kind:      session.Kind,
+		Ip:        session.Ip,
+		SessionId: session.SessionId,
+		Commands:  lines,
+		Flags:     session.Flags(),
+	})
+
+	sessionLogger := logger.With(
+		"commands", len(session.Commands),
+		"duration", session.Commands[len(session.Commands)-1].Time.Sub(session.StartedAt).String(),
+	)
+	if flags := session.Flags(); len(flags) > 0 {
+		sessionLogger.With("flags", flags).Warnf("session with exploitation")
+	} else {
+		sessionLogger.Infof("session")
+	}
+}
+
+func (h *Handler) handlePayload(ctx context.Context, payload *Payload) {
+	logger := logs.From(ctx)
+
+	protocol := sniff.Detect(payload.Data)
+	if err := h.db.Create(ctx, &model.TcpPayload{
+		Ip:        payload.Ip,
+		Port:      payload.Port,
+		Protocol:  string(protocol),
+		Size:      len(payload.Data),
+		Hex:       hex.EncodeToString(payload.Data),
+		Printable: sniff.Printable(payload.Data),
+		Time:      payload.Time,
+	}); err != nil {
+		logger.Errorf("create tcp payload: %v", err)
+	}
+
+	h.currentSinks().Send(ctx, &sink.Event{
+		Type:     sink.EventTypePayload,
+		Time:     payload.Time,
+		Ip:       payload.Ip,
+		Port:     payload.Port,
+		Protocol: string(protocol),
+		Size:     len(payload.Data),
+	})
+
+	logger.With(
+		"protocol", protocol,
+		"size", len(payload.Data),
+	).Infof("payload")
+}
+
+// reportAttempt reports the attempt if it's frequent enough, or it's a part of the detected campaign.
+func (h *Handler) reportAttempt(ctx context.Context, attempt *model.BruteAttempt, campaign *model.Campaign) {
 	logger := logs.From(ctx)
 
 	if !h.abuseipdbClient.Enabled() {
 		return
 	}
-	if attempt.Count < 5 {
+	if !h.campaign.Report {
+		campaign = nil
+	}
+	rule, ok := h.reportRules[attempt.Kind]
+	if !ok {
+		return
+	}
+	if (attempt.Count < rule.minCount || attempt.Duration() < rule.minDuration) && campaign == nil {
+		return
+	}
+	comment, err := h.reportComment(rule, attempt, campaign)
+	if err != nil {
+		logger.Errorf("execute comment template: %v", err)
+		return
+	}
+	pending := &model.AbuseipdbPending{
+		Ip:          attempt.Ip,
+		Kind:        attempt.Kind,
+		Categories:  abuseipdb.JoinCategories(rule.categories),
+		Comment:     comment,
+		AttemptedAt: attempt.StoppedAt,
+		Attempts:    attempt.Count,
+	}
+
+	if h.reporting.Queue.Enabled {
+		// merge it into the pending one of the ip, which will be sent by the queue
+		existing, ok, err := h.db.GetAbuseipdbPending(ctx, attempt.Ip)
+		if err != nil {
+			logger.Errorf("get pending report: %v", err)
+			return
+		}
+		if ok {
+			pending.Retries = existing.Retries
+			pending.NextAt = existing.NextAt
+			pending.LastError = existing.LastError
+			if err := h.db.SaveAbuseipdbPending(ctx, pending); err != nil {
+				logger.Errorf("save pending report: %v", err)
+			}
+			return
+		}
+	}
+
+	if h.reporting.Bulk.Enabled {
+		// it's sent with the next bulk
+		pending.NextAt = time.Now()
+		if err := h.db.SaveAbuseipdbPending(ctx, pending); err != nil {
+			logger.Errorf("save pending report: %v", err)
+		}
 		return
 	}
+
 	if until, ok := h.abuseipdbClient.Cooldown(); ok {
 		logger.Debugf("abuseipdb cooldown, until: %v", until.Format(time.RFC3339))
+		h.deferReport(ctx, pending, until, "cooldown")
 		return
 	}
 	report, ok, err := h.db.LastAbuseipdbReport(ctx, attempt.Ip)
@@ -152,41 +554,17 @@ func (h *Handler) reportAttempt(ctx context.Context, attempt *model.BruteAttempt
 	if ok && time.Since(report.ReportedAt) < h.abuseipdbClient.Interval() {
 		return
 	}
-
-	comment := fmt.Sprintf(
-		"Funeypot detected %d %s attempts in %s. Last by user %q, password %q, client %q.",
-		attempt.Count,
-		attempt.Kind.String(),
-		attempt.Duration().Truncate(time.Second).String(),
-		attempt.User,
-		attempt.MaskedPassword(),
-		attempt.ShortClientVersion(),
-	)
-
-	var score int
-	switch attempt.Kind {
-	case model.BruteAttemptKindSsh:
-		score, err = h.abuseipdbClient.ReportSsh(ctx, attempt.Ip, attempt.StoppedAt, comment)
-	case model.BruteAttemptKindHttp:
-		score, err = h.abuseipdbClient.ReportHttp(ctx, attempt.Ip, attempt.StoppedAt, comment)
-	case model.BruteAttemptKindFtp:
-		score, err = h.abuseipdbClient.ReportFtp(ctx, attempt.Ip, attempt.StoppedAt, comment)
-	}
-	if err != nil {
-		logger.Errorf("report attempt: %v", err)
+	if remaining, err := h.remainingQuota(ctx); err != nil {
+		logger.Errorf("get remaining quota: %v", err)
+		return
+	} else if remaining <= 0 {
+		h.deferReport(ctx, pending, nextUtcDay(time.Now()), "daily quota exceeded")
 		return
 	}
 
-	logger.Infof("reported, score: %d", score)
-	if report != nil && report.Score != score {
-		logger.Infof("score changed, %d -> %d", report.Score, score)
-	}
-	newReport := &model.AbuseipdbReport{
-		Ip:         attempt.Ip,
-		ReportedAt: time.Now(),
-		Score:      score,
-	}
-	if err := h.db.Create(ctx, newReport); err != nil {
-		logger.Errorf("create report: %v", err)
+	if err := h.sendReport(ctx, pending, rule.categories, report); err != nil {
+		logger.Errorf("report attempt: %v", err)
+		pending.Retries = 1
+		h.deferReport(ctx, pending, h.retryAt(pending.Retries), err.Error())
 	}
 }
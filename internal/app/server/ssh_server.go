@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"sync/atomic"
 	"time"
 
 	"github.com/funeypot/funeypot/internal/app/config"
@@ -20,28 +21,44 @@ import (
 )
 
 type SshServer struct {
-	server *ssh.Server
-	delay  time.Duration
+	server   *ssh.Server
+	listener net.Listener // nil until it listens
+	delay    atomic.Int64 // time.Duration
 
 	handler *Handler
 }
 
-var _ Server = (*SshServer)(nil)
+var _ Reloadable = (*SshServer)(nil)
 
-func NewSshServer(cfg config.Ssh, handler *Handler) (*SshServer, error) {
+// NewSshServers returns a server for each listener, they share the same host key.
+func NewSshServers(cfg config.Ssh, handler *Handler) ([]*SshServer, error) {
+	signer, err := sshkey.GenerateSigner(cfg.KeySeed)
+	if err != nil {
+		return nil, fmt.Errorf("generate signer: %w", err)
+	}
+
+	listeners := cfg.AllListeners()
+	ret := make([]*SshServer, 0, len(listeners))
+	for _, v := range listeners {
+		ret = append(ret, newSshServer(v, signer, handler))
+	}
+	return ret, nil
+}
+
+func newSshServer(cfg config.SshListener, signer ssh.Signer, handler *Handler) *SshServer {
 	ret := &SshServer{
-		delay:   cfg.Delay,
 		handler: handler,
 	}
+	ret.delay.Store(int64(*cfg.Delay))
 
-	signer, err := sshkey.GenerateSigner(cfg.KeySeed)
-	if err != nil {
-		return nil, fmt.Errorf("generate signer: %w", err)
+	version := cfg.Version
+	if version == "" {
+		version = fakever.SshVersion
 	}
 
 	ret.server = &ssh.Server{
 		HostSigners: []ssh.Signer{signer},
-		Version:     fakever.SshVersion,
+		Version:     version,
 		Addr:        cfg.Address,
 		Handler: func(session ssh.Session) {
 			_ = session.Exit(0)
@@ -53,23 +70,56 @@ func NewSshServer(cfg config.Ssh, handler *Handler) (*SshServer, error) {
 		PasswordHandler: ret.handlePassword,
 	}
 
-	return ret, nil
+	return ret
 }
 
 func (s *SshServer) Startup(ctx context.Context, cancel context.CancelFunc) {
+	logger := logs.From(ctx)
+	logger.Infof("start ssh server, listen on %s", s.server.Addr)
+	if s.listener == nil {
+		if err := s.Listen(); err != nil {
+			logger.Errorf("listen: %v", err)
+			cancel()
+			return
+		}
+	}
 	go func() {
-		logger := logs.From(ctx)
-		logger.Infof("start ssh server, listen on %s", s.server.Addr)
-		if err := s.server.ListenAndServe(); !errors.Is(err, ssh.ErrServerClosed) {
-			logger.Errorf("listen and serve: %v", err)
+		if err := s.server.Serve(s.listener); !errors.Is(err, ssh.ErrServerClosed) {
+			logger.Errorf("serve: %v", err)
+			cancel()
 		}
-		cancel()
 	}()
 }
 
+func (s *SshServer) Listen() error {
+	listener, err := net.Listen("tcp", s.server.Addr)
+	if err != nil {
+		return err
+	}
+	s.listener = listener
+	return nil
+}
+
 func (s *SshServer) Shutdown(ctx context.Context) error {
-	logs.From(ctx).Infof("shutdown ssh server")
-	return s.server.Shutdown(ctx)
+	logs.From(ctx).Infof("shutdown ssh server on %s", s.server.Addr)
+	err := s.server.Shutdown(ctx)
+	if s.listener != nil {
+		// it's not closed by the server if it has never been served
+		_ = s.listener.Close()
+	}
+	return err
+}
+
+func (s *SshServer) Addr() string {
+	return s.server.Addr
+}
+
+// Reload applies the delay of the next server.
+func (s *SshServer) Reload(ctx context.Context, next Server) {
+	if n, ok := next.(*SshServer); ok && n.delay.Load() != s.delay.Load() {
+		logs.From(ctx).Infof("change delay of ssh server on %s to %v", s.server.Addr, time.Duration(n.delay.Load()))
+		s.delay.Store(n.delay.Load())
+	}
 }
 
 func (s *SshServer) handlePassword(ctx ssh.Context, password string) bool {
@@ -88,10 +138,14 @@ func (s *SshServer) handlePassword(ctx ssh.Context, password string) bool {
 			Password:      password,
 			SessionId:     ctx.SessionID(),
 			ClientVersion: ctx.ClientVersion(),
+			LocalAddr:     addrString(ctx.LocalAddr()),
+			LocalPort:     addrPort(ctx.LocalAddr()),
+			RemotePort:    addrPort(ctx.RemoteAddr()),
+			Variant:       "password",
 		})
 	}
 
-	wait := time.After(s.delay)
+	wait := time.After(time.Duration(s.delay.Load()))
 
 	select {
 	case <-ctx.Done():
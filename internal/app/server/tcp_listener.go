@@ -0,0 +1,133 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+)
+
+var errListenerClosed = errors.New("listener closed")
+
+// tcpListener accepts raw TCP connections for servers which implement their protocols by hand.
+type tcpListener struct {
+	addr   string
+	handle func(ctx context.Context, conn net.Conn)
+
+	mu       sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+func newTcpListener(addr string, handle func(ctx context.Context, conn net.Conn)) *tcpListener {
+	return &tcpListener{
+		addr:   addr,
+		handle: handle,
+		conns:  map[net.Conn]struct{}{},
+	}
+}
+
+// Listen binds the address, ListenAndServe serves on it.
+func (l *tcpListener) Listen() error {
+	listener, err := net.Listen("tcp", l.addr)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		_ = listener.Close()
+		return errListenerClosed
+	}
+	l.listener = listener
+	return nil
+}
+
+// ListenAndServe serves on the address, it listens first if Listen hasn't been called.
+func (l *tcpListener) ListenAndServe(ctx context.Context) error {
+	l.mu.Lock()
+	listener := l.listener
+	l.mu.Unlock()
+	if listener == nil {
+		if err := l.Listen(); err != nil {
+			return err
+		}
+		l.mu.Lock()
+		listener = l.listener
+		l.mu.Unlock()
+	}
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			l.mu.Lock()
+			closed := l.closed
+			l.mu.Unlock()
+			if closed {
+				return errListenerClosed
+			}
+			return err
+		}
+
+		if !l.track(conn) {
+			_ = conn.Close()
+			return errListenerClosed
+		}
+		go func() {
+			defer l.untrack(conn)
+			defer conn.Close() //nolint:errcheck
+			l.handle(ctx, conn)
+		}()
+	}
+}
+
+// Shutdown stops accepting new connections, closes the active ones and waits for their handlers to return.
+func (l *tcpListener) Shutdown(_ context.Context) error {
+	l.mu.Lock()
+	l.closed = true
+	var err error
+	if l.listener != nil {
+		err = l.listener.Close()
+	}
+	for conn := range l.conns {
+		_ = conn.Close()
+	}
+	l.mu.Unlock()
+
+	// the handlers return soon since their connections have been closed
+	l.wg.Wait()
+	return err
+}
+
+func (l *tcpListener) track(conn net.Conn) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return false
+	}
+	l.conns[conn] = struct{}{}
+	l.wg.Add(1)
+	return true
+}
+
+func (l *tcpListener) untrack(conn net.Conn) {
+	l.mu.Lock()
+	delete(l.conns, conn)
+	l.mu.Unlock()
+	l.wg.Done()
+}
+
+// remoteIp returns the IP of the remote address, or false if it is not a valid IP address.
+func remoteIp(addr net.Addr) (string, bool) {
+	ip, _, err := net.SplitHostPort(addr.String())
+	if err != nil || net.ParseIP(ip) == nil {
+		return "", false
+	}
+	return ip, true
+}
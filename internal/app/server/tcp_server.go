@@ -0,0 +1,133 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/funeypot/funeypot/internal/app/config"
+	"github.com/funeypot/funeypot/internal/pkg/logs"
+)
+
+// TcpServer is a generic TCP listener for the ports which are not emulated,
+// it records the first bytes sent by the clients.
+type TcpServer struct {
+	listener    *tcpListener
+	port        int
+	banner      []byte
+	readTimeout time.Duration
+	maxBytes    int
+
+	handler *Handler
+}
+
+var _ Server = (*TcpServer)(nil)
+
+// NewTcpServers returns a server for each configured listener.
+func NewTcpServers(cfg []config.Tcp, handler *Handler) []*TcpServer {
+	ret := make([]*TcpServer, 0, len(cfg))
+	for _, v := range cfg {
+		ret = append(ret, NewTcpServer(v, handler))
+	}
+	return ret
+}
+
+func NewTcpServer(cfg config.Tcp, handler *Handler) *TcpServer {
+	ret := &TcpServer{
+		banner:      []byte(cfg.Banner),
+		readTimeout: cfg.ReadTimeout,
+		maxBytes:    cfg.MaxBytes,
+		handler:     handler,
+	}
+	if _, port, err := net.SplitHostPort(cfg.Address); err == nil {
+		ret.port, _ = strconv.Atoi(port)
+	}
+	ret.listener = newTcpListener(cfg.Address, ret.handleConn)
+	return ret
+}
+
+func (s *TcpServer) Enabled() bool {
+	return s != nil
+}
+
+func (s *TcpServer) Startup(ctx context.Context, cancel context.CancelFunc) {
+	logger := logs.From(ctx)
+
+	if !s.Enabled() {
+		logger.Infof("skip starting tcp server since it is not enabled")
+		return
+	}
+	go func() {
+		logger.Infof("start tcp server, listen on %s", s.listener.addr)
+		if err := s.listener.ListenAndServe(ctx); !errors.Is(err, errListenerClosed) {
+			logger.Errorf("listen and serve: %v", err)
+			cancel()
+		}
+	}()
+}
+
+func (s *TcpServer) Listen() error {
+	if !s.Enabled() {
+		return nil
+	}
+	return s.listener.Listen()
+}
+
+func (s *TcpServer) Shutdown(ctx context.Context) error {
+	if !s.Enabled() {
+		return nil
+	}
+	logs.From(ctx).Infof("shutdown tcp server on %s", s.listener.addr)
+	return s.listener.Shutdown(ctx)
+}
+
+func (s *TcpServer) Addr() string {
+	if !s.Enabled() {
+		return ""
+	}
+	return s.listener.addr
+}
+
+func (s *TcpServer) handleConn(ctx context.Context, conn net.Conn) {
+	logger := logs.From(ctx)
+
+	ip, ok := remoteIp(conn.RemoteAddr())
+	if !ok {
+		logger.Warnf("invalid remote addr %q", conn.RemoteAddr().String())
+		return
+	}
+	payload := &Payload{
+		Ip:   ip,
+		Port: s.port,
+		Time: time.Now(),
+	}
+	defer func() {
+		s.handler.HandlePayload(ctx, payload)
+	}()
+
+	if len(s.banner) > 0 {
+		_ = conn.SetWriteDeadline(time.Now().Add(s.readTimeout))
+		if _, err := conn.Write(s.banner); err != nil {
+			logger.Debugf("write banner: %v", err)
+			return
+		}
+	}
+
+	// read until the buffer is full, the client closes the connection, or the timeout
+	_ = conn.SetReadDeadline(time.Now().Add(s.readTimeout))
+	buf := make([]byte, s.maxBytes)
+	n, err := io.ReadFull(conn, buf)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, net.ErrClosed) {
+		var netErr net.Error
+		if !errors.As(err, &netErr) || !netErr.Timeout() {
+			logger.Debugf("read payload: %v", err)
+		}
+	}
+	payload.Data = buf[:n]
+}
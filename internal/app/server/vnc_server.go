@@ -0,0 +1,211 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/funeypot/funeypot/internal/app/config"
+	"github.com/funeypot/funeypot/internal/app/model"
+	"github.com/funeypot/funeypot/internal/pkg/logs"
+	"github.com/funeypot/funeypot/internal/pkg/vncauth"
+
+	"github.com/google/uuid"
+)
+
+const (
+	vncTimeout = 30 * time.Second
+
+	vncSecurityTypeVncAuth = 2
+)
+
+var vncVersionRegexp = regexp.MustCompile(`^RFB (\d{3})\.(\d{3})$`)
+
+type VncServer struct {
+	listener *tcpListener
+	version  string
+	minor    int // the minor version of the server
+	wordlist *vncauth.Wordlist
+
+	handler *Handler
+}
+
+var _ Server = (*VncServer)(nil)
+
+func NewVncServer(cfg config.Vnc, handler *Handler) (*VncServer, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	matches := vncVersionRegexp.FindStringSubmatch(cfg.Version)
+	if matches == nil {
+		return nil, fmt.Errorf("invalid version %q", cfg.Version)
+	}
+	minor, _ := strconv.Atoi(matches[2])
+
+	ret := &VncServer{
+		version: cfg.Version,
+		minor:   minor,
+		handler: handler,
+	}
+	if cfg.Wordlist != "" {
+		wordlist, err := vncauth.LoadWordlist(cfg.Wordlist)
+		if err != nil {
+			return nil, fmt.Errorf("load wordlist: %w", err)
+		}
+		ret.wordlist = wordlist
+	}
+	ret.listener = newTcpListener(cfg.Address, ret.handleConn)
+
+	return ret, nil
+}
+
+func (s *VncServer) Enabled() bool {
+	return s != nil
+}
+
+func (s *VncServer) Startup(ctx context.Context, cancel context.CancelFunc) {
+	logger := logs.From(ctx)
+
+	if !s.Enabled() {
+		logger.Infof("skip starting vnc server since it is not enabled")
+		return
+	}
+	go func() {
+		logger.Infof("start vnc server, listen on %s, %d passwords in wordlist", s.listener.addr, s.wordlist.Len())
+		if err := s.listener.ListenAndServe(ctx); !errors.Is(err, errListenerClosed) {
+			logger.Errorf("listen and serve: %v", err)
+			cancel()
+		}
+	}()
+}
+
+func (s *VncServer) Listen() error {
+	if !s.Enabled() {
+		return nil
+	}
+	return s.listener.Listen()
+}
+
+func (s *VncServer) Shutdown(ctx context.Context) error {
+	if !s.Enabled() {
+		return nil
+	}
+	logs.From(ctx).Infof("shutdown vnc server")
+	return s.listener.Shutdown(ctx)
+}
+
+func (s *VncServer) Addr() string {
+	if !s.Enabled() {
+		return ""
+	}
+	return s.listener.addr
+}
+
+func (s *VncServer) handleConn(ctx context.Context, conn net.Conn) {
+	logger := logs.From(ctx)
+
+	ip, ok := remoteIp(conn.RemoteAddr())
+	if !ok {
+		logger.Warnf("invalid remote addr %q", conn.RemoteAddr().String())
+		return
+	}
+	_ = conn.SetDeadline(time.Now().Add(vncTimeout))
+
+	if _, err := conn.Write([]byte(s.version + "\n")); err != nil {
+		logger.Debugf("write version: %v", err)
+		return
+	}
+
+	buf := make([]byte, 12)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		logger.Debugf("read version: %v", err)
+		return
+	}
+	clientVersion := string(buf[:11])
+	matches := vncVersionRegexp.FindStringSubmatch(clientVersion)
+	if matches == nil || buf[11] != '\n' {
+		logger.Debugf("invalid client version %q", buf)
+		return
+	}
+	minor, _ := strconv.Atoi(matches[2])
+	// both sides use the lower version
+	minor = min(minor, s.minor)
+
+	if minor >= 7 {
+		// the server offers the security types, and the client picks one
+		if _, err := conn.Write([]byte{1, vncSecurityTypeVncAuth}); err != nil {
+			logger.Debugf("write security types: %v", err)
+			return
+		}
+		if _, err := io.ReadFull(conn, buf[:1]); err != nil {
+			logger.Debugf("read security type: %v", err)
+			return
+		}
+		if buf[0] != vncSecurityTypeVncAuth {
+			logger.Debugf("unsupported security type %d", buf[0])
+			return
+		}
+	} else {
+		// the server decides the security type for RFB 3.3
+		if err := binary.Write(conn, binary.BigEndian, uint32(vncSecurityTypeVncAuth)); err != nil {
+			logger.Debugf("write security type: %v", err)
+			return
+		}
+	}
+
+	var challenge, response [vncauth.ChallengeSize]byte
+	_, _ = rand.Read(challenge[:])
+	if _, err := conn.Write(challenge[:]); err != nil {
+		logger.Debugf("write challenge: %v", err)
+		return
+	}
+	if _, err := io.ReadFull(conn, response[:]); err != nil {
+		logger.Debugf("read response: %v", err)
+		return
+	}
+
+	password, recovered := s.wordlist.Recover(challenge, response)
+	if !recovered {
+		logger.Debugf("password not recovered, challenge %x, response %x", challenge, response)
+	}
+
+	s.handler.Handle(ctx, &Request{
+		Kind:          model.BruteAttemptKindVnc,
+		Ip:            ip,
+		Time:          time.Now(),
+		Password:      password,
+		SessionId:     uuid.New().String(),
+		ClientVersion: clientVersion,
+		LocalAddr:     addrString(conn.LocalAddr()),
+		LocalPort:     addrPort(conn.LocalAddr()),
+		RemotePort:    addrPort(conn.RemoteAddr()),
+		Variant:       "vnc_auth",
+		Extra: map[string]string{
+			// so the password can be recovered later with other wordlists
+			"challenge": hex.EncodeToString(challenge[:]),
+			"response":  hex.EncodeToString(response[:]),
+			"recovered": strconv.FormatBool(recovered),
+		},
+	})
+
+	// SecurityResult failed, with the reason since RFB 3.8
+	result := binary.BigEndian.AppendUint32(nil, 1)
+	if minor >= 8 {
+		const reason = "Authentication failed"
+		result = binary.BigEndian.AppendUint32(result, uint32(len(reason)))
+		result = append(result, reason...)
+	}
+	_, _ = conn.Write(result)
+}
@@ -0,0 +1,325 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package sink
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/funeypot/funeypot/internal/app/config"
+	"github.com/funeypot/funeypot/internal/pkg/logs"
+
+	"github.com/go-resty/resty/v2"
+)
+
+type chatAlert string
+
+const (
+	chatAlertNewIp     chatAlert = "new_ip"
+	chatAlertThreshold chatAlert = "threshold"
+	chatAlertSession   chatAlert = "session"
+	chatAlertCampaign  chatAlert = "campaign"
+)
+
+var defaultChatTemplates = map[chatAlert]string{
+	chatAlertNewIp: `New attacker {{.Ip}}{{with .Location}} from {{.}}{{end}} tried {{.Kind}} ` +
+		`with user {{printf "%q" .User}} and password {{printf "%q" .Password}}.`,
+	chatAlertThreshold: `{{.Ip}}{{with .Location}} from {{.}}{{end}} made {{.Count}} {{.Kind}} attempts in {{.Duration}}, ` +
+		`the last with user {{printf "%q" .User}} and password {{printf "%q" .Password}}.`,
+	chatAlertSession: `{{.Ip}} got into a {{.Kind}} session and ran {{len .Commands}} commands` +
+		`{{with .Flags}}, flags: {{join . ", "}}{{end}}.`,
+	chatAlertCampaign: `Campaign of {{.Ips}} IPs from {{.Network}} made {{.Count}} {{.Kind}} attempts in {{.Duration}}, ` +
+		`detected by {{.Ip}}.`,
+}
+
+// chatData is the data of the templates.
+type chatData struct {
+	Kind          string
+	Ip            string
+	User          string
+	Password      string
+	ClientVersion string
+	Count         int64
+	Duration      string
+	Location      string
+	Country       string
+	Asn           int
+	Threshold     int
+	SessionId     string
+	Commands      []string
+	Flags         []string
+	Network       string
+	Ips           int64
+}
+
+// chatProvider builds the request to post a message to the chat.
+type chatProvider interface {
+	request(text string) (url string, body any)
+}
+
+var chatProviders = map[string]func(cfg config.Chat) chatProvider{
+	"slack": func(cfg config.Chat) chatProvider {
+		return slackProvider{url: cfg.Url}
+	},
+	"discord": func(cfg config.Chat) chatProvider {
+		return discordProvider{url: cfg.Url}
+	},
+	"telegram": func(cfg config.Chat) chatProvider {
+		ret := telegramProvider{url: strings.TrimSuffix(cfg.Url, "/"), token: cfg.Token, chatId: cfg.ChatId}
+		if ret.url == "" {
+			ret.url = "https://api.telegram.org"
+		}
+		return ret
+	},
+}
+
+// see https://api.slack.com/messaging/webhooks
+type slackProvider struct {
+	url string
+}
+
+// slackEscaper escapes the control characters of slack, or the credentials like "<!channel>" would notify everyone.
+// See https://api.slack.com/reference/surfaces/formatting#escaping
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+func (p slackProvider) request(text string) (string, any) {
+	return p.url, map[string]string{"text": slackEscaper.Replace(text)}
+}
+
+// see https://discord.com/developers/docs/resources/webhook#execute-webhook
+type discordProvider struct {
+	url string
+}
+
+func (p discordProvider) request(text string) (string, any) {
+	// the content is up to 2000 characters
+	if r := []rune(text); len(r) > 2000 {
+		text = string(r[:1999]) + "…"
+	}
+	return p.url, map[string]any{
+		"content": text,
+		// never notify anyone, or the credentials like "@everyone" would
+		"allowed_mentions": map[string]any{"parse": []string{}},
+	}
+}
+
+// see https://core.telegram.org/bots/api#sendmessage
+type telegramProvider struct {
+	url    string
+	token  string
+	chatId string
+}
+
+func (p telegramProvider) request(text string) (string, any) {
+	return p.url + "/bot" + p.token + "/sendMessage", map[string]string{"chat_id": p.chatId, "text": text}
+}
+
+type Chat struct {
+	provider     chatProvider
+	client       *resty.Client
+	alerts       config.ChatAlerts
+	templates    map[chatAlert]*template.Template
+	maskPassword bool
+	limiter      *chatLimiter
+	queue        chan string
+	cancel       context.CancelFunc
+}
+
+var _ Sink = (*Chat)(nil)
+
+// NewChat returns a sink sending alerts to the chat until the context is done or it's closed,
+// or nil if it is not enabled.
+func NewChat(ctx context.Context, cfg config.Chat) (*Chat, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	newProvider, ok := chatProviders[cfg.Provider]
+	if !ok {
+		return nil, fmt.Errorf("unknown provider %q", cfg.Provider)
+	}
+
+	templates := map[chatAlert]*template.Template{}
+	for alert, text := range map[chatAlert]string{
+		chatAlertNewIp:     cfg.Templates.NewIp,
+		chatAlertThreshold: cfg.Templates.Threshold,
+		chatAlertSession:   cfg.Templates.Session,
+		chatAlertCampaign:  cfg.Templates.Campaign,
+	} {
+		if text == "" {
+			text = defaultChatTemplates[alert]
+		}
+		t, err := template.New(string(alert)).
+			Funcs(template.FuncMap{"join": strings.Join}).
+			Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("parse template of %s: %w", alert, err)
+		}
+		templates[alert] = t
+	}
+
+	ret := &Chat{
+		provider:     newProvider(cfg),
+		client:       resty.New().SetTimeout(cfg.Timeout),
+		alerts:       cfg.Alerts,
+		templates:    templates,
+		maskPassword: cfg.MaskPassword,
+		limiter: &chatLimiter{
+			perIp:   cfg.RateLimit.PerIp,
+			perHour: cfg.RateLimit.PerHour,
+		},
+		queue: make(chan string, cfg.BufferSize),
+	}
+	ctx, ret.cancel = context.WithCancel(ctx)
+	go ret.run(ctx)
+	return ret, nil
+}
+
+func (c *Chat) Enabled() bool {
+	return c != nil
+}
+
+func (c *Chat) Close() {
+	c.cancel()
+}
+
+func (c *Chat) Send(ctx context.Context, event *Event) {
+	logger := logs.From(ctx)
+
+	alert, data := c.alert(event)
+	if alert == "" {
+		return
+	}
+	if !c.limiter.allow(event.Ip, event.Time) {
+		logger.Debugf("drop chat alert %s over the rate limit", alert)
+		return
+	}
+
+	b := &strings.Builder{}
+	if err := c.templates[alert].Execute(b, data); err != nil {
+		logger.Errorf("execute template of %s: %v", alert, err)
+		return
+	}
+
+	select {
+	case c.queue <- b.String():
+	default:
+		logger.Warnf("chat queue full, drop alerts")
+	}
+}
+
+// alert returns the alert of the event and the data of its template, or "" if it shouldn't be alerted.
+func (c *Chat) alert(event *Event) (chatAlert, *chatData) {
+	data := &chatData{
+		Kind: event.Kind.String(),
+		Ip:   event.Ip,
+	}
+
+	switch event.Type {
+	case EventTypeAttempt:
+		attempt := event.Attempt
+		data.User = attempt.User
+		data.Password = attempt.Password
+		if c.maskPassword {
+			data.Password = attempt.MaskedPassword()
+		}
+		data.ClientVersion = attempt.ClientVersion
+		data.Count = attempt.Count
+		data.Duration = attempt.Duration().Truncate(time.Second).String()
+		if geo := event.Geo; geo != nil {
+			data.Location = geo.Location
+			data.Country = geo.CountryCode
+			data.Asn = geo.Asn
+		}
+		if c.alerts.NewIp && event.NewIp {
+			return chatAlertNewIp, data
+		}
+		if slices.Contains(c.alerts.Thresholds, int(attempt.Count)) {
+			data.Threshold = int(attempt.Count)
+			return chatAlertThreshold, data
+		}
+	case EventTypeSession:
+		if c.alerts.Session {
+			data.SessionId = event.SessionId
+			data.Commands = event.Commands
+			data.Flags = event.Flags
+			return chatAlertSession, data
+		}
+	case EventTypeCampaign:
+		if c.alerts.Campaign {
+			campaign := event.Campaign
+			data.Network = campaign.Network
+			data.Ips = campaign.Ips
+			data.Count = campaign.Attempts
+			data.Duration = campaign.StoppedAt.Sub(campaign.StartedAt).Truncate(time.Second).String()
+			return chatAlertCampaign, data
+		}
+	}
+	return "", nil
+}
+
+func (c *Chat) run(ctx context.Context) {
+	logger := logs.From(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case text := <-c.queue:
+			url, body := c.provider.request(text)
+			resp, err := c.client.R().
+				SetContext(ctx).
+				SetBody(body).
+				Post(url)
+			if err != nil {
+				// the url may contain the secret, like the token of telegram
+				logger.Errorf("send chat alert: %v", scrubUrl(err, url))
+				continue
+			}
+			if resp.IsError() {
+				logger.Errorf("send chat alert, response: %v %s", resp.Status(), resp.String())
+			}
+		}
+	}
+}
+
+// scrubUrl removes the url from the error.
+func scrubUrl(err error, url string) string {
+	return strings.ReplaceAll(err.Error(), url, "<url>")
+}
+
+// chatLimiter limits the alerts in the fixed windows of hours.
+type chatLimiter struct {
+	perIp   int
+	perHour int
+
+	mu    sync.Mutex
+	hour  time.Time
+	total int
+	ips   map[string]int
+}
+
+func (l *chatLimiter) allow(ip string, t time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if hour := t.Truncate(time.Hour); !hour.Equal(l.hour) {
+		l.hour = hour
+		l.total = 0
+		l.ips = map[string]int{}
+	}
+	if l.perHour > 0 && l.total >= l.perHour {
+		return false
+	}
+	if l.perIp > 0 && l.ips[ip] >= l.perIp {
+		return false
+	}
+	l.total++
+	l.ips[ip]++
+	return true
+}
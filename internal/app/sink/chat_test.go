@@ -0,0 +1,224 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/funeypot/funeypot/internal/app/config"
+	"github.com/funeypot/funeypot/internal/app/model"
+	"github.com/funeypot/funeypot/internal/pkg/ipgeo"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChat(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	type request struct {
+		path string
+		body map[string]any
+	}
+	requests := make(chan request, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := map[string]any{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		requests <- request{path: r.URL.Path, body: body}
+	}))
+	defer server.Close()
+
+	receive := func() request {
+		select {
+		case r := <-requests:
+			return r
+		case <-time.After(5 * time.Second):
+			t.Fatal("no request")
+			return request{}
+		}
+	}
+	assertNothing := func() {
+		select {
+		case r := <-requests:
+			t.Fatalf("unexpected request: %v", r)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+
+	newConfig := func(provider string) config.Chat {
+		return config.Chat{
+			Enabled:      true,
+			Provider:     provider,
+			Url:          server.URL + "/webhook",
+			Token:        "token",
+			ChatId:       "42",
+			MaskPassword: true,
+			Timeout:      time.Second,
+			BufferSize:   10,
+			Alerts: config.ChatAlerts{
+				NewIp:      true,
+				Thresholds: []int{3},
+				Session:    true,
+				Campaign:   true,
+			},
+		}
+	}
+
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	attemptEvent := func(ip string, count int64, newIp bool) *Event {
+		return &Event{
+			Type: EventTypeAttempt,
+			Time: now,
+			Kind: model.BruteAttemptKindSsh,
+			Ip:   ip,
+			Attempt: &model.BruteAttempt{
+				Ip:        ip,
+				Kind:      model.BruteAttemptKindSsh,
+				StartedAt: now.Add(-time.Minute),
+				StoppedAt: now,
+				Count:     count,
+				User:      "root",
+				Password:  "password",
+			},
+			NewIp: newIp,
+			Geo:   &ipgeo.Info{Location: "Tokyo, Japan"},
+		}
+	}
+
+	t.Run("slack", func(t *testing.T) {
+		chat, err := NewChat(ctx, newConfig("slack"))
+		require.NoError(t, err)
+
+		chat.Send(ctx, attemptEvent("203.0.113.1", 1, true))
+		r := receive()
+		assert.Equal(t, "/webhook", r.path)
+		assert.Equal(t, `New attacker 203.0.113.1 from Tokyo, Japan tried ssh with user "root" and password "pa****rd".`, r.body["text"])
+
+		// neither new nor a threshold
+		chat.Send(ctx, attemptEvent("203.0.113.1", 2, false))
+		assertNothing()
+
+		chat.Send(ctx, attemptEvent("203.0.113.1", 3, false))
+		assert.Equal(t, `203.0.113.1 from Tokyo, Japan made 3 ssh attempts in 1m0s, the last with user "root" and password "pa****rd".`, receive().body["text"])
+
+		chat.Send(ctx, &Event{
+			Type:     EventTypeSession,
+			Time:     now,
+			Kind:     model.BruteAttemptKindRedis,
+			Ip:       "203.0.113.1",
+			Commands: []string{"INFO", "CONFIG SET dir /root/.ssh"},
+			Flags:    []string{"ssh_key", "config"},
+		})
+		assert.Equal(t, `203.0.113.1 got into a redis session and ran 2 commands, flags: ssh_key, config.`, receive().body["text"])
+
+		chat.Send(ctx, &Event{
+			Type: EventTypeCampaign,
+			Time: now,
+			Kind: model.BruteAttemptKindSsh,
+			Ip:   "203.0.113.5",
+			Campaign: &model.Campaign{
+				Network:   "203.0.113.0/24",
+				Ips:       5,
+				Attempts:  12,
+				StartedAt: now.Add(-10 * time.Minute),
+				StoppedAt: now,
+			},
+		})
+		assert.Equal(t, `Campaign of 5 IPs from 203.0.113.0/24 made 12 ssh attempts in 10m0s, detected by 203.0.113.5.`, receive().body["text"])
+
+		chat.Send(ctx, &Event{Type: EventTypePayload, Time: now, Ip: "203.0.113.1"})
+		assertNothing()
+	})
+
+	t.Run("discord", func(t *testing.T) {
+		cfg := newConfig("discord")
+		cfg.MaskPassword = false
+		cfg.Templates.NewIp = "{{.Kind}} {{.Ip}} {{.Password}}"
+		chat, err := NewChat(ctx, cfg)
+		require.NoError(t, err)
+
+		chat.Send(ctx, attemptEvent("203.0.113.1", 1, true))
+		r := receive()
+		assert.Equal(t, "/webhook", r.path)
+		assert.Equal(t, "ssh 203.0.113.1 password", r.body["content"])
+	})
+
+	t.Run("telegram", func(t *testing.T) {
+		cfg := newConfig("telegram")
+		cfg.Url = server.URL
+		chat, err := NewChat(ctx, cfg)
+		require.NoError(t, err)
+
+		chat.Send(ctx, attemptEvent("203.0.113.1", 1, true))
+		r := receive()
+		assert.Equal(t, "/bottoken/sendMessage", r.path)
+		assert.Equal(t, "42", r.body["chat_id"])
+		assert.Contains(t, r.body["text"], "New attacker 203.0.113.1")
+	})
+
+	t.Run("mentions", func(t *testing.T) {
+		cfg := newConfig("slack")
+		cfg.MaskPassword = false
+		chat, err := NewChat(ctx, cfg)
+		require.NoError(t, err)
+
+		event := attemptEvent("203.0.113.1", 1, true)
+		event.Attempt.User = "<!channel>"
+		event.Attempt.Password = "a&b"
+		chat.Send(ctx, event)
+		assert.Equal(t, `New attacker 203.0.113.1 from Tokyo, Japan tried ssh with user "&lt;!channel&gt;" and password "a&amp;b".`, receive().body["text"])
+
+		cfg = newConfig("discord")
+		chat, err = NewChat(ctx, cfg)
+		require.NoError(t, err)
+
+		event = attemptEvent("203.0.113.2", 1, true)
+		event.Attempt.User = "@everyone"
+		chat.Send(ctx, event)
+		r := receive()
+		assert.Contains(t, r.body["content"], `with user "@everyone"`)
+		assert.Equal(t, map[string]any{"parse": []any{}}, r.body["allowed_mentions"])
+	})
+
+	t.Run("rate limit", func(t *testing.T) {
+		cfg := newConfig("slack")
+		cfg.RateLimit = config.ChatRateLimit{PerIp: 1, PerHour: 2}
+		chat, err := NewChat(ctx, cfg)
+		require.NoError(t, err)
+
+		chat.Send(ctx, attemptEvent("203.0.113.1", 1, true))
+		receive()
+		chat.Send(ctx, attemptEvent("203.0.113.1", 3, false))
+		assertNothing()
+		chat.Send(ctx, attemptEvent("203.0.113.2", 1, true))
+		receive()
+		chat.Send(ctx, attemptEvent("203.0.113.3", 1, true))
+		assertNothing()
+
+		// the next hour
+		event := attemptEvent("203.0.113.1", 3, false)
+		event.Time = now.Add(time.Hour)
+		chat.Send(ctx, event)
+		receive()
+	})
+
+	t.Run("invalid template", func(t *testing.T) {
+		cfg := newConfig("slack")
+		cfg.Templates.Session = "{{.Ip"
+		_, err := NewChat(ctx, cfg)
+		assert.Error(t, err)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		chat, err := NewChat(ctx, config.Chat{})
+		require.NoError(t, err)
+		assert.False(t, chat.Enabled())
+	})
+}
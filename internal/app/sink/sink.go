@@ -0,0 +1,84 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+// Package sink sends the events of the honeypot to the external systems, like a syslog collector.
+package sink
+
+import (
+	"context"
+	"time"
+
+	"github.com/funeypot/funeypot/internal/app/model"
+	"github.com/funeypot/funeypot/internal/pkg/ipgeo"
+)
+
+type EventType string
+
+const (
+	EventTypeAttempt  EventType = "attempt"
+	EventTypeSession  EventType = "session"
+	EventTypePayload  EventType = "payload"
+	EventTypeCampaign EventType = "campaign"
+)
+
+// Event is something handled by the honeypot.
+type Event struct {
+	Type EventType
+	Time time.Time
+	Kind model.BruteAttemptKind // none for payloads
+	Ip   string
+
+	// for attempts
+	Attempt    *model.BruteAttempt // the aggregated attempt, with the credentials of the latest one
+	NewIp      bool                // whether it's the first attempt ever of the ip
+	LocalPort  int
+	Geo        *ipgeo.Info         // nil if unknown
+	Reputation *model.IpReputation // the reputation on AbuseIPDB, nil if unknown
+
+	// for sessions
+	SessionId string
+	Commands  []string
+	Flags     []string
+
+	// for payloads
+	Port     int
+	Protocol string
+	Size     int
+
+	// for campaigns, the ip is the one which gets it detected
+	Campaign *model.Campaign
+}
+
+// Sink receives the events, Send should never block the caller.
+type Sink interface {
+	Send(ctx context.Context, event *Event)
+	// Close stops sending, the events not sent yet are dropped.
+	Close()
+}
+
+// Sinks are the enabled sinks.
+type Sinks []Sink
+
+// NewSinks collects the enabled sinks, a disabled sink is a nil pointer.
+func NewSinks(syslog *Syslog, chat *Chat) Sinks {
+	var ret Sinks
+	if syslog.Enabled() {
+		ret = append(ret, syslog)
+	}
+	if chat.Enabled() {
+		ret = append(ret, chat)
+	}
+	return ret
+}
+
+func (s Sinks) Send(ctx context.Context, event *Event) {
+	for _, v := range s {
+		v.Send(ctx, event)
+	}
+}
+
+func (s Sinks) Close() {
+	for _, v := range s {
+		v.Close()
+	}
+}
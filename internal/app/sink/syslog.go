@@ -0,0 +1,219 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package sink
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/funeypot/funeypot/internal/app/config"
+	"github.com/funeypot/funeypot/internal/pkg/logs"
+	"github.com/funeypot/funeypot/internal/pkg/syslog"
+)
+
+// enterpriseId is in the ids of the structured data elements.
+// Funeypot has no registered private enterprise number, so it uses the one reserved for documentation, see RFC 5612.
+const enterpriseId = "32473"
+
+type Syslog struct {
+	writer       *syslog.Writer
+	facility     syslog.Facility
+	hostname     string
+	appName      string
+	procId       string
+	maskPassword bool
+	cancel       context.CancelFunc
+	full         atomic.Bool // whether the last event has been dropped, to log only when it changes
+}
+
+var _ Sink = (*Syslog)(nil)
+
+// NewSyslog returns a sink sending the events to the syslog collector until the context is done or it's closed,
+// or nil if it is not enabled.
+func NewSyslog(ctx context.Context, cfg config.Syslog) (*Syslog, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	facility, ok := syslog.ParseFacility(cfg.Facility)
+	if !ok {
+		return nil, fmt.Errorf("invalid facility %q", cfg.Facility)
+	}
+	hostname := cfg.Hostname
+	if hostname == "" {
+		hostname, _ = os.Hostname()
+	}
+
+	var tlsConfig *tls.Config
+	if cfg.Network == "tls" {
+		tlsConfig = &tls.Config{
+			ServerName:         cfg.Tls.ServerName,
+			InsecureSkipVerify: cfg.Tls.InsecureSkipVerify,
+		}
+		if cfg.Tls.CaFile != "" {
+			pem, err := os.ReadFile(cfg.Tls.CaFile)
+			if err != nil {
+				return nil, fmt.Errorf("read ca file: %w", err)
+			}
+			tlsConfig.RootCAs = x509.NewCertPool()
+			if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates in ca file %s", cfg.Tls.CaFile)
+			}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	writer, err := syslog.NewWriter(ctx, syslog.Options{
+		Network:    cfg.Network,
+		Address:    cfg.Address,
+		TLSConfig:  tlsConfig,
+		Timeout:    cfg.Timeout,
+		BufferSize: cfg.BufferSize,
+	})
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("new writer: %w", err)
+	}
+
+	return &Syslog{
+		writer:       writer,
+		facility:     facility,
+		hostname:     hostname,
+		appName:      cfg.AppName,
+		procId:       strconv.Itoa(os.Getpid()),
+		maskPassword: cfg.MaskPassword,
+		cancel:       cancel,
+	}, nil
+}
+
+func (s *Syslog) Enabled() bool {
+	return s != nil
+}
+
+func (s *Syslog) Close() {
+	s.cancel()
+}
+
+func (s *Syslog) Send(ctx context.Context, event *Event) {
+	message := s.message(event)
+	if message == nil {
+		return
+	}
+	if !s.writer.Write(message) {
+		if s.full.CompareAndSwap(false, true) {
+			logs.From(ctx).Warnf("syslog buffer full, drop events until it's available")
+		}
+	} else if s.full.CompareAndSwap(true, false) {
+		logs.From(ctx).Infof("syslog buffer available, %d events dropped in total", s.writer.Dropped())
+	}
+}
+
+func (s *Syslog) message(event *Event) *syslog.Message {
+	ret := &syslog.Message{
+		Facility:  s.facility,
+		Timestamp: event.Time,
+		Hostname:  s.hostname,
+		AppName:   s.appName,
+		ProcId:    s.procId,
+		MsgId:     string(event.Type),
+	}
+
+	switch event.Type {
+	case EventTypeAttempt:
+		attempt := event.Attempt
+		password := attempt.Password
+		if s.maskPassword {
+			password = attempt.MaskedPassword()
+		}
+		ret.Severity = syslog.SeverityNotice
+		ret.StructuredData = []syslog.Element{{
+			Id: "attempt@" + enterpriseId,
+			Params: []syslog.Param{
+				{Name: "kind", Value: event.Kind.String()},
+				{Name: "ip", Value: event.Ip},
+				{Name: "user", Value: attempt.User},
+				{Name: "password", Value: password},
+				{Name: "client_version", Value: attempt.ClientVersion},
+				{Name: "local_port", Value: strconv.Itoa(event.LocalPort)},
+				{Name: "count", Value: strconv.FormatInt(attempt.Count, 10)},
+				{Name: "duration", Value: attempt.Duration().String()},
+			},
+		}}
+		if geo := event.Geo; geo != nil && geo.Location != "" {
+			ret.StructuredData = append(ret.StructuredData, syslog.Element{
+				Id: "geo@" + enterpriseId,
+				Params: []syslog.Param{
+					{Name: "location", Value: geo.Location},
+					{Name: "country", Value: geo.CountryCode},
+					{Name: "asn", Value: strconv.Itoa(geo.Asn)},
+				},
+			})
+		}
+		if reputation := event.Reputation; reputation != nil {
+			ret.StructuredData = append(ret.StructuredData, syslog.Element{
+				Id: "reputation@" + enterpriseId,
+				Params: []syslog.Param{
+					{Name: "score", Value: strconv.Itoa(reputation.Score)},
+					{Name: "usage_type", Value: reputation.UsageType},
+					{Name: "isp", Value: reputation.Isp},
+					{Name: "total_reports", Value: strconv.Itoa(reputation.TotalReports)},
+					{Name: "blacklisted", Value: strconv.FormatBool(reputation.Blacklisted())},
+				},
+			})
+		}
+		ret.Msg = fmt.Sprintf("%s login from %s as user %q", event.Kind, event.Ip, attempt.User)
+	case EventTypeSession:
+		ret.Severity = syslog.SeverityNotice
+		if len(event.Flags) > 0 {
+			ret.Severity = syslog.SeverityWarning
+		}
+		ret.StructuredData = []syslog.Element{{
+			Id: "session@" + enterpriseId,
+			Params: []syslog.Param{
+				{Name: "kind", Value: event.Kind.String()},
+				{Name: "ip", Value: event.Ip},
+				{Name: "session_id", Value: event.SessionId},
+				{Name: "commands", Value: strconv.Itoa(len(event.Commands))},
+				{Name: "flags", Value: strings.Join(event.Flags, ",")},
+			},
+		}}
+		ret.Msg = fmt.Sprintf("%s session from %s with %d commands", event.Kind, event.Ip, len(event.Commands))
+	case EventTypePayload:
+		ret.Severity = syslog.SeverityInfo
+		ret.StructuredData = []syslog.Element{{
+			Id: "payload@" + enterpriseId,
+			Params: []syslog.Param{
+				{Name: "ip", Value: event.Ip},
+				{Name: "port", Value: strconv.Itoa(event.Port)},
+				{Name: "protocol", Value: event.Protocol},
+				{Name: "size", Value: strconv.Itoa(event.Size)},
+			},
+		}}
+		ret.Msg = fmt.Sprintf("%s payload from %s to port %d", event.Protocol, event.Ip, event.Port)
+	case EventTypeCampaign:
+		campaign := event.Campaign
+		ret.Severity = syslog.SeverityWarning
+		ret.StructuredData = []syslog.Element{{
+			Id: "campaign@" + enterpriseId,
+			Params: []syslog.Param{
+				{Name: "kind", Value: event.Kind.String()},
+				{Name: "ip", Value: event.Ip},
+				{Name: "network", Value: campaign.Network},
+				{Name: "ips", Value: strconv.FormatInt(campaign.Ips, 10)},
+				{Name: "attempts", Value: strconv.FormatInt(campaign.Attempts, 10)},
+				{Name: "duration", Value: campaign.StoppedAt.Sub(campaign.StartedAt).String()},
+			},
+		}}
+		ret.Msg = fmt.Sprintf("%s campaign from %s with %d ips", event.Kind, campaign.Network, campaign.Ips)
+	default:
+		return nil
+	}
+	return ret
+}
@@ -0,0 +1,174 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package sink
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/funeypot/funeypot/internal/app/config"
+	"github.com/funeypot/funeypot/internal/app/model"
+	"github.com/funeypot/funeypot/internal/pkg/ipgeo"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyslog(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close() // nolint:errcheck
+
+	newSyslog := func(maskPassword bool) *Syslog {
+		s, err := NewSyslog(ctx, config.Syslog{
+			Enabled:      true,
+			Network:      "udp",
+			Address:      conn.LocalAddr().String(),
+			Facility:     "local0",
+			Hostname:     "honeypot",
+			AppName:      "funeypot",
+			MaskPassword: maskPassword,
+			Timeout:      time.Second,
+			BufferSize:   10,
+		})
+		require.NoError(t, err)
+		s.procId = "1"
+		return s
+	}
+	read := func() string {
+		buf := make([]byte, 2048)
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+		n, _, err := conn.ReadFrom(buf)
+		require.NoError(t, err)
+		return string(buf[:n])
+	}
+
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	attempt := &model.BruteAttempt{
+		Ip:            "203.0.113.1",
+		Kind:          model.BruteAttemptKindSsh,
+		StartedAt:     now.Add(-time.Minute),
+		StoppedAt:     now,
+		Count:         3,
+		User:          "root",
+		Password:      "password",
+		ClientVersion: "SSH-2.0-Go",
+	}
+
+	t.Run("attempt", func(t *testing.T) {
+		newSyslog(false).Send(ctx, &Event{
+			Type:      EventTypeAttempt,
+			Time:      now,
+			Kind:      model.BruteAttemptKindSsh,
+			Ip:        "203.0.113.1",
+			Attempt:   attempt,
+			LocalPort: 22,
+			Geo:       &ipgeo.Info{Location: "Tokyo, Japan", CountryCode: "JP", Asn: 2497},
+		})
+		assert.Equal(t,
+			`<133>1 2024-01-02T03:04:05.000000Z honeypot funeypot 1 attempt `+
+				`[attempt@32473 kind="ssh" ip="203.0.113.1" user="root" password="password" client_version="SSH-2.0-Go" local_port="22" count="3" duration="1m0s"]`+
+				`[geo@32473 location="Tokyo, Japan" country="JP" asn="2497"] ssh login from 203.0.113.1 as user "root"`,
+			read(),
+		)
+	})
+
+	t.Run("attempt with reputation", func(t *testing.T) {
+		newSyslog(false).Send(ctx, &Event{
+			Type:    EventTypeAttempt,
+			Time:    now,
+			Kind:    model.BruteAttemptKindSsh,
+			Ip:      "203.0.113.1",
+			Attempt: attempt,
+			Reputation: &model.IpReputation{
+				Score:         100,
+				UsageType:     "Data Center/Web Hosting/Transit",
+				Isp:           "Example Inc.",
+				TotalReports:  1234,
+				BlacklistedAt: now,
+			},
+		})
+		assert.Contains(t, read(),
+			`[reputation@32473 score="100" usage_type="Data Center/Web Hosting/Transit" isp="Example Inc." total_reports="1234" blacklisted="true"]`)
+	})
+
+	t.Run("masked attempt without geo", func(t *testing.T) {
+		newSyslog(true).Send(ctx, &Event{
+			Type:    EventTypeAttempt,
+			Time:    now,
+			Kind:    model.BruteAttemptKindSsh,
+			Ip:      "203.0.113.1",
+			Attempt: attempt,
+		})
+		got := read()
+		assert.Contains(t, got, `password="pa****rd"`)
+		assert.NotContains(t, got, "geo@")
+	})
+
+	t.Run("session", func(t *testing.T) {
+		newSyslog(false).Send(ctx, &Event{
+			Type:      EventTypeSession,
+			Time:      now,
+			Kind:      model.BruteAttemptKindRedis,
+			Ip:        "203.0.113.1",
+			SessionId: "abc",
+			Commands:  []string{"INFO", "CONFIG SET dir /root/.ssh"},
+			Flags:     []string{"ssh_key"},
+		})
+		assert.Equal(t,
+			`<132>1 2024-01-02T03:04:05.000000Z honeypot funeypot 1 session `+
+				`[session@32473 kind="redis" ip="203.0.113.1" session_id="abc" commands="2" flags="ssh_key"] redis session from 203.0.113.1 with 2 commands`,
+			read(),
+		)
+	})
+
+	t.Run("payload", func(t *testing.T) {
+		newSyslog(false).Send(ctx, &Event{
+			Type:     EventTypePayload,
+			Time:     now,
+			Ip:       "203.0.113.1",
+			Port:     23,
+			Protocol: "unknown",
+			Size:     5,
+		})
+		assert.Equal(t,
+			`<134>1 2024-01-02T03:04:05.000000Z honeypot funeypot 1 payload `+
+				`[payload@32473 ip="203.0.113.1" port="23" protocol="unknown" size="5"] unknown payload from 203.0.113.1 to port 23`,
+			read(),
+		)
+	})
+
+	t.Run("campaign", func(t *testing.T) {
+		newSyslog(false).Send(ctx, &Event{
+			Type: EventTypeCampaign,
+			Time: now,
+			Kind: model.BruteAttemptKindSsh,
+			Ip:   "203.0.113.5",
+			Campaign: &model.Campaign{
+				Network:   "203.0.113.0/24",
+				Ips:       5,
+				Attempts:  12,
+				StartedAt: now.Add(-10 * time.Minute),
+				StoppedAt: now,
+			},
+		})
+		assert.Equal(t,
+			`<132>1 2024-01-02T03:04:05.000000Z honeypot funeypot 1 campaign `+
+				`[campaign@32473 kind="ssh" ip="203.0.113.5" network="203.0.113.0/24" ips="5" attempts="12" duration="10m0s"] ssh campaign from 203.0.113.0/24 with 5 ips`,
+			read(),
+		)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		s, err := NewSyslog(ctx, config.Syslog{})
+		require.NoError(t, err)
+		assert.False(t, s.Enabled())
+		assert.Empty(t, NewSinks(s, nil))
+	})
+}
@@ -18,23 +18,32 @@ import (
 	"github.com/gochore/pt"
 )
 
-const ReportUrl = "https://api.abuseipdb.com/api/v2/report"
+// DefaultBaseUrl is the base URL of the API v2.
+const DefaultBaseUrl = "https://api.abuseipdb.com/api/v2"
 
 type Client struct {
-	key        string
-	interval   time.Duration
-	client     *resty.Client
-	retryUntil atomic.Pointer[time.Time]
+	key      string
+	interval atomic.Int64 // time.Duration
+	client   *resty.Client
+	// the endpoints are limited separately
+	retryUntil          atomic.Pointer[time.Time] // of reporting
+	checkRetryUntil     atomic.Pointer[time.Time]
+	blacklistRetryUntil atomic.Pointer[time.Time]
 }
 
-func NewClient(key string, interval time.Duration) *Client {
-	return &Client{
-		key:      key,
-		interval: interval,
+// NewClient returns a client of the API at the base URL, empty means DefaultBaseUrl.
+func NewClient(baseUrl, key string, interval time.Duration) *Client {
+	if baseUrl == "" {
+		baseUrl = DefaultBaseUrl
+	}
+	ret := &Client{
+		key: key,
 		client: resty.NewWithClient(&http.Client{
 			Transport: http.DefaultTransport,
-		}),
+		}).SetBaseURL(strings.TrimSuffix(baseUrl, "/")),
 	}
+	ret.interval.Store(int64(interval))
+	return ret
 }
 
 func (c *Client) Enabled() bool {
@@ -42,50 +51,70 @@ func (c *Client) Enabled() bool {
 }
 
 func (c *Client) Interval() time.Duration {
-	return c.interval
+	return time.Duration(c.interval.Load())
+}
+
+// SetInterval changes the interval of reporting a same ip, it's safe to call concurrently.
+func (c *Client) SetInterval(interval time.Duration) {
+	c.interval.Store(int64(interval))
 }
 
+// Cooldown returns the time until which the reporting is limited.
 func (c *Client) Cooldown() (time.Time, bool) {
-	if v := c.retryUntil.Load(); v != nil && time.Now().Before(*v) {
-		return *v, true
-	}
-	return time.Time{}, false
+	return cooldown(&c.retryUntil)
 }
 
-func (c *Client) ReportSsh(ctx context.Context, ip string, timestamp time.Time, comment string) (int, error) {
-	// see https://www.abuseipdb.com/categories
-	return c.Report(ctx, ip, []string{"18", "22"}, timestamp, comment)
+// CheckCooldown returns the time until which the checking is limited.
+func (c *Client) CheckCooldown() (time.Time, bool) {
+	return cooldown(&c.checkRetryUntil)
 }
 
-func (c *Client) ReportHttp(ctx context.Context, ip string, timestamp time.Time, comment string) (int, error) {
-	// see https://www.abuseipdb.com/categories
-	return c.Report(ctx, ip, []string{"18", "21"}, timestamp, comment)
+// BlacklistCooldown returns the time until which fetching the blacklist is limited.
+func (c *Client) BlacklistCooldown() (time.Time, bool) {
+	return cooldown(&c.blacklistRetryUntil)
 }
 
-func (c *Client) ReportFtp(ctx context.Context, ip string, timestamp time.Time, comment string) (int, error) {
-	// see https://www.abuseipdb.com/categories
-	return c.Report(ctx, ip, []string{"18", "5"}, timestamp, comment)
+func cooldown(retryUntil *atomic.Pointer[time.Time]) (time.Time, bool) {
+	if v := retryUntil.Load(); v != nil && time.Now().Before(*v) {
+		return *v, true
+	}
+	return time.Time{}, false
 }
 
-func (c *Client) Report(ctx context.Context, ip string, categories []string, timestamp time.Time, comment string) (int, error) {
-	result := &response{}
+// Report reports the ip with the ids of the categories, see Categories. It returns the abuse confidence score of the ip.
+func (c *Client) Report(ctx context.Context, ip string, categories []int, timestamp time.Time, comment string) (int, error) {
+	result := &response[struct {
+		IpAddress            string `json:"ipAddress"`
+		AbuseConfidenceScore int    `json:"abuseConfidenceScore"`
+	}]{}
 
-	resp, err := c.client.R().
-		SetContext(ctx).
-		SetHeader("Key", c.key).
+	req := c.client.R().
 		SetFormData(map[string]string{
 			"ip":         ip,
-			"categories": strings.Join(categories, ","),
+			"categories": JoinCategories(categories),
 			"timestamp":  timestamp.Format(time.RFC3339),
 			"comment":    comment,
-		}).
+		})
+	if err := c.do(ctx, req, http.MethodPost, "/report", &c.retryUntil, result); err != nil {
+		return 0, err
+	}
+
+	return result.Data.AbuseConfidenceScore, nil
+}
+
+// do sends the request with the key, and starts the cooldown if it's limited and retryUntil is not nil.
+func (c *Client) do(ctx context.Context, req *resty.Request, method, path string, retryUntil *atomic.Pointer[time.Time], result errorsGetter) error {
+	resp, err := req.
+		SetContext(ctx).
+		SetHeader("Key", c.key).
+		SetHeader("Accept", "application/json").
 		SetResult(result).
-		Post(ReportUrl)
+		Execute(method, path)
 	if err != nil {
-		return 0, fmt.Errorf("do request: %w", err)
+		return fmt.Errorf("do request: %w", err)
 	}
 
-	if resp.StatusCode() == http.StatusTooManyRequests {
+	if resp.StatusCode() == http.StatusTooManyRequests && retryUntil != nil {
 		// see: https://docs.abuseipdb.com/#api-daily-rate-limits
 		const headerKey = "Retry-After"
 		header := resp.Header().Get(headerKey)
@@ -94,31 +123,38 @@ func (c *Client) Report(ctx context.Context, ip string, categories []string, tim
 			logs.From(ctx).Warnf("invalid %q header: %q", headerKey, header)
 			// go on
 		} else {
-			c.retryUntil.Store(pt.P(time.Now().Add(time.Duration(retryAfter) * time.Second)))
+			retryUntil.Store(pt.P(time.Now().Add(time.Duration(retryAfter) * time.Second)))
 		}
 	}
 
 	if resp.IsError() {
-		return 0, fmt.Errorf("response: %v", resp.Status())
+		return fmt.Errorf("response: %v", resp.Status())
 	}
 
-	if len(result.Errors) > 0 {
-		return 0, fmt.Errorf("response: %v", result.Errors)
+	if errs := result.errors(); len(errs) > 0 {
+		return fmt.Errorf("response: %v", errs)
 	}
 
-	return result.Data.AbuseConfidenceScore, nil
+	return nil
 }
 
-type response struct {
-	Data struct {
-		IpAddress            string `json:"ipAddress"`
-		AbuseConfidenceScore int    `json:"abuseConfidenceScore"`
-	} `json:"data"`
-	Errors []struct {
-		Detail string `json:"detail"`
-		Status int    `json:"status"`
-		Source struct {
-			Parameter string `json:"parameter"`
-		} `json:"source"`
-	} `json:"errors"`
+type response[T any] struct {
+	Data   T             `json:"data"`
+	Errors []errorDetail `json:"errors"`
+}
+
+func (r *response[T]) errors() []errorDetail {
+	return r.Errors
+}
+
+type errorsGetter interface {
+	errors() []errorDetail
+}
+
+type errorDetail struct {
+	Detail string `json:"detail"`
+	Status int    `json:"status"`
+	Source struct {
+		Parameter string `json:"parameter"`
+	} `json:"source"`
 }
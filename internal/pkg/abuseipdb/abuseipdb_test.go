@@ -11,14 +11,34 @@ import (
 
 	"github.com/jarcoal/httpmock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestClient_Report(t *testing.T) {
 	httpmock.Activate()
 	defer httpmock.DeactivateAndReset()
+	t.Run("Report", func(t *testing.T) {
+		defer httpmock.Reset()
+		httpmock.RegisterResponder("POST", DefaultBaseUrl+"/report",
+			func(request *http.Request) (*http.Response, error) {
+				assert.Equal(t, "test_key", request.Header.Get("Key"))
+				assert.NoError(t, request.ParseForm())
+				assert.Equal(t, "127.0.0.1", request.PostForm.Get("ip"))
+				assert.Equal(t, "18,22", request.PostForm.Get("categories"))
+				assert.Equal(t, "test", request.PostForm.Get("comment"))
+				return httpmock.NewJsonResponse(200, map[string]any{
+					"data": map[string]any{"ipAddress": "127.0.0.1", "abuseConfidenceScore": 52},
+				})
+			})
+		client := NewClient("", "test_key", time.Second)
+
+		score, err := client.Report(context.Background(), "127.0.0.1", []int{18, 22}, time.Now(), "test")
+		assert.NoError(t, err)
+		assert.Equal(t, 52, score)
+	})
 	t.Run("Cooldown", func(t *testing.T) {
 		defer httpmock.Reset()
-		httpmock.RegisterResponder("POST", ReportUrl,
+		httpmock.RegisterResponder("POST", DefaultBaseUrl+"/report",
 			func(request *http.Request) (*http.Response, error) {
 				resp := httpmock.NewStringResponse(429, `{
   "errors": [
@@ -31,13 +51,13 @@ func TestClient_Report(t *testing.T) {
 				resp.Header.Set("Retry-After", "60")
 				return resp, nil
 			})
-		client := NewClient("test_key", time.Second)
+		client := NewClient("", "test_key", time.Second)
 
 		until, ok := client.Cooldown()
 		assert.False(t, ok)
 		assert.Zero(t, until)
 
-		score, err := client.ReportSsh(context.Background(), "127.0.0.1", time.Now(), "test")
+		score, err := client.Report(context.Background(), "127.0.0.1", []int{18, 22}, time.Now(), "test")
 		assert.ErrorContains(t, err, "429")
 		assert.Zero(t, score)
 
@@ -48,7 +68,7 @@ func TestClient_Report(t *testing.T) {
 
 	t.Run("Cooldown invalid Retry-After", func(t *testing.T) {
 		defer httpmock.Reset()
-		httpmock.RegisterResponder("POST", ReportUrl,
+		httpmock.RegisterResponder("POST", DefaultBaseUrl+"/report",
 			func(request *http.Request) (*http.Response, error) {
 				resp := httpmock.NewStringResponse(429, `{
   "errors": [
@@ -61,13 +81,13 @@ func TestClient_Report(t *testing.T) {
 				resp.Header.Set("Retry-After", "invalid")
 				return resp, nil
 			})
-		client := NewClient("test_key", time.Second)
+		client := NewClient("", "test_key", time.Second)
 
 		until, ok := client.Cooldown()
 		assert.False(t, ok)
 		assert.Zero(t, until)
 
-		score, err := client.ReportSsh(context.Background(), "127.0.0.1", time.Now(), "test")
+		score, err := client.Report(context.Background(), "127.0.0.1", []int{18, 22}, time.Now(), "test")
 		assert.ErrorContains(t, err, "429")
 		assert.Zero(t, score)
 
@@ -76,3 +96,40 @@ func TestClient_Report(t *testing.T) {
 		assert.Zero(t, until)
 	})
 }
+
+func TestParseCategory(t *testing.T) {
+	tests := []struct {
+		s      string
+		want   int
+		wantOk bool
+	}{
+		{"22", 22, true},
+		{"ssh", 22, true},
+		{"Brute-Force", 18, true},
+		{"web app attack", 21, true},
+		{"0", 0, false},
+		{"24", 24, false},
+		{"telnet", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			got, ok := ParseCategory(tt.s)
+			assert.Equal(t, tt.wantOk, ok)
+			if tt.wantOk {
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestJoinCategories(t *testing.T) {
+	s := JoinCategories([]int{18, 22})
+	assert.Equal(t, "18,22", s)
+
+	got, err := SplitCategories(s)
+	require.NoError(t, err)
+	assert.Equal(t, []int{18, 22}, got)
+
+	_, err = SplitCategories("18,ssh")
+	assert.Error(t, err)
+}
@@ -0,0 +1,67 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package abuseipdb
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// MaxBulkReports is the maximum count of the reports in a bulk report.
+const MaxBulkReports = 10000
+
+// BulkReport is a report in a bulk report.
+type BulkReport struct {
+	Ip         string
+	Categories []int
+	Timestamp  time.Time
+	Comment    string
+}
+
+// BulkResult is the result of a bulk report.
+type BulkResult struct {
+	Saved   int             `json:"savedReports"`
+	Invalid []InvalidReport `json:"invalidReports"`
+}
+
+// InvalidReport is a report refused in a bulk report, like a duplicate ip reported in 15 minutes.
+type InvalidReport struct {
+	Error string `json:"error"`
+	// Input is the ip of the report.
+	Input string `json:"input"`
+	// RowNumber is the row of the report in the CSV, counted by the API.
+	RowNumber int `json:"rowNumber"`
+}
+
+// BulkReport reports up to MaxBulkReports in a CSV, which counts as one request against the daily limit.
+// The refused reports are returned in BulkResult.Invalid, they're not an error.
+func (c *Client) BulkReport(ctx context.Context, reports []BulkReport) (*BulkResult, error) {
+	if len(reports) == 0 || len(reports) > MaxBulkReports {
+		return nil, fmt.Errorf("invalid count of reports %d, it should be 1 to %d", len(reports), MaxBulkReports)
+	}
+
+	// see: https://www.abuseipdb.com/bulk-report
+	buf := &bytes.Buffer{}
+	w := csv.NewWriter(buf)
+	_ = w.Write([]string{"IP", "Categories", "ReportDate", "Comment"})
+	for _, v := range reports {
+		_ = w.Write([]string{v.Ip, JoinCategories(v.Categories), v.Timestamp.Format(time.RFC3339), v.Comment})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, fmt.Errorf("write csv: %w", err)
+	}
+
+	result := &response[BulkResult]{}
+	req := c.client.R().SetFileReader("csv", "report.csv", buf)
+	if err := c.do(ctx, req, http.MethodPost, "/bulk-report", &c.retryUntil, result); err != nil {
+		return nil, err
+	}
+
+	return &result.Data, nil
+}
@@ -0,0 +1,55 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package abuseipdb
+
+import (
+	"context"
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_BulkReport(t *testing.T) {
+	timestamp := time.Date(2024, 3, 4, 5, 6, 7, 0, time.UTC)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v2/bulk-report", r.URL.Path)
+		assert.Equal(t, "test_key", r.Header.Get("Key"))
+		file, _, err := r.FormFile("csv")
+		require.NoError(t, err)
+		records, err := csv.NewReader(file).ReadAll()
+		require.NoError(t, err)
+		assert.Equal(t, [][]string{
+			{"IP", "Categories", "ReportDate", "Comment"},
+			{"127.0.0.1", "18,22", "2024-03-04T05:06:07Z", `test "1", with comma`},
+			{"127.0.0.2", "18", "2024-03-04T05:06:07Z", "test 2"},
+		}, records)
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data": {"savedReports": 1, "invalidReports": [{"error": "Duplicate IP", "input": "127.0.0.2", "rowNumber": 2}]}}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL+"/api/v2/", "test_key", time.Second)
+
+	result, err := client.BulkReport(context.Background(), []BulkReport{
+		{Ip: "127.0.0.1", Categories: []int{18, 22}, Timestamp: timestamp, Comment: `test "1", with comma`},
+		{Ip: "127.0.0.2", Categories: []int{18}, Timestamp: timestamp, Comment: "test 2"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, &BulkResult{
+		Saved:   1,
+		Invalid: []InvalidReport{{Error: "Duplicate IP", Input: "127.0.0.2", RowNumber: 2}},
+	}, result)
+
+	_, err = client.BulkReport(context.Background(), nil)
+	assert.Error(t, err)
+	_, err = client.BulkReport(context.Background(), make([]BulkReport, MaxBulkReports+1))
+	assert.Error(t, err)
+}
@@ -0,0 +1,72 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package abuseipdb
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Categories are the names of the categories of reports by ids, see https://www.abuseipdb.com/categories
+var Categories = map[int]string{
+	1:  "DNS Compromise",
+	2:  "DNS Poisoning",
+	3:  "Fraud Orders",
+	4:  "DDoS Attack",
+	5:  "FTP Brute-Force",
+	6:  "Ping of Death",
+	7:  "Phishing",
+	8:  "Fraud VoIP",
+	9:  "Open Proxy",
+	10: "Web Spam",
+	11: "Email Spam",
+	12: "Blog Spam",
+	13: "VPN IP",
+	14: "Port Scan",
+	15: "Hacking",
+	16: "SQL Injection",
+	17: "Spoofing",
+	18: "Brute-Force",
+	19: "Bad Web Bot",
+	20: "Exploited Host",
+	21: "Web App Attack",
+	22: "SSH",
+	23: "IoT Targeted",
+}
+
+// ParseCategory returns the id of the category by its id or its name case-insensitively, like "22" or "ssh".
+func ParseCategory(s string) (int, bool) {
+	if id, err := strconv.Atoi(s); err == nil {
+		_, ok := Categories[id]
+		return id, ok
+	}
+	for id, name := range Categories {
+		if strings.EqualFold(name, s) {
+			return id, true
+		}
+	}
+	return 0, false
+}
+
+// JoinCategories returns the ids of the categories separated by commas, like "18,22".
+func JoinCategories(categories []int) string {
+	ids := make([]string, 0, len(categories))
+	for _, v := range categories {
+		ids = append(ids, strconv.Itoa(v))
+	}
+	return strings.Join(ids, ",")
+}
+
+// SplitCategories parses the ids of the categories joined by JoinCategories.
+func SplitCategories(s string) ([]int, error) {
+	var ret []int
+	for _, v := range strings.Split(s, ",") {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, id)
+	}
+	return ret, nil
+}
@@ -0,0 +1,66 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package abuseipdb
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// CheckResult is the reputation of an ip.
+type CheckResult struct {
+	IpAddress            string `json:"ipAddress"`
+	IsWhitelisted        bool   `json:"isWhitelisted"`
+	AbuseConfidenceScore int    `json:"abuseConfidenceScore"`
+	CountryCode          string `json:"countryCode"`
+	// UsageType is like "Data Center/Web Hosting/Transit".
+	UsageType        string     `json:"usageType"`
+	Isp              string     `json:"isp"`
+	Domain           string     `json:"domain"`
+	TotalReports     int        `json:"totalReports"`
+	NumDistinctUsers int        `json:"numDistinctUsers"`
+	LastReportedAt   *time.Time `json:"lastReportedAt"`
+}
+
+// Check returns the reputation of the ip, with the reports in the max age of days, 1 to 365.
+func (c *Client) Check(ctx context.Context, ip string, maxAgeInDays int) (*CheckResult, error) {
+	result := &response[CheckResult]{}
+
+	req := c.client.R().
+		SetQueryParams(map[string]string{
+			"ipAddress":    ip,
+			"maxAgeInDays": strconv.Itoa(maxAgeInDays),
+		})
+	if err := c.do(ctx, req, http.MethodGet, "/check", &c.checkRetryUntil, result); err != nil {
+		return nil, err
+	}
+
+	return &result.Data, nil
+}
+
+// BlacklistedIp is an ip in the blacklist.
+type BlacklistedIp struct {
+	IpAddress            string    `json:"ipAddress"`
+	AbuseConfidenceScore int       `json:"abuseConfidenceScore"`
+	LastReportedAt       time.Time `json:"lastReportedAt"`
+}
+
+// Blacklist returns at most limit ips with the abuse confidence score at least the minimum, 25 to 100.
+// The limit and the minimum are capped by the plan of the key, see https://www.abuseipdb.com/pricing.
+func (c *Client) Blacklist(ctx context.Context, confidenceMinimum, limit int) ([]*BlacklistedIp, error) {
+	result := &response[[]*BlacklistedIp]{}
+
+	req := c.client.R().
+		SetQueryParams(map[string]string{
+			"confidenceMinimum": strconv.Itoa(confidenceMinimum),
+			"limit":             strconv.Itoa(limit),
+		})
+	if err := c.do(ctx, req, http.MethodGet, "/blacklist", &c.blacklistRetryUntil, result); err != nil {
+		return nil, err
+	}
+
+	return result.Data, nil
+}
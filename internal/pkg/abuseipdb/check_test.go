@@ -0,0 +1,94 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package abuseipdb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_Check(t *testing.T) {
+	var limited bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/check", r.URL.Path)
+		assert.Equal(t, "test_key", r.Header.Get("Key"))
+		assert.Equal(t, "203.0.113.1", r.URL.Query().Get("ipAddress"))
+		assert.Equal(t, "90", r.URL.Query().Get("maxAgeInDays"))
+
+		w.Header().Set("Content-Type", "application/json")
+		if limited {
+			w.Header().Set("Retry-After", "60")
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"errors": [{"detail": "Daily rate limit of 1000 requests exceeded.", "status": 429}]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"data": {"ipAddress": "203.0.113.1", "abuseConfidenceScore": 100, "usageType": "Data Center/Web Hosting/Transit", ` +
+			`"isp": "Example Inc.", "totalReports": 1234, "numDistinctUsers": 56, "lastReportedAt": "2024-03-04T05:06:07+00:00"}}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "test_key", time.Second)
+
+	result, err := client.Check(context.Background(), "203.0.113.1", 90)
+	require.NoError(t, err)
+	assert.Equal(t, 100, result.AbuseConfidenceScore)
+	assert.Equal(t, "Data Center/Web Hosting/Transit", result.UsageType)
+	assert.Equal(t, "Example Inc.", result.Isp)
+	assert.Equal(t, 1234, result.TotalReports)
+	require.NotNil(t, result.LastReportedAt)
+	assert.True(t, time.Date(2024, 3, 4, 5, 6, 7, 0, time.UTC).Equal(*result.LastReportedAt))
+
+	limited = true
+	_, err = client.Check(context.Background(), "203.0.113.1", 90)
+	assert.ErrorContains(t, err, "429")
+	_, ok := client.CheckCooldown()
+	assert.True(t, ok)
+	_, ok = client.Cooldown()
+	assert.False(t, ok, "the reporting is limited separately")
+}
+
+func TestClient_Blacklist(t *testing.T) {
+	limited := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if limited {
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		assert.Equal(t, "/blacklist", r.URL.Path)
+		assert.Equal(t, "application/json", r.Header.Get("Accept"))
+		assert.Equal(t, "90", r.URL.Query().Get("confidenceMinimum"))
+		assert.Equal(t, "2", r.URL.Query().Get("limit"))
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"meta": {"generatedAt": "2024-03-04T05:06:07+00:00"}, "data": [` +
+			`{"ipAddress": "203.0.113.1", "abuseConfidenceScore": 100, "lastReportedAt": "2024-03-04T05:00:00+00:00"},` +
+			`{"ipAddress": "203.0.113.2", "abuseConfidenceScore": 95, "lastReportedAt": "2024-03-04T04:00:00+00:00"}]}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "test_key", time.Second)
+
+	ips, err := client.Blacklist(context.Background(), 90, 2)
+	require.NoError(t, err)
+	require.Len(t, ips, 2)
+	assert.Equal(t, "203.0.113.1", ips[0].IpAddress)
+	assert.Equal(t, 100, ips[0].AbuseConfidenceScore)
+	assert.Equal(t, 95, ips[1].AbuseConfidenceScore)
+
+	limited = true
+	_, err = client.Blacklist(context.Background(), 90, 2)
+	assert.ErrorContains(t, err, "429")
+	until, ok := client.BlacklistCooldown()
+	assert.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), until, time.Minute)
+	_, ok = client.CheckCooldown()
+	assert.False(t, ok, "the checking is limited separately")
+}
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/go-resty/resty/v2"
@@ -95,9 +96,21 @@ func (r *ipapiComQuerierResponse) Info() *Info {
 	}
 
 	return &Info{
-		Ip:        net.ParseIP(r.Query),
-		Location:  strings.Join(location, ", "),
-		Latitude:  r.Lat,
-		Longitude: r.Lon,
+		Ip:          net.ParseIP(r.Query),
+		Location:    strings.Join(location, ", "),
+		Latitude:    r.Lat,
+		Longitude:   r.Lon,
+		CountryCode: r.CountryCode,
+		Asn:         parseAsn(r.As),
 	}
 }
+
+// parseAsn parses the number of an AS like "AS3215 Orange S.A.", it returns 0 if it's invalid.
+func parseAsn(as string) int {
+	number, _, _ := strings.Cut(strings.TrimPrefix(as, "AS"), " ")
+	ret, err := strconv.Atoi(number)
+	if err != nil || ret < 0 {
+		return 0
+	}
+	return ret
+}
@@ -56,10 +56,19 @@ func TestIpapiComQuerier_Query(t *testing.T) {
 		info, err := querier.Query(context.Background(), "2.3.4.5")
 		require.NoError(t, err)
 		require.Equal(t, &Info{
-			Ip:        net.ParseIP("2.3.4.5"),
-			Location:  "France, Auvergne-Rhone-Alpes, Clermont-Ferrand",
-			Latitude:  45.7838,
-			Longitude: 3.0966,
+			Ip:          net.ParseIP("2.3.4.5"),
+			Location:    "France, Auvergne-Rhone-Alpes, Clermont-Ferrand",
+			Latitude:    45.7838,
+			Longitude:   3.0966,
+			CountryCode: "FR",
+			Asn:         3215,
 		}, info)
 	})
 }
+
+func Test_parseAsn(t *testing.T) {
+	require.Equal(t, 3215, parseAsn("AS3215 Orange S.A."))
+	require.Equal(t, 13335, parseAsn("AS13335"))
+	require.Equal(t, 0, parseAsn(""))
+	require.Equal(t, 0, parseAsn("Orange"))
+}
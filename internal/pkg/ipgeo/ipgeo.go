@@ -9,10 +9,12 @@ import (
 )
 
 type Info struct {
-	Ip        net.IP
-	Location  string
-	Latitude  float64
-	Longitude float64
+	Ip          net.IP
+	Location    string
+	Latitude    float64
+	Longitude   float64
+	CountryCode string // ISO 3166-1 alpha-2, empty if unknown
+	Asn         int    // the autonomous system number, 0 if unknown
 }
 
 type Querier interface {
@@ -6,18 +6,28 @@ package logs
 import (
 	"context"
 	"fmt"
+	"io"
+	"os"
+	"sync"
 	"sync/atomic"
+	"time"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
 )
 
 type Logger = *zap.SugaredLogger
 
-var def atomic.Pointer[zap.SugaredLogger]
+var (
+	def atomic.Pointer[zap.SugaredLogger]
+	// level is shared by all the loggers, including the ones derived from the default one before it changes
+	level = zap.NewAtomicLevelAt(zapcore.DebugLevel)
+)
 
 func init() {
 	config := zap.NewDevelopmentConfig()
+	config.Level = level
 	logger, err := config.Build(zap.AddStacktrace(zapcore.DPanicLevel))
 	if err != nil {
 		panic(fmt.Errorf("init logger: %w", err))
@@ -29,20 +39,147 @@ func Default() Logger {
 	return def.Load()
 }
 
-func SetLevel(level string) Logger {
-	l, err := zapcore.ParseLevel(level)
+// SetLevel sets the level of all the loggers, it can be called again to change the level.
+func SetLevel(name string) Logger {
+	l, err := zapcore.ParseLevel(name)
 	logger := def.Load()
 	if err != nil {
-		logger.Errorf("ignore invalid log level %s: %v", level, err)
+		logger.Errorf("ignore invalid log level %s: %v", name, err)
 		return logger
 	}
-	logger = logger.WithOptions(zap.IncreaseLevel(l))
-	def.Store(logger)
+	level.SetLevel(l)
 	return logger
 }
 
+// Options are the options to build the default logger by Setup.
+type Options struct {
+	Level string
+	// Encoding is "console" or "json"
+	Encoding string
+	// Outputs are "stderr", "stdout" or the paths of files
+	Outputs  []string
+	Rotation Rotation
+	Sampling Sampling
+}
+
+// Rotation is how to rotate the files of the outputs, it doesn't rotate if MaxSize is 0.
+type Rotation struct {
+	// MaxSize is the maximum size in megabytes of a file before it gets rotated
+	MaxSize int
+	// MaxBackups is the maximum number of the rotated files to retain, 0 means retaining all
+	MaxBackups int
+	// MaxAge is the maximum number of days to retain the rotated files, 0 means retaining all
+	MaxAge   int
+	Compress bool
+}
+
+// Sampling is to drop the repeated entries, see zapcore.NewSamplerWithOptions.
+type Sampling struct {
+	Enabled bool
+	// Initial is the number of the entries with the same level and message to log every second
+	Initial int
+	// Thereafter is to log every Thereafter-th entry after Initial in the same second
+	Thereafter int
+}
+
+var (
+	// closers are the files opened by the last Setup
+	closers   []io.Closer
+	closersMu sync.Mutex
+)
+
+// Setup replaces the default logger with the one built by the options, and returns it.
+// The loggers derived from the default one before calling it still write to the previous outputs,
+// so it should be called before storing the logger to contexts by With.
+func Setup(opts Options) (Logger, error) {
+	l, err := zapcore.ParseLevel(opts.Level)
+	if err != nil {
+		return nil, fmt.Errorf("parse level %q: %w", opts.Level, err)
+	}
+
+	var encoder zapcore.Encoder
+	switch opts.Encoding {
+	case "", "console":
+		encoder = zapcore.NewConsoleEncoder(zap.NewDevelopmentEncoderConfig())
+	case "json":
+		config := zap.NewProductionEncoderConfig()
+		config.TimeKey = "time"
+		config.EncodeTime = zapcore.ISO8601TimeEncoder
+		encoder = zapcore.NewJSONEncoder(config)
+	default:
+		return nil, fmt.Errorf("unknown encoding %q", opts.Encoding)
+	}
+
+	outputs := opts.Outputs
+	if len(outputs) == 0 {
+		outputs = []string{"stderr"}
+	}
+	var (
+		syncers []zapcore.WriteSyncer
+		files   []io.Closer
+	)
+	for _, v := range outputs {
+		switch v {
+		case "stderr":
+			syncers = append(syncers, zapcore.Lock(os.Stderr))
+		case "stdout":
+			syncers = append(syncers, zapcore.Lock(os.Stdout))
+		default:
+			var w io.WriteCloser
+			if opts.Rotation.MaxSize > 0 {
+				w = &lumberjack.Logger{
+					Filename:   v,
+					MaxSize:    opts.Rotation.MaxSize,
+					MaxBackups: opts.Rotation.MaxBackups,
+					MaxAge:     opts.Rotation.MaxAge,
+					Compress:   opts.Rotation.Compress,
+				}
+			} else {
+				f, err := os.OpenFile(v, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+				if err != nil {
+					closeAll(files)
+					return nil, fmt.Errorf("open output: %w", err)
+				}
+				w = f
+			}
+			syncers = append(syncers, zapcore.AddSync(w))
+			files = append(files, w)
+		}
+	}
+
+	level.SetLevel(l)
+	core := zapcore.NewCore(encoder, zapcore.NewMultiWriteSyncer(syncers...), level)
+	if opts.Sampling.Enabled {
+		core = zapcore.NewSamplerWithOptions(core, time.Second, opts.Sampling.Initial, opts.Sampling.Thereafter)
+	}
+	logger := zap.New(core,
+		zap.AddCaller(),
+		zap.AddStacktrace(zapcore.DPanicLevel),
+		zap.ErrorOutput(zapcore.Lock(os.Stderr)),
+	).Sugar()
+
+	previous := def.Swap(logger)
+	_ = previous.Sync()
+	closersMu.Lock()
+	closeAll(closers)
+	closers = files
+	closersMu.Unlock()
+
+	return logger, nil
+}
+
+func closeAll(closers []io.Closer) {
+	for _, c := range closers {
+		_ = c.Close()
+	}
+}
+
 func Close() {
 	_ = def.Load().Sync()
+	closersMu.Lock()
+	closeAll(closers)
+	closers = nil
+	closersMu.Unlock()
 }
 
 type loggerInContext struct{}
@@ -0,0 +1,80 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package logs
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetup(t *testing.T) {
+	defer func() {
+		_, err := Setup(Options{Level: "debug"})
+		require.NoError(t, err)
+	}()
+
+	t.Run("json", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "funeypot.log")
+		logger, err := Setup(Options{
+			Level:    "info",
+			Encoding: "json",
+			Outputs:  []string{file},
+			Rotation: Rotation{MaxSize: 1, MaxBackups: 1},
+		})
+		require.NoError(t, err)
+		assert.Same(t, logger, Default())
+
+		ctx := With(context.Background(), logger.With("ip", "127.0.0.1"))
+		From(ctx).Debugf("ignored")
+		From(ctx).Infof("hello %s", "world")
+		Close()
+
+		content, err := os.ReadFile(file)
+		require.NoError(t, err)
+		lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+		require.Len(t, lines, 1)
+		entry := map[string]any{}
+		require.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+		assert.Equal(t, "info", entry["level"])
+		assert.Equal(t, "hello world", entry["msg"])
+		assert.Equal(t, "127.0.0.1", entry["ip"])
+		assert.Contains(t, entry, "time")
+	})
+
+	t.Run("sampling", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "funeypot.log")
+		logger, err := Setup(Options{
+			Level:    "info",
+			Outputs:  []string{file},
+			Sampling: Sampling{Enabled: true, Initial: 2, Thereafter: 5},
+		})
+		require.NoError(t, err)
+
+		for i := 0; i < 10; i++ {
+			logger.Info("repeated")
+		}
+		Close()
+
+		content, err := os.ReadFile(file)
+		require.NoError(t, err)
+		// the first 2 ones, then the 7th one
+		assert.Equal(t, 3, strings.Count(string(content), "repeated"))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := Setup(Options{Level: "test"})
+		assert.Error(t, err)
+		_, err = Setup(Options{Level: "info", Encoding: "text"})
+		assert.Error(t, err)
+		_, err = Setup(Options{Level: "info", Outputs: []string{filepath.Join(t.TempDir(), "missing", "funeypot.log")}})
+		assert.Error(t, err)
+	})
+}
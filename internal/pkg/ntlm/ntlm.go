@@ -0,0 +1,275 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+// Package ntlm implements the messages of the NTLM authentication protocol needed to capture client identities.
+// See https://learn.microsoft.com/en-us/openspecs/windows_protocols/ms-nlmp/
+package ntlm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+	"unicode/utf16"
+)
+
+const (
+	MessageTypeNegotiate    = 1
+	MessageTypeChallenge    = 2
+	MessageTypeAuthenticate = 3
+)
+
+const (
+	FlagNegotiateUnicode         = 0x00000001
+	FlagNegotiateOem             = 0x00000002
+	FlagRequestTarget            = 0x00000004
+	FlagNegotiateSign            = 0x00000010
+	FlagNegotiateSeal            = 0x00000020
+	FlagNegotiateNtlm            = 0x00000200
+	FlagNegotiateAlwaysSign      = 0x00008000
+	FlagTargetTypeServer         = 0x00020000
+	FlagNegotiateExtendedSession = 0x00080000
+	FlagNegotiateTargetInfo      = 0x00800000
+	FlagNegotiateVersion         = 0x02000000
+	FlagNegotiate128             = 0x20000000
+	FlagNegotiateKeyExchange     = 0x40000000
+	FlagNegotiate56              = 0x80000000
+)
+
+const (
+	avIdEol             = 0
+	avIdNbComputerName  = 1
+	avIdNbDomainName    = 2
+	avIdDnsComputerName = 3
+	avIdDnsDomainName   = 4
+	avIdTimestamp       = 7
+)
+
+var (
+	signature = []byte("NTLMSSP\x00")
+
+	// version is the version of Windows 10 2004, with NTLMSSP_REVISION_W2K3.
+	version = []byte{10, 0, 0x61, 0x4a, 0, 0, 0, 0x0f}
+
+	ErrInvalidMessage = errors.New("invalid ntlm message")
+)
+
+// MessageType returns the type of the message, or an error if it is not a NTLM message.
+func MessageType(msg []byte) (uint32, error) {
+	if len(msg) < 12 || !bytes.Equal(msg[:8], signature) {
+		return 0, ErrInvalidMessage
+	}
+	return binary.LittleEndian.Uint32(msg[8:12]), nil
+}
+
+type Negotiate struct {
+	Flags uint32
+}
+
+func ParseNegotiate(msg []byte) (*Negotiate, error) {
+	typ, err := MessageType(msg)
+	if err != nil {
+		return nil, err
+	}
+	if typ != MessageTypeNegotiate || len(msg) < 16 {
+		return nil, fmt.Errorf("%w: unexpected negotiate message", ErrInvalidMessage)
+	}
+	return &Negotiate{
+		Flags: binary.LittleEndian.Uint32(msg[12:16]),
+	}, nil
+}
+
+func (n *Negotiate) Marshal() []byte {
+	buf := &bytes.Buffer{}
+	buf.Write(signature)
+	_ = binary.Write(buf, binary.LittleEndian, uint32(MessageTypeNegotiate))
+	_ = binary.Write(buf, binary.LittleEndian, n.Flags)
+	buf.Write(make([]byte, 16)) // empty domain and workstation fields
+	return buf.Bytes()
+}
+
+// Challenge is the message sent by a server to pretend to be a standalone Windows machine.
+type Challenge struct {
+	Flags           uint32
+	ServerChallenge [8]byte
+	ComputerName    string
+	Timestamp       time.Time
+}
+
+func (c *Challenge) Marshal() []byte {
+	targetName := encodeUtf16(c.ComputerName)
+
+	info := &bytes.Buffer{}
+	writeAvPair(info, avIdNbDomainName, targetName)
+	writeAvPair(info, avIdNbComputerName, targetName)
+	writeAvPair(info, avIdDnsDomainName, targetName)
+	writeAvPair(info, avIdDnsComputerName, targetName)
+	timestamp := make([]byte, 8)
+	binary.LittleEndian.PutUint64(timestamp, fileTime(c.Timestamp))
+	writeAvPair(info, avIdTimestamp, timestamp)
+	writeAvPair(info, avIdEol, nil)
+
+	const headerSize = 56
+	buf := &bytes.Buffer{}
+	buf.Write(signature)
+	_ = binary.Write(buf, binary.LittleEndian, uint32(MessageTypeChallenge))
+	writeFields(buf, len(targetName), headerSize)
+	_ = binary.Write(buf, binary.LittleEndian, c.Flags)
+	buf.Write(c.ServerChallenge[:])
+	buf.Write(make([]byte, 8)) // reserved
+	writeFields(buf, info.Len(), headerSize+len(targetName))
+	buf.Write(version)
+	buf.Write(targetName)
+	buf.Write(info.Bytes())
+	return buf.Bytes()
+}
+
+func ParseChallenge(msg []byte) (*Challenge, error) {
+	typ, err := MessageType(msg)
+	if err != nil {
+		return nil, err
+	}
+	if typ != MessageTypeChallenge || len(msg) < 48 {
+		return nil, fmt.Errorf("%w: unexpected challenge message", ErrInvalidMessage)
+	}
+	ret := &Challenge{
+		Flags: binary.LittleEndian.Uint32(msg[20:24]),
+	}
+	copy(ret.ServerChallenge[:], msg[24:32])
+	targetName, err := readFields(msg, 12)
+	if err != nil {
+		return nil, err
+	}
+	ret.ComputerName = decodeString(targetName, ret.Flags)
+	return ret, nil
+}
+
+// Authenticate is the message sent by a client with its identity and the response to the challenge.
+type Authenticate struct {
+	Flags       uint32
+	Domain      string
+	User        string
+	Workstation string
+	LmResponse  []byte
+	NtResponse  []byte
+}
+
+func ParseAuthenticate(msg []byte) (*Authenticate, error) {
+	typ, err := MessageType(msg)
+	if err != nil {
+		return nil, err
+	}
+	if typ != MessageTypeAuthenticate || len(msg) < 64 {
+		return nil, fmt.Errorf("%w: unexpected authenticate message", ErrInvalidMessage)
+	}
+
+	ret := &Authenticate{
+		Flags: binary.LittleEndian.Uint32(msg[60:64]),
+	}
+	fields := make([][]byte, 5)
+	for i, offset := range []int{12, 20, 28, 36, 44} {
+		fields[i], err = readFields(msg, offset)
+		if err != nil {
+			return nil, err
+		}
+	}
+	ret.LmResponse = fields[0]
+	ret.NtResponse = fields[1]
+	ret.Domain = decodeString(fields[2], ret.Flags)
+	ret.User = decodeString(fields[3], ret.Flags)
+	ret.Workstation = decodeString(fields[4], ret.Flags)
+	return ret, nil
+}
+
+func (a *Authenticate) Marshal() []byte {
+	const headerSize = 72
+	payloads := [][]byte{
+		a.LmResponse,
+		a.NtResponse,
+		encodeString(a.Domain, a.Flags),
+		encodeString(a.User, a.Flags),
+		encodeString(a.Workstation, a.Flags),
+		nil, // encrypted random session key
+	}
+
+	buf := &bytes.Buffer{}
+	buf.Write(signature)
+	_ = binary.Write(buf, binary.LittleEndian, uint32(MessageTypeAuthenticate))
+	offset := headerSize
+	for _, p := range payloads {
+		writeFields(buf, len(p), offset)
+		offset += len(p)
+	}
+	_ = binary.Write(buf, binary.LittleEndian, a.Flags)
+	buf.Write(version)
+	for _, p := range payloads {
+		buf.Write(p)
+	}
+	return buf.Bytes()
+}
+
+// Identity returns the user in the form of "DOMAIN\user", or just the user if the domain is empty.
+func (a *Authenticate) Identity() string {
+	if a.Domain == "" {
+		return a.User
+	}
+	return a.Domain + `\` + a.User
+}
+
+func writeFields(buf *bytes.Buffer, length, offset int) {
+	_ = binary.Write(buf, binary.LittleEndian, uint16(length))
+	_ = binary.Write(buf, binary.LittleEndian, uint16(length))
+	_ = binary.Write(buf, binary.LittleEndian, uint32(offset))
+}
+
+func readFields(msg []byte, at int) ([]byte, error) {
+	length := int(binary.LittleEndian.Uint16(msg[at : at+2]))
+	offset := int(binary.LittleEndian.Uint32(msg[at+4 : at+8]))
+	if length == 0 {
+		return nil, nil
+	}
+	if offset < 0 || offset+length > len(msg) {
+		return nil, fmt.Errorf("%w: field out of range", ErrInvalidMessage)
+	}
+	return msg[offset : offset+length], nil
+}
+
+func writeAvPair(buf *bytes.Buffer, id uint16, value []byte) {
+	_ = binary.Write(buf, binary.LittleEndian, id)
+	_ = binary.Write(buf, binary.LittleEndian, uint16(len(value)))
+	buf.Write(value)
+}
+
+func decodeString(b []byte, flags uint32) string {
+	if flags&FlagNegotiateUnicode == 0 {
+		return string(b)
+	}
+	u := make([]uint16, len(b)/2)
+	for i := range u {
+		u[i] = binary.LittleEndian.Uint16(b[i*2:])
+	}
+	return string(utf16.Decode(u))
+}
+
+func encodeString(s string, flags uint32) []byte {
+	if flags&FlagNegotiateUnicode == 0 {
+		return []byte(s)
+	}
+	return encodeUtf16(s)
+}
+
+func encodeUtf16(s string) []byte {
+	u := utf16.Encode([]rune(s))
+	b := make([]byte, len(u)*2)
+	for i, v := range u {
+		binary.LittleEndian.PutUint16(b[i*2:], v)
+	}
+	return b
+}
+
+// fileTime converts the time to the number of 100-nanosecond intervals since January 1, 1601 (UTC).
+func fileTime(t time.Time) uint64 {
+	const epochDiff = 116444736000000000
+	return uint64(t.UnixNano()/100) + epochDiff
+}
@@ -0,0 +1,74 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package ntlm
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNegotiate(t *testing.T) {
+	msg := (&Negotiate{Flags: FlagNegotiateUnicode | FlagNegotiateNtlm}).Marshal()
+
+	typ, err := MessageType(msg)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(MessageTypeNegotiate), typ)
+
+	negotiate, err := ParseNegotiate(msg)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(FlagNegotiateUnicode|FlagNegotiateNtlm), negotiate.Flags)
+
+	_, err = ParseNegotiate([]byte("not a ntlm message"))
+	assert.ErrorIs(t, err, ErrInvalidMessage)
+}
+
+func TestChallenge(t *testing.T) {
+	challenge := &Challenge{
+		Flags:           FlagNegotiateUnicode | FlagNegotiateNtlm | FlagNegotiateTargetInfo,
+		ServerChallenge: [8]byte{1, 2, 3, 4, 5, 6, 7, 8},
+		ComputerName:    "WIN-TEST",
+		Timestamp:       time.Now(),
+	}
+
+	got, err := ParseChallenge(challenge.Marshal())
+	require.NoError(t, err)
+	assert.Equal(t, challenge.Flags, got.Flags)
+	assert.Equal(t, challenge.ServerChallenge, got.ServerChallenge)
+	assert.Equal(t, "WIN-TEST", got.ComputerName)
+}
+
+func TestParseAuthenticate(t *testing.T) {
+	t.Run("unicode", func(t *testing.T) {
+		authenticate := &Authenticate{
+			Flags:       FlagNegotiateUnicode | FlagNegotiateNtlm,
+			Domain:      "CORP",
+			User:        "administrator",
+			Workstation: "DESKTOP-1",
+			NtResponse:  []byte{1, 2, 3},
+		}
+		got, err := ParseAuthenticate(authenticate.Marshal())
+		require.NoError(t, err)
+		assert.Equal(t, authenticate, got)
+		assert.Equal(t, `CORP\administrator`, got.Identity())
+	})
+
+	t.Run("oem", func(t *testing.T) {
+		authenticate := &Authenticate{
+			Flags: FlagNegotiateOem,
+			User:  "guest",
+		}
+		got, err := ParseAuthenticate(authenticate.Marshal())
+		require.NoError(t, err)
+		assert.Equal(t, "guest", got.Identity())
+	})
+
+	t.Run("out of range", func(t *testing.T) {
+		msg := (&Authenticate{Flags: FlagNegotiateUnicode, User: "user"}).Marshal()
+		_, err := ParseAuthenticate(msg[:len(msg)-2])
+		assert.ErrorIs(t, err, ErrInvalidMessage)
+	})
+}
@@ -0,0 +1,89 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package rdp
+
+import (
+	"encoding/asn1"
+	"fmt"
+	"io"
+)
+
+// StatusLogonFailure is STATUS_LOGON_FAILURE as a signed NTSTATUS.
+const StatusLogonFailure = -1073741715 // 0xC000006D
+
+const maxTsRequestSize = 64 * 1024
+
+// TsRequest is the message of CredSSP.
+// See https://learn.microsoft.com/en-us/openspecs/windows_protocols/ms-cssp/6aac4dea-08ef-47a6-8747-22ea7f6d8685
+type TsRequest struct {
+	Version     int         `asn1:"explicit,tag:0"`
+	NegoTokens  []NegoToken `asn1:"optional,explicit,tag:1"`
+	AuthInfo    []byte      `asn1:"optional,explicit,tag:2"`
+	PubKeyAuth  []byte      `asn1:"optional,explicit,tag:3"`
+	ErrorCode   int         `asn1:"optional,explicit,tag:4"`
+	ClientNonce []byte      `asn1:"optional,explicit,tag:5"`
+}
+
+type NegoToken struct {
+	Token []byte `asn1:"explicit,tag:0"`
+}
+
+// Token returns the first nego token, or nil if there is none.
+func (r *TsRequest) Token() []byte {
+	if len(r.NegoTokens) == 0 {
+		return nil
+	}
+	return r.NegoTokens[0].Token
+}
+
+// ReadTsRequest reads a DER encoded TsRequest from the stream.
+func ReadTsRequest(r io.Reader) (*TsRequest, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	if header[0] != 0x30 {
+		return nil, fmt.Errorf("%w: unexpected tag 0x%02x", ErrInvalidPacket, header[0])
+	}
+
+	length := int(header[1])
+	if length&0x80 != 0 {
+		n := length & 0x7f
+		if n == 0 || n > 3 {
+			return nil, fmt.Errorf("%w: unsupported length of length %d", ErrInvalidPacket, n)
+		}
+		lengthBytes := make([]byte, n)
+		if _, err := io.ReadFull(r, lengthBytes); err != nil {
+			return nil, err
+		}
+		header = append(header, lengthBytes...)
+		length = 0
+		for _, b := range lengthBytes {
+			length = length<<8 | int(b)
+		}
+	}
+	if length > maxTsRequestSize {
+		return nil, fmt.Errorf("%w: too large ts request %d", ErrInvalidPacket, length)
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+
+	ret := &TsRequest{}
+	if _, err := asn1.Unmarshal(append(header, body...), ret); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPacket, err)
+	}
+	return ret, nil
+}
+
+func WriteTsRequest(w io.Writer, req *TsRequest) error {
+	data, err := asn1.Marshal(*req)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
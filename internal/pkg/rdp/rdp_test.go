@@ -0,0 +1,98 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package rdp
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseConnectionRequest(t *testing.T) {
+	t.Run("mstsc", func(t *testing.T) {
+		// TPKT + X.224 CR with "Cookie: mstshash=administrator" and a negotiation request for SSL|HYBRID|HYBRID_EX
+		packet, err := hex.DecodeString("030000332ee00000000000" +
+			hex.EncodeToString([]byte("Cookie: mstshash=administrator\r\n")) +
+			"010008000b000000")
+		require.NoError(t, err)
+
+		payload, err := ReadTpkt(bytes.NewReader(packet))
+		require.NoError(t, err)
+
+		req, err := ParseConnectionRequest(payload)
+		require.NoError(t, err)
+		assert.Equal(t, &ConnectionRequest{
+			Cookie:             "administrator",
+			Negotiation:        true,
+			RequestedProtocols: ProtocolSsl | ProtocolHybrid | ProtocolHybridEx,
+		}, req)
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		want := &ConnectionRequest{
+			Cookie:             "user",
+			Negotiation:        true,
+			RequestedProtocols: ProtocolHybrid,
+		}
+		got, err := ParseConnectionRequest(want.Marshal())
+		require.NoError(t, err)
+		assert.Equal(t, want, got)
+	})
+
+	t.Run("no negotiation", func(t *testing.T) {
+		got, err := ParseConnectionRequest((&ConnectionRequest{}).Marshal())
+		require.NoError(t, err)
+		assert.Equal(t, &ConnectionRequest{}, got)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := ParseConnectionRequest([]byte{6, 0xd0, 0, 0, 0, 0, 0})
+		assert.ErrorIs(t, err, ErrInvalidPacket)
+		_, err = ParseConnectionRequest([]byte{1, 0xe0})
+		assert.ErrorIs(t, err, ErrInvalidPacket)
+	})
+}
+
+func TestConnectionConfirm(t *testing.T) {
+	for _, want := range []*ConnectionConfirm{
+		{SelectedProtocol: ProtocolHybrid},
+		{Failure: FailureSslRequired},
+	} {
+		buf := &bytes.Buffer{}
+		require.NoError(t, WriteTpkt(buf, want.Marshal()))
+		payload, err := ReadTpkt(buf)
+		require.NoError(t, err)
+		got, err := ParseConnectionConfirm(payload)
+		require.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+}
+
+func TestTsRequest(t *testing.T) {
+	want := &TsRequest{
+		Version: 6,
+		NegoTokens: []NegoToken{
+			{Token: bytes.Repeat([]byte{1}, 300)},
+		},
+	}
+	buf := &bytes.Buffer{}
+	require.NoError(t, WriteTsRequest(buf, want))
+
+	got, err := ReadTsRequest(buf)
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+	assert.Len(t, got.Token(), 300)
+
+	_, err = ReadTsRequest(bytes.NewReader([]byte{0x31, 0}))
+	assert.ErrorIs(t, err, ErrInvalidPacket)
+}
+
+func TestProtocolNames(t *testing.T) {
+	assert.Equal(t, "rdp", ProtocolNames(ProtocolRdp))
+	assert.Equal(t, "ssl|hybrid", ProtocolNames(ProtocolSsl|ProtocolHybrid))
+	assert.Equal(t, "hybrid_ex|0x100", ProtocolNames(ProtocolHybridEx|0x100))
+}
@@ -0,0 +1,196 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+// Package rdp implements the parts of the Remote Desktop Protocol which happen before the real authentication,
+// the X.224 connection sequence and CredSSP.
+// See https://learn.microsoft.com/en-us/openspecs/windows_protocols/ms-rdpbcgr/
+package rdp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	ProtocolRdp      uint32 = 0x00000000
+	ProtocolSsl      uint32 = 0x00000001
+	ProtocolHybrid   uint32 = 0x00000002
+	ProtocolRdstls   uint32 = 0x00000004
+	ProtocolHybridEx uint32 = 0x00000008
+	ProtocolRdsaad   uint32 = 0x00000010
+)
+
+const (
+	FailureSslRequired    uint32 = 0x00000001
+	FailureHybridRequired uint32 = 0x00000005
+)
+
+const (
+	tpktVersion = 3
+
+	x224ConnectionRequest = 0xe0
+	x224ConnectionConfirm = 0xd0
+
+	negotiationRequest  = 0x01
+	negotiationResponse = 0x02
+	negotiationFailure  = 0x03
+
+	cookiePrefix = "Cookie: mstshash="
+)
+
+var ErrInvalidPacket = errors.New("invalid rdp packet")
+
+// ReadTpkt reads a TPKT packet and returns its payload.
+func ReadTpkt(r io.Reader) ([]byte, error) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	if header[0] != tpktVersion {
+		return nil, fmt.Errorf("%w: unexpected tpkt version %d", ErrInvalidPacket, header[0])
+	}
+	length := int(binary.BigEndian.Uint16(header[2:4]))
+	if length < 4 {
+		return nil, fmt.Errorf("%w: invalid tpkt length %d", ErrInvalidPacket, length)
+	}
+	payload := make([]byte, length-4)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+func WriteTpkt(w io.Writer, payload []byte) error {
+	header := []byte{tpktVersion, 0, 0, 0}
+	binary.BigEndian.PutUint16(header[2:4], uint16(len(payload)+4))
+	_, err := w.Write(append(header, payload...))
+	return err
+}
+
+// ConnectionRequest is the X.224 Connection Request PDU sent by a client.
+type ConnectionRequest struct {
+	// Cookie is the value of "mstshash", which is usually the user name of the client.
+	Cookie string
+	// Negotiation reports whether the RDP Negotiation Request is present.
+	Negotiation        bool
+	RequestedProtocols uint32
+}
+
+func ParseConnectionRequest(tpdu []byte) (*ConnectionRequest, error) {
+	// length indicator, code, dst-ref(2), src-ref(2), class option
+	if len(tpdu) < 7 || int(tpdu[0]) != len(tpdu)-1 {
+		return nil, fmt.Errorf("%w: invalid x224 length", ErrInvalidPacket)
+	}
+	if tpdu[1]&0xf0 != x224ConnectionRequest {
+		return nil, fmt.Errorf("%w: unexpected x224 code 0x%02x", ErrInvalidPacket, tpdu[1])
+	}
+
+	ret := &ConnectionRequest{}
+	data := tpdu[7:]
+	if bytes.HasPrefix(data, []byte(cookiePrefix)) {
+		end := bytes.Index(data, []byte("\r\n"))
+		if end < 0 {
+			return nil, fmt.Errorf("%w: unterminated cookie", ErrInvalidPacket)
+		}
+		ret.Cookie = string(data[len(cookiePrefix):end])
+		data = data[end+2:]
+	} else if end := bytes.Index(data, []byte("\r\n")); end >= 0 && bytes.HasPrefix(data, []byte("Cookie: ")) {
+		// routing token
+		data = data[end+2:]
+	}
+
+	if len(data) >= 8 && data[0] == negotiationRequest {
+		ret.Negotiation = true
+		ret.RequestedProtocols = binary.LittleEndian.Uint32(data[4:8])
+	}
+	return ret, nil
+}
+
+func (r *ConnectionRequest) Marshal() []byte {
+	buf := &bytes.Buffer{}
+	buf.Write([]byte{0, x224ConnectionRequest, 0, 0, 0, 0, 0})
+	if r.Cookie != "" {
+		buf.WriteString(cookiePrefix + r.Cookie + "\r\n")
+	}
+	if r.Negotiation {
+		buf.Write([]byte{negotiationRequest, 0, 8, 0})
+		_ = binary.Write(buf, binary.LittleEndian, r.RequestedProtocols)
+	}
+	ret := buf.Bytes()
+	ret[0] = byte(len(ret) - 1)
+	return ret
+}
+
+// ConnectionConfirm is the X.224 Connection Confirm PDU sent by a server.
+type ConnectionConfirm struct {
+	// Failure is the failure code of the negotiation, zero if the negotiation succeeded.
+	Failure          uint32
+	SelectedProtocol uint32
+}
+
+func (c *ConnectionConfirm) Marshal() []byte {
+	buf := &bytes.Buffer{}
+	buf.Write([]byte{0, x224ConnectionConfirm, 0, 0, 0x12, 0x34, 0})
+	if c.Failure != 0 {
+		buf.Write([]byte{negotiationFailure, 0, 8, 0})
+		_ = binary.Write(buf, binary.LittleEndian, c.Failure)
+	} else {
+		// EXTENDED_CLIENT_DATA_SUPPORTED | DYNVC_GFX_PROTOCOL_SUPPORTED | RESTRICTED_ADMIN_MODE_SUPPORTED
+		buf.Write([]byte{negotiationResponse, 0x0b, 8, 0})
+		_ = binary.Write(buf, binary.LittleEndian, c.SelectedProtocol)
+	}
+	ret := buf.Bytes()
+	ret[0] = byte(len(ret) - 1)
+	return ret
+}
+
+func ParseConnectionConfirm(tpdu []byte) (*ConnectionConfirm, error) {
+	if len(tpdu) < 7 || int(tpdu[0]) != len(tpdu)-1 {
+		return nil, fmt.Errorf("%w: invalid x224 length", ErrInvalidPacket)
+	}
+	if tpdu[1]&0xf0 != x224ConnectionConfirm {
+		return nil, fmt.Errorf("%w: unexpected x224 code 0x%02x", ErrInvalidPacket, tpdu[1])
+	}
+	ret := &ConnectionConfirm{}
+	data := tpdu[7:]
+	if len(data) >= 8 {
+		switch data[0] {
+		case negotiationResponse:
+			ret.SelectedProtocol = binary.LittleEndian.Uint32(data[4:8])
+		case negotiationFailure:
+			ret.Failure = binary.LittleEndian.Uint32(data[4:8])
+		}
+	}
+	return ret, nil
+}
+
+// ProtocolNames returns the readable names of the protocols, like "ssl|hybrid".
+func ProtocolNames(protocols uint32) string {
+	if protocols == ProtocolRdp {
+		return "rdp"
+	}
+	var names []string
+	for _, v := range []struct {
+		flag uint32
+		name string
+	}{
+		{ProtocolSsl, "ssl"},
+		{ProtocolHybrid, "hybrid"},
+		{ProtocolRdstls, "rdstls"},
+		{ProtocolHybridEx, "hybrid_ex"},
+		{ProtocolRdsaad, "rdsaad"},
+	} {
+		if protocols&v.flag != 0 {
+			names = append(names, v.name)
+			protocols &^= v.flag
+		}
+	}
+	if protocols != 0 {
+		names = append(names, fmt.Sprintf("0x%x", protocols))
+	}
+	return strings.Join(names, "|")
+}
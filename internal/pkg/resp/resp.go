@@ -0,0 +1,166 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+// Package resp implements the subset of the Redis serialization protocol needed to emulate a Redis server.
+// See https://redis.io/docs/latest/develop/reference/protocol-spec/
+package resp
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	maxArgs        = 1024
+	maxBulkLength  = 512 * 1024
+	maxInlineSize  = 64 * 1024
+	maxCommandSize = 128 * 1024 // total length of the bulk strings of a command
+)
+
+var ErrProtocol = errors.New("protocol error")
+
+type Reader struct {
+	r *bufio.Reader
+}
+
+func NewReader(r io.Reader) *Reader {
+	return &Reader{
+		r: bufio.NewReader(r),
+	}
+}
+
+// ReadCommand reads a command sent by a client, either as an array of bulk strings or as an inline command.
+// It returns an empty slice for empty inline commands.
+func (r *Reader) ReadCommand() ([]string, error) {
+	b, err := r.r.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+	if b[0] != '*' {
+		return r.readInline()
+	}
+
+	line, err := r.readLine()
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n > maxArgs {
+		return nil, fmt.Errorf("%w: invalid multibulk length %q", ErrProtocol, line[1:])
+	}
+
+	args := make([]string, 0, max(n, 0))
+	size := 0
+	for i := 0; i < n; i++ {
+		arg, err := r.readBulk(maxCommandSize - size)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		size += len(arg)
+	}
+	return args, nil
+}
+
+func (r *Reader) readInline() ([]string, error) {
+	line, err := r.readLine()
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(line), nil
+}
+
+// readBulk reads a bulk string no longer than limit.
+func (r *Reader) readBulk(limit int) (string, error) {
+	line, err := r.readLine()
+	if err != nil {
+		return "", err
+	}
+	if len(line) == 0 || line[0] != '$' {
+		return "", fmt.Errorf("%w: expected '$', got %q", ErrProtocol, line)
+	}
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n < 0 || n > maxBulkLength {
+		return "", fmt.Errorf("%w: invalid bulk length %q", ErrProtocol, line[1:])
+	}
+	if n > limit {
+		return "", fmt.Errorf("%w: too big command", ErrProtocol)
+	}
+	// read it incrementally, the buffer grows with what is actually sent rather than with the length claimed
+	buf := &bytes.Buffer{}
+	if _, err := io.CopyN(buf, r.r, int64(n)+2); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return "", err
+	}
+	b := buf.Bytes()
+	if b[n] != '\r' || b[n+1] != '\n' {
+		return "", fmt.Errorf("%w: bulk string not terminated by CRLF", ErrProtocol)
+	}
+	return string(b[:n]), nil
+}
+
+func (r *Reader) readLine() (string, error) {
+	var line []byte
+	for {
+		chunk, isPrefix, err := r.r.ReadLine()
+		if err != nil {
+			return "", err
+		}
+		line = append(line, chunk...)
+		if len(line) > maxInlineSize {
+			return "", fmt.Errorf("%w: too big inline request", ErrProtocol)
+		}
+		if !isPrefix {
+			break
+		}
+	}
+	return string(line), nil
+}
+
+type Writer struct {
+	w *bufio.Writer
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{
+		w: bufio.NewWriter(w),
+	}
+}
+
+func (w *Writer) WriteSimple(s string) {
+	_, _ = w.w.WriteString("+" + s + "\r\n")
+}
+
+func (w *Writer) WriteError(s string) {
+	_, _ = w.w.WriteString("-" + s + "\r\n")
+}
+
+func (w *Writer) WriteInteger(n int64) {
+	_, _ = w.w.WriteString(":" + strconv.FormatInt(n, 10) + "\r\n")
+}
+
+func (w *Writer) WriteBulk(s string) {
+	_, _ = w.w.WriteString("$" + strconv.Itoa(len(s)) + "\r\n" + s + "\r\n")
+}
+
+func (w *Writer) WriteNull() {
+	_, _ = w.w.WriteString("$-1\r\n")
+}
+
+func (w *Writer) WriteArray(items []string) {
+	_, _ = w.w.WriteString("*" + strconv.Itoa(len(items)) + "\r\n")
+	for _, item := range items {
+		w.WriteBulk(item)
+	}
+}
+
+func (w *Writer) Flush() error {
+	return w.w.Flush()
+}
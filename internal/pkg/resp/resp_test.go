@@ -0,0 +1,104 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package resp
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReader_ReadCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []string
+		wantErr error
+	}{
+		{
+			name:  "multibulk",
+			input: "*3\r\n$6\r\nCONFIG\r\n$3\r\nSET\r\n$3\r\ndir\r\n",
+			want:  []string{"CONFIG", "SET", "dir"},
+		},
+		{
+			name:  "binary safe",
+			input: "*2\r\n$4\r\nECHO\r\n$5\r\na\r\nbc\r\n",
+			want:  []string{"ECHO", "a\r\nbc"},
+		},
+		{
+			name:  "inline",
+			input: "AUTH  password\r\n",
+			want:  []string{"AUTH", "password"},
+		},
+		{
+			name:  "empty inline",
+			input: "\r\n",
+			want:  []string{},
+		},
+		{
+			name:    "invalid multibulk length",
+			input:   "*x\r\n",
+			wantErr: ErrProtocol,
+		},
+		{
+			name:    "invalid bulk",
+			input:   "*1\r\n:1\r\n",
+			wantErr: ErrProtocol,
+		},
+		{
+			name:    "unterminated bulk",
+			input:   "*1\r\n$3\r\nabcde",
+			wantErr: ErrProtocol,
+		},
+		{
+			name:    "too big bulk",
+			input:   "*1\r\n$524289\r\n",
+			wantErr: ErrProtocol,
+		},
+		{
+			name:    "too big command",
+			input:   "*3\r\n$3\r\nSET\r\n$65536\r\n" + strings.Repeat("<", 65536) + "\r\n$65536\r\n" + strings.Repeat("<", 65536) + "\r\n",
+			wantErr: ErrProtocol,
+		},
+		{
+			name:    "short bulk",
+			input:   "*1\r\n$65536\r\nabc",
+			wantErr: io.ErrUnexpectedEOF,
+		},
+		{
+			name:    "eof",
+			input:   "",
+			wantErr: io.EOF,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewReader(strings.NewReader(tt.input)).ReadCommand()
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestWriter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w := NewWriter(buf)
+	w.WriteSimple("OK")
+	w.WriteError("ERR unknown command")
+	w.WriteInteger(42)
+	w.WriteBulk("value")
+	w.WriteNull()
+	w.WriteArray([]string{"a", "bc"})
+	require.NoError(t, w.Flush())
+
+	assert.Equal(t, "+OK\r\n-ERR unknown command\r\n:42\r\n$5\r\nvalue\r\n$-1\r\n*2\r\n$1\r\na\r\n$2\r\nbc\r\n", buf.String())
+}
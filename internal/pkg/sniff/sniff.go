@@ -0,0 +1,76 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+// Package sniff guesses the protocol of a connection by the first bytes sent by the client.
+package sniff
+
+import (
+	"bytes"
+	"strings"
+)
+
+type Protocol string
+
+const (
+	ProtocolNone    Protocol = "none" // nothing has been sent
+	ProtocolUnknown Protocol = "unknown"
+	ProtocolTls     Protocol = "tls"
+	ProtocolHttp    Protocol = "http"
+	ProtocolSsh     Protocol = "ssh"
+)
+
+var httpMethods = []string{
+	"GET", "HEAD", "POST", "PUT", "DELETE", "CONNECT", "OPTIONS", "TRACE", "PATCH",
+	"PRI", // the connection preface of HTTP/2
+}
+
+// Detect returns the protocol of the payload.
+func Detect(payload []byte) Protocol {
+	switch {
+	case len(payload) == 0:
+		return ProtocolNone
+	case isTlsClientHello(payload):
+		return ProtocolTls
+	case isHttp(payload):
+		return ProtocolHttp
+	case bytes.HasPrefix(payload, []byte("SSH-")):
+		return ProtocolSsh
+	}
+	return ProtocolUnknown
+}
+
+// isTlsClientHello reports whether the payload starts with a TLS handshake record containing a ClientHello.
+func isTlsClientHello(payload []byte) bool {
+	// content type, version(2), length(2), handshake type
+	return len(payload) >= 6 &&
+		payload[0] == 0x16 &&
+		payload[1] == 0x03 && payload[2] <= 0x04 &&
+		payload[5] == 0x01
+}
+
+func isHttp(payload []byte) bool {
+	method, _, ok := bytes.Cut(payload, []byte(" "))
+	if !ok {
+		return false
+	}
+	for _, v := range httpMethods {
+		if string(method) == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Printable returns the payload as a string, with the non-printable bytes replaced by '.'.
+func Printable(payload []byte) string {
+	b := &strings.Builder{}
+	b.Grow(len(payload))
+	for _, c := range payload {
+		if c >= 0x20 && c < 0x7f {
+			b.WriteByte(c)
+		} else {
+			b.WriteByte('.')
+		}
+	}
+	return b.String()
+}
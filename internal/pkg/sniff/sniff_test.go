@@ -0,0 +1,65 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package sniff
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload []byte
+		want    Protocol
+	}{
+		{
+			name:    "empty",
+			payload: nil,
+			want:    ProtocolNone,
+		},
+		{
+			name:    "tls client hello",
+			payload: []byte{0x16, 0x03, 0x01, 0x02, 0x00, 0x01, 0x00, 0x01, 0xfc, 0x03, 0x03},
+			want:    ProtocolTls,
+		},
+		{
+			name:    "tls server hello",
+			payload: []byte{0x16, 0x03, 0x03, 0x00, 0x5a, 0x02, 0x00, 0x00, 0x56},
+			want:    ProtocolUnknown,
+		},
+		{
+			name:    "http",
+			payload: []byte("GET / HTTP/1.1\r\nHost: example.com\r\n\r\n"),
+			want:    ProtocolHttp,
+		},
+		{
+			name:    "http2",
+			payload: []byte("PRI * HTTP/2.0\r\n\r\nSM\r\n\r\n"),
+			want:    ProtocolHttp,
+		},
+		{
+			name:    "ssh",
+			payload: []byte("SSH-2.0-Go\r\n"),
+			want:    ProtocolSsh,
+		},
+		{
+			name:    "unknown",
+			payload: []byte("GETS / HTTP/1.1\r\n"),
+			want:    ProtocolUnknown,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Detect(tt.payload))
+		})
+	}
+}
+
+func TestPrintable(t *testing.T) {
+	assert.Equal(t, "GET / HTTP/1.1..", Printable([]byte("GET / HTTP/1.1\r\n")))
+	assert.Equal(t, ".....", Printable([]byte{0x16, 0x03, 0x01, 0x00, 0xff}))
+	assert.Equal(t, "", Printable(nil))
+}
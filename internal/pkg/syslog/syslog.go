@@ -0,0 +1,154 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+// Package syslog sends messages of RFC 5424 to a collector, over UDP, TCP or TLS.
+// The standard log/syslog doesn't support structured data or TLS, and it's frozen.
+package syslog
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+type Facility int
+
+// see https://datatracker.ietf.org/doc/html/rfc5424#section-6.2.1
+var facilities = []string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+	"uucp", "cron", "authpriv", "ftp", "ntp", "audit", "alert", "clock",
+	"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+}
+
+// ParseFacility returns the facility of the name, like "local0".
+func ParseFacility(name string) (Facility, bool) {
+	for i, v := range facilities {
+		if v == name {
+			return Facility(i), true
+		}
+	}
+	return 0, false
+}
+
+func (f Facility) String() string {
+	if f < 0 || int(f) >= len(facilities) {
+		return fmt.Sprintf("Facility(%d)", int(f))
+	}
+	return facilities[f]
+}
+
+type Severity int
+
+const (
+	SeverityEmergency Severity = iota
+	SeverityAlert
+	SeverityCritical
+	SeverityError
+	SeverityWarning
+	SeverityNotice
+	SeverityInfo
+	SeverityDebug
+)
+
+type Message struct {
+	Facility       Facility
+	Severity       Severity
+	Timestamp      time.Time // zero means unknown
+	Hostname       string
+	AppName        string
+	ProcId         string
+	MsgId          string
+	StructuredData []Element
+	Msg            string
+}
+
+// Element is an element of the structured data, like [attempt@32473 ip="1.2.3.4"].
+type Element struct {
+	Id     string
+	Params []Param
+}
+
+type Param struct {
+	Name  string
+	Value string
+}
+
+// Format returns the message in the format of RFC 5424, without the framing of the transport.
+func (m *Message) Format() []byte {
+	b := &strings.Builder{}
+	_, _ = fmt.Fprintf(b, "<%d>1 ", int(m.Facility)*8+int(m.Severity))
+	if m.Timestamp.IsZero() {
+		b.WriteString("-")
+	} else {
+		// at most 6 digits of the fraction are allowed
+		b.WriteString(m.Timestamp.Format("2006-01-02T15:04:05.000000Z07:00"))
+	}
+	for _, v := range []struct {
+		value string
+		max   int
+	}{
+		{m.Hostname, 255},
+		{m.AppName, 48},
+		{m.ProcId, 128},
+		{m.MsgId, 32},
+	} {
+		b.WriteString(" ")
+		b.WriteString(headerField(v.value, v.max))
+	}
+
+	b.WriteString(" ")
+	if len(m.StructuredData) == 0 {
+		b.WriteString("-")
+	}
+	for _, e := range m.StructuredData {
+		b.WriteString("[")
+		b.WriteString(sdName(e.Id))
+		for _, p := range e.Params {
+			b.WriteString(" ")
+			b.WriteString(sdName(p.Name))
+			b.WriteString(`="`)
+			b.WriteString(sdValueReplacer.Replace(p.Value))
+			b.WriteString(`"`)
+		}
+		b.WriteString("]")
+	}
+
+	if m.Msg != "" {
+		b.WriteString(" ")
+		b.WriteString(m.Msg)
+	}
+	return []byte(b.String())
+}
+
+var sdValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+// headerField returns the printable ASCII characters of the value, or "-" if it's empty.
+func headerField(value string, max int) string {
+	ret := strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return -1
+		}
+		return r
+	}, value)
+	if len(ret) > max {
+		ret = ret[:max]
+	}
+	if ret == "" {
+		return "-"
+	}
+	return ret
+}
+
+// sdName returns the characters of the name allowed by SD-NAME.
+func sdName(name string) string {
+	ret := strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 || r == '=' || r == ']' || r == '"' {
+			return -1
+		}
+		return r
+	}, name)
+	if len(ret) > 32 {
+		ret = ret[:32]
+	}
+	return ret
+}
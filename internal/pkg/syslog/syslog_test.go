@@ -0,0 +1,165 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package syslog
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessage_Format(t *testing.T) {
+	tests := []struct {
+		name    string
+		message *Message
+		want    string
+	}{
+		{
+			name:    "empty",
+			message: &Message{},
+			want:    "<0>1 - - - - - -",
+		},
+		{
+			name: "full",
+			message: &Message{
+				Facility:  16,
+				Severity:  SeverityNotice,
+				Timestamp: time.Date(2024, 1, 2, 3, 4, 5, 123456789, time.UTC),
+				Hostname:  "honeypot",
+				AppName:   "funeypot",
+				ProcId:    "42",
+				MsgId:     "attempt",
+				StructuredData: []Element{
+					{Id: "attempt@32473", Params: []Param{{"ip", "1.2.3.4"}, {"user", `a"b\c]d`}}},
+					{Id: "geo@32473", Params: []Param{{"country", "US"}}},
+				},
+				Msg: "login from 1.2.3.4",
+			},
+			want: `<133>1 2024-01-02T03:04:05.123456Z honeypot funeypot 42 attempt [attempt@32473 ip="1.2.3.4" user="a\"b\\c\]d"][geo@32473 country="US"] login from 1.2.3.4`,
+		},
+		{
+			name: "invalid header",
+			message: &Message{
+				Severity: SeverityInfo,
+				Hostname: "my host",
+				MsgId:    strings.Repeat("a", 40),
+			},
+			want: "<6>1 - myhost - - " + strings.Repeat("a", 32) + " -",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, string(tt.message.Format()))
+		})
+	}
+}
+
+func TestParseFacility(t *testing.T) {
+	f, ok := ParseFacility("local0")
+	assert.True(t, ok)
+	assert.Equal(t, Facility(16), f)
+	assert.Equal(t, "local0", f.String())
+
+	_, ok = ParseFacility("local8")
+	assert.False(t, ok)
+}
+
+func TestWriter(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	t.Run("udp", func(t *testing.T) {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer conn.Close() // nolint:errcheck
+
+		w, err := NewWriter(ctx, Options{Network: "udp", Address: conn.LocalAddr().String(), BufferSize: 10})
+		require.NoError(t, err)
+		assert.True(t, w.Write(&Message{MsgId: "test"}))
+
+		buf := make([]byte, 1024)
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+		n, _, err := conn.ReadFrom(buf)
+		require.NoError(t, err)
+		assert.Equal(t, "<0>1 - - - - test -", string(buf[:n]))
+	})
+
+	t.Run("tcp reconnect", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer listener.Close() // nolint:errcheck
+
+		w, err := NewWriter(ctx, Options{Network: "tcp", Address: listener.Addr().String(), BufferSize: 10})
+		require.NoError(t, err)
+
+		read := func(conn net.Conn) string {
+			r := bufio.NewReader(conn)
+			length, err := r.ReadString(' ')
+			require.NoError(t, err)
+			n, err := strconv.Atoi(strings.TrimSpace(length))
+			require.NoError(t, err)
+			buf := make([]byte, n)
+			_, err = r.Read(buf)
+			require.NoError(t, err)
+			return string(buf)
+		}
+
+		assert.True(t, w.Write(&Message{MsgId: "first"}))
+		conn, err := listener.Accept()
+		require.NoError(t, err)
+		assert.Equal(t, "<0>1 - - - - first -", read(conn))
+		// the collector restarts
+		_ = conn.Close()
+
+		// the first write after closing may succeed, since the writer doesn't know it until the peer resets it
+		for i := 0; i < 2; i++ {
+			assert.True(t, w.Write(&Message{MsgId: "second"}))
+			time.Sleep(100 * time.Millisecond)
+		}
+		conn, err = listener.Accept()
+		require.NoError(t, err)
+		defer conn.Close() // nolint:errcheck
+		assert.Equal(t, "<0>1 - - - - second -", read(conn))
+	})
+
+	t.Run("dead collector", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		addr := listener.Addr().String()
+		require.NoError(t, listener.Close())
+
+		ctx, cancel := context.WithCancel(ctx)
+		w, err := NewWriter(ctx, Options{Network: "tcp", Address: addr, BufferSize: 2})
+		require.NoError(t, err)
+
+		start := time.Now()
+		// one is held by the writer, two are in the buffer
+		for i := 0; i < 5; i++ {
+			w.Write(&Message{})
+		}
+		assert.Less(t, time.Since(start), time.Second)
+		assert.GreaterOrEqual(t, w.Dropped(), int64(2))
+
+		cancel()
+		select {
+		case <-w.Done():
+		case <-time.After(5 * time.Second):
+			t.Fatal("writer doesn't stop")
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := NewWriter(ctx, Options{Network: "unix", Address: "/dev/log", BufferSize: 1})
+		assert.Error(t, err)
+		_, err = NewWriter(ctx, Options{Network: "udp", Address: "127.0.0.1:514"})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,145 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package syslog
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/funeypot/funeypot/internal/pkg/logs"
+)
+
+const (
+	minBackoff = time.Second
+	maxBackoff = time.Minute
+)
+
+type Options struct {
+	Network    string // "udp", "tcp" or "tls"
+	Address    string
+	TLSConfig  *tls.Config // for "tls", nil means the default one
+	Timeout    time.Duration
+	BufferSize int // the maximum number of the messages waiting to be sent
+}
+
+// Writer sends the messages in the background, so writing never blocks.
+// It reconnects with backoff if the connection fails, and drops the messages if the buffer is full.
+type Writer struct {
+	opts    Options
+	queue   chan []byte
+	dropped atomic.Int64
+	done    chan struct{}
+}
+
+// NewWriter returns a writer which sends the messages until the context is done.
+func NewWriter(ctx context.Context, opts Options) (*Writer, error) {
+	switch opts.Network {
+	case "udp", "tcp", "tls":
+	default:
+		return nil, fmt.Errorf("unknown network %q", opts.Network)
+	}
+	if opts.BufferSize <= 0 {
+		return nil, fmt.Errorf("invalid buffer size %d", opts.BufferSize)
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 5 * time.Second
+	}
+
+	ret := &Writer{
+		opts:  opts,
+		queue: make(chan []byte, opts.BufferSize),
+		done:  make(chan struct{}),
+	}
+	go ret.run(ctx)
+	return ret, nil
+}
+
+// Write queues the message, it returns false if the message is dropped since the buffer is full.
+func (w *Writer) Write(m *Message) bool {
+	data := m.Format()
+	if w.opts.Network != "udp" {
+		// the octet counting framing of RFC 6587
+		data = append([]byte(fmt.Sprintf("%d ", len(data))), data...)
+	}
+	select {
+	case w.queue <- data:
+		return true
+	default:
+		w.dropped.Add(1)
+		return false
+	}
+}
+
+// Dropped returns the number of the messages dropped since the buffer is full.
+func (w *Writer) Dropped() int64 {
+	return w.dropped.Load()
+}
+
+// Done returns a channel which is closed after the writer stops.
+func (w *Writer) Done() <-chan struct{} {
+	return w.done
+}
+
+func (w *Writer) run(ctx context.Context) {
+	defer close(w.done)
+	logger := logs.From(ctx)
+
+	var (
+		conn    net.Conn
+		backoff = minBackoff
+	)
+	defer func() {
+		if conn != nil {
+			_ = conn.Close()
+		}
+	}()
+
+	for {
+		var data []byte
+		select {
+		case <-ctx.Done():
+			return
+		case data = <-w.queue:
+		}
+
+		// keep the message until it's sent, or the writer stops
+		for {
+			var err error
+			if conn == nil {
+				conn, err = w.dial(ctx)
+			}
+			if err == nil {
+				_ = conn.SetWriteDeadline(time.Now().Add(w.opts.Timeout))
+				if _, err = conn.Write(data); err != nil {
+					_ = conn.Close()
+					conn = nil
+				}
+			}
+			if err == nil {
+				backoff = minBackoff
+				break
+			}
+
+			logger.Warnf("send syslog to %s, retry after %v: %v", w.opts.Address, backoff, err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(backoff*2, maxBackoff)
+		}
+	}
+}
+
+func (w *Writer) dial(ctx context.Context) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: w.opts.Timeout}
+	if w.opts.Network == "tls" {
+		return (&tls.Dialer{NetDialer: dialer, Config: w.opts.TLSConfig}).DialContext(ctx, "tcp", w.opts.Address)
+	}
+	return dialer.DialContext(ctx, w.opts.Network, w.opts.Address)
+}
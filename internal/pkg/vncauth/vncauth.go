@@ -0,0 +1,128 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+// Package vncauth implements the challenge-response of the VNC authentication,
+// and the recovering of passwords from captured responses.
+// See https://datatracker.ietf.org/doc/html/rfc6143#section-7.2.2
+package vncauth
+
+import (
+	"bufio"
+	"crypto/cipher"
+	"crypto/des"
+	"fmt"
+	"math/bits"
+	"os"
+	"strings"
+)
+
+const (
+	ChallengeSize = 16
+
+	// MaxWordlistSize limits the passwords tried for every response, since it's done while the client is connected.
+	MaxWordlistSize = 100000
+
+	maxPasswordLength = 8
+)
+
+// Encrypt returns the response of the challenge, only the first 8 bytes of the password are used.
+func Encrypt(challenge [ChallengeSize]byte, password string) [ChallengeSize]byte {
+	return encrypt(newCipher(password), challenge)
+}
+
+func newCipher(password string) cipher.Block {
+	key := make([]byte, maxPasswordLength)
+	copy(key, password)
+	for i, b := range key {
+		// VNC uses the bits of every byte in reverse order as the DES key
+		key[i] = bits.Reverse8(b)
+	}
+
+	block, err := des.NewCipher(key)
+	if err != nil {
+		// it's impossible since the length of key is always 8
+		panic(err)
+	}
+	return block
+}
+
+func encrypt(block cipher.Block, challenge [ChallengeSize]byte) [ChallengeSize]byte {
+	var ret [ChallengeSize]byte
+	block.Encrypt(ret[:8], challenge[:8])
+	block.Encrypt(ret[8:], challenge[8:])
+	return ret
+}
+
+// Wordlist holds the passwords to try, with their ciphers prepared once.
+type Wordlist struct {
+	passwords []string
+	ciphers   []cipher.Block
+}
+
+func NewWordlist(passwords []string) *Wordlist {
+	ret := &Wordlist{
+		passwords: passwords,
+		ciphers:   make([]cipher.Block, 0, len(passwords)),
+	}
+	for _, password := range passwords {
+		ret.ciphers = append(ret.ciphers, newCipher(password))
+	}
+	return ret
+}
+
+// Len returns the number of passwords, it's 0 for a nil wordlist.
+func (w *Wordlist) Len() int {
+	if w == nil {
+		return 0
+	}
+	return len(w.passwords)
+}
+
+// Recover tries the passwords in the wordlist, and returns the one which matches the response.
+func (w *Wordlist) Recover(challenge, response [ChallengeSize]byte) (string, bool) {
+	if w == nil {
+		return "", false
+	}
+	for i, block := range w.ciphers {
+		if encrypt(block, challenge) == response {
+			return w.passwords[i], true
+		}
+	}
+	return "", false
+}
+
+// LoadWordlist reads passwords line by line, empty lines are ignored.
+// Passwords which are equal in the first 8 bytes are deduplicated, since they are the same for VNC.
+// It fails if there are more than MaxWordlistSize passwords.
+func LoadWordlist(file string) (*Wordlist, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("open file %q: %w", file, err)
+	}
+	defer f.Close() //nolint:errcheck
+
+	var ret []string
+	seen := map[string]struct{}{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		password := strings.TrimRight(scanner.Text(), "\r")
+		if password == "" {
+			continue
+		}
+		if len(password) > maxPasswordLength {
+			password = password[:maxPasswordLength]
+		}
+		if _, ok := seen[password]; ok {
+			continue
+		}
+		if len(ret) >= MaxWordlistSize {
+			return nil, fmt.Errorf("read file %q: more than %d passwords", file, MaxWordlistSize)
+		}
+		seen[password] = struct{}{}
+		ret = append(ret, password)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read file %q: %w", file, err)
+	}
+	return NewWordlist(ret), nil
+}
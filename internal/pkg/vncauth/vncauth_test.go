@@ -0,0 +1,68 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package vncauth
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncrypt(t *testing.T) {
+	// the fixed key used by vncpasswd to obfuscate stored passwords,
+	// "password" is stored as "dbd83cfd727a1458" with it.
+	fixedKey := string([]byte{23, 82, 107, 6, 35, 78, 88, 7})
+
+	var challenge [ChallengeSize]byte
+	copy(challenge[:], "passwordpassword")
+	got := Encrypt(challenge, fixedKey)
+	assert.Equal(t, "dbd83cfd727a1458dbd83cfd727a1458", hex.EncodeToString(got[:]))
+
+	assert.Equal(t, Encrypt(challenge, "password"), Encrypt(challenge, "password_too_long"))
+	assert.NotEqual(t, Encrypt(challenge, "password"), Encrypt(challenge, "passwor"))
+}
+
+func TestRecover(t *testing.T) {
+	var challenge [ChallengeSize]byte
+	copy(challenge[:], "fedcba9876543210")
+	response := Encrypt(challenge, "123456")
+
+	password, ok := NewWordlist([]string{"admin", "123456", "password"}).Recover(challenge, response)
+	assert.True(t, ok)
+	assert.Equal(t, "123456", password)
+
+	_, ok = NewWordlist([]string{"admin", "password"}).Recover(challenge, response)
+	assert.False(t, ok)
+
+	var empty *Wordlist
+	_, ok = empty.Recover(challenge, response)
+	assert.False(t, ok)
+}
+
+func TestLoadWordlist(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "wordlist.txt")
+	require.NoError(t, os.WriteFile(file, []byte("admin\r\n\n123456\npassword1\npassword2\n"), 0o644))
+
+	wordlist, err := LoadWordlist(file)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"admin", "123456", "password"}, wordlist.passwords)
+	assert.Equal(t, 3, wordlist.Len())
+
+	_, err = LoadWordlist(filepath.Join(t.TempDir(), "missing.txt"))
+	assert.Error(t, err)
+
+	tooMany := &strings.Builder{}
+	for i := 0; i <= MaxWordlistSize; i++ {
+		_, _ = fmt.Fprintf(tooMany, "%d\n", i)
+	}
+	require.NoError(t, os.WriteFile(file, []byte(tooMany.String()), 0o644))
+	_, err = LoadWordlist(file)
+	assert.ErrorContains(t, err, "more than 100000 passwords")
+}
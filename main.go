@@ -15,8 +15,6 @@ var (
 )
 
 func main() {
-	// TODO: support more subcommands
-
 	if err := entry.Run(context.Background(), Version, os.Args[1:]); err != nil {
 		os.Exit(1)
 	}
@@ -0,0 +1,63 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/funeypot/funeypot/internal/app/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChat(t *testing.T) {
+	texts := make(chan string, 10)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := map[string]string{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		texts <- body["text"]
+	}))
+	defer webhook.Close()
+
+	defer PrepareServers(t, func(cfg *config.Config) {
+		cfg.Http.Enabled = true
+		cfg.Chat.Enabled = true
+		cfg.Chat.Url = webhook.URL
+		cfg.Chat.Alerts.Thresholds = []int{2}
+	})()
+
+	for i := 0; i < 3; i++ {
+		req, err := http.NewRequest("GET", "http://127.0.0.1:8080", nil)
+		require.NoError(t, err)
+		req.SetBasicAuth("username", "password")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+	}
+
+	var got []string
+	timeout := time.After(5 * time.Second)
+	for len(got) < 2 {
+		select {
+		case text := <-texts:
+			got = append(got, text)
+		case <-timeout:
+			t.Fatalf("got %d alerts only", len(got))
+		}
+	}
+	// the location depends on the ip geolocation
+	assert.Regexp(t, `^New attacker 127.0.0.1 (from .+ )?tried http with user "username" and password "pa\*\*\*\*rd".$`, got[0])
+	assert.Regexp(t, `^127.0.0.1 (from .+ )?made 2 http attempts in \S+, the last with user "username" and password "pa\*\*\*\*rd".$`, got[1])
+
+	select {
+	case text := <-texts:
+		t.Fatalf("unexpected alert: %s", text)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
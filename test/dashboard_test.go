@@ -4,8 +4,11 @@
 package test
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/funeypot/funeypot/internal/app/config"
 
@@ -94,4 +97,212 @@ func TestDashboard(t *testing.T) {
 		require.NoError(t, err)
 		assert.Equal(t, 200, resp.StatusCode)
 	})
+
+	t.Run("/api/v1/records", func(t *testing.T) {
+		req, err := http.NewRequest("GET", "http://127.0.0.1:8080/login?next=1", nil)
+		require.NoError(t, err)
+		req.SetBasicAuth("admin", "admin")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+
+		getRecords := func(query string) (int, []map[string]any) {
+			req, err := http.NewRequest("GET", "http://127.0.0.1:8080/api/v1/records?"+query, nil)
+			require.NoError(t, err)
+			req.SetBasicAuth("dashboard_username", "dashboard_password")
+
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close() // nolint:errcheck
+			if resp.StatusCode != 200 {
+				return resp.StatusCode, nil
+			}
+			body := struct {
+				Records []map[string]any `json:"records"`
+			}{}
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+			return resp.StatusCode, body.Records
+		}
+
+		// the latest one, the others are from the previous cases
+		var records []map[string]any
+		WaitAssert(time.Second, func() bool {
+			_, records = getRecords("kind=http&local_port=8080&limit=1")
+			return len(records) > 0 && records[0]["user"] == "admin"
+		})
+		require.Len(t, records, 1)
+		assert.Equal(t, "admin", records[0]["user"])
+		assert.Equal(t, "plain", records[0]["variant"])
+		assert.Equal(t, map[string]any{"method": "GET", "host": "127.0.0.1:8080", "path": "/login"}, records[0]["extra"])
+
+		code, records := getRecords("kind=ssh")
+		assert.Equal(t, 200, code)
+		assert.Empty(t, records)
+
+		code, _ = getRecords("kind=telnet")
+		assert.Equal(t, 400, code)
+		code, _ = getRecords("local_port=65536")
+		assert.Equal(t, 400, code)
+	})
+
+	t.Run("/api/v1/stats", func(t *testing.T) {
+		getStats := func(query string) (int, []map[string]any) {
+			req, err := http.NewRequest("GET", "http://127.0.0.1:8080/api/v1/stats?"+query, nil)
+			require.NoError(t, err)
+			req.SetBasicAuth("dashboard_username", "dashboard_password")
+
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close() // nolint:errcheck
+			if resp.StatusCode != 200 {
+				return resp.StatusCode, nil
+			}
+			body := struct {
+				Points []map[string]any `json:"points"`
+			}{}
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+			return resp.StatusCode, body.Points
+		}
+
+		// the attempts of the previous cases
+		for _, period := range []string{"hour", "day"} {
+			var points []map[string]any
+			WaitAssert(time.Second, func() bool {
+				_, points = getStats("kind=http&period=" + period)
+				return len(points) > 0
+			})
+			require.Len(t, points, 1)
+			assert.GreaterOrEqual(t, points[0]["attempts"], float64(2))
+			assert.Equal(t, float64(1), points[0]["unique_ips"])
+			assert.Equal(t, float64(2), points[0]["unique_credentials"])
+		}
+
+		code, points := getStats("kind=ssh")
+		assert.Equal(t, 200, code)
+		assert.Empty(t, points)
+
+		code, _ = getStats("period=week")
+		assert.Equal(t, 400, code)
+		code, _ = getStats("asn=AS3215")
+		assert.Equal(t, 400, code)
+	})
+
+	t.Run("/api/v1/credentials", func(t *testing.T) {
+		getCredentials := func(path, query string) (int, []map[string]any) {
+			req, err := http.NewRequest("GET", "http://127.0.0.1:8080/api/v1/credentials/"+path+"?"+query, nil)
+			require.NoError(t, err)
+			req.SetBasicAuth("dashboard_username", "dashboard_password")
+
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close() // nolint:errcheck
+			if resp.StatusCode != 200 {
+				return resp.StatusCode, nil
+			}
+			body := struct {
+				Credentials []map[string]any `json:"credentials"`
+			}{}
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+			return resp.StatusCode, body.Credentials
+		}
+
+		// the attempts of the previous cases
+		code, credentials := getCredentials("top", "kind=http&group=user")
+		assert.Equal(t, 200, code)
+		require.Len(t, credentials, 2)
+		users := []any{credentials[0]["user"], credentials[1]["user"]}
+		assert.ElementsMatch(t, []any{"admin", "username"}, users)
+		assert.NotContains(t, credentials[0], "password")
+
+		code, credentials = getCredentials("top", "group=ip&user=admin&password=admin")
+		assert.Equal(t, 200, code)
+		require.Len(t, credentials, 1)
+		assert.Equal(t, "127.0.0.1", credentials[0]["ip"])
+
+		code, credentials = getCredentials("new", "group=pair")
+		assert.Equal(t, 200, code)
+		assert.Len(t, credentials, 2)
+		code, credentials = getCredentials("new", fmt.Sprintf("group=pair&after=%d", time.Now().Add(time.Hour).Unix()))
+		assert.Equal(t, 200, code)
+		assert.Empty(t, credentials)
+
+		code, _ = getCredentials("top", "group=country")
+		assert.Equal(t, 400, code)
+		code, _ = getCredentials("new", "before=1")
+		assert.Equal(t, 400, code)
+
+		req, err := http.NewRequest("GET", "http://127.0.0.1:8080/credentials", nil)
+		require.NoError(t, err)
+		req.SetBasicAuth("dashboard_username", "dashboard_password")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		assert.Equal(t, 200, resp.StatusCode)
+	})
+}
+
+func TestDashboard_Campaigns(t *testing.T) {
+	defer PrepareServers(t, func(cfg *config.Config) {
+		cfg.Http.Enabled = true
+		cfg.Dashboard.Enabled = true
+		cfg.Dashboard.Username = "dashboard_username"
+		cfg.Dashboard.Password = "dashboard_password"
+		cfg.Campaign.Enabled = true
+		cfg.Campaign.Ipv4Prefix = 24
+		cfg.Campaign.MinIps = 2
+	})()
+
+	get := func(path string, body any) int {
+		req, err := http.NewRequest("GET", "http://127.0.0.1:8080"+path, nil)
+		require.NoError(t, err)
+		req.SetBasicAuth("dashboard_username", "dashboard_password")
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close() // nolint:errcheck
+		if resp.StatusCode == 200 {
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(body))
+		}
+		return resp.StatusCode
+	}
+
+	// the attempts from different ips of a same network, forwarded by the local proxy
+	for _, ip := range []string{"203.0.113.1", "203.0.113.2", "203.0.113.2", "198.51.100.1"} {
+		req, err := http.NewRequest("GET", "http://127.0.0.1:8080", nil)
+		require.NoError(t, err)
+		req.SetBasicAuth("admin", "admin")
+		req.Header.Set("X-Forwarded-For", ip)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+	}
+
+	var campaigns struct {
+		Campaigns []map[string]any `json:"campaigns"`
+	}
+	WaitAssert(time.Second, func() bool {
+		return get("/api/v1/campaigns?type=prefix", &campaigns) == 200 && len(campaigns.Campaigns) > 0
+	})
+	require.Len(t, campaigns.Campaigns, 1)
+	campaign := campaigns.Campaigns[0]
+	assert.Equal(t, "203.0.113.0/24", campaign["network"])
+	assert.Equal(t, "http", campaign["kind"])
+	assert.Equal(t, float64(2), campaign["ips"])
+
+	var detail struct {
+		Network string           `json:"network"`
+		Members []map[string]any `json:"members"`
+	}
+	// it's detected by the second ip, wait for the last attempt
+	WaitAssert(time.Second, func() bool {
+		return get(fmt.Sprintf("/api/v1/campaigns/%v", campaign["id"]), &detail) == 200 &&
+			len(detail.Members) > 0 && detail.Members[0]["attempts"] == float64(2)
+	})
+	assert.Equal(t, "203.0.113.0/24", detail.Network)
+	require.Len(t, detail.Members, 2)
+	assert.Equal(t, map[string]any{"ip": "203.0.113.2", "attempts": float64(2)}, detail.Members[0])
+
+	assert.Equal(t, 404, get("/api/v1/campaigns/1000", &detail))
+	assert.Equal(t, 400, get("/api/v1/campaigns/abc", &detail))
+	assert.Equal(t, 400, get("/api/v1/campaigns?type=city", &campaigns))
 }
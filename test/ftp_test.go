@@ -55,7 +55,7 @@ func TestFtpServer_Report(t *testing.T) {
 
 	t.Run("first", func(t *testing.T) {
 		defer httpmock.Reset()
-		httpmock.RegisterResponder("POST", abuseipdb.ReportUrl,
+		httpmock.RegisterResponder("POST", abuseipdb.DefaultBaseUrl+"/report",
 			func(request *http.Request) (*http.Response, error) {
 				assert.Equal(t, "test_key", request.Header.Get("Key"))
 				assert.Equal(t, "application/x-www-form-urlencoded", request.Header.Get("Content-Type"))
@@ -83,7 +83,7 @@ func TestFtpServer_Report(t *testing.T) {
 
 	t.Run("continue", func(t *testing.T) {
 		defer httpmock.Reset()
-		httpmock.RegisterResponder("POST", abuseipdb.ReportUrl,
+		httpmock.RegisterResponder("POST", abuseipdb.DefaultBaseUrl+"/report",
 			func(request *http.Request) (*http.Response, error) {
 				assert.Equal(t, "test_key", request.Header.Get("Key"))
 				assert.Equal(t, "application/x-www-form-urlencoded", request.Header.Get("Content-Type"))
@@ -4,17 +4,24 @@
 package test
 
 import (
+	"encoding/csv"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/funeypot/funeypot/internal/app/config"
+	"github.com/funeypot/funeypot/internal/app/model"
 	"github.com/funeypot/funeypot/internal/pkg/abuseipdb"
 
+	"github.com/glebarez/sqlite"
 	"github.com/jarcoal/httpmock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
 )
 
 func TestHttpServer(t *testing.T) {
@@ -44,6 +51,20 @@ func TestHttpServer(t *testing.T) {
 	})
 }
 
+func TestHttpServer_Listeners(t *testing.T) {
+	defer PrepareServers(t, func(cfg *config.Config) {
+		cfg.Http.Enabled = true
+		cfg.Http.Listeners = []config.HttpListener{{Address: ":8081"}, {Address: ":8082"}}
+	})()
+
+	for _, port := range []int{8080, 8081, 8082} {
+		resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d", port))
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		assert.Equal(t, 401, resp.StatusCode)
+	}
+}
+
 func TestHttpServer_Report(t *testing.T) {
 	HttpClient := &http.Client{
 		Transport: http.DefaultTransport,
@@ -61,7 +82,7 @@ func TestHttpServer_Report(t *testing.T) {
 
 	t.Run("first", func(t *testing.T) {
 		defer httpmock.Reset()
-		httpmock.RegisterResponder("POST", abuseipdb.ReportUrl,
+		httpmock.RegisterResponder("POST", abuseipdb.DefaultBaseUrl+"/report",
 			func(request *http.Request) (*http.Response, error) {
 				assert.Equal(t, "test_key", request.Header.Get("Key"))
 				assert.Equal(t, "application/x-www-form-urlencoded", request.Header.Get("Content-Type"))
@@ -89,7 +110,7 @@ func TestHttpServer_Report(t *testing.T) {
 
 	t.Run("continue", func(t *testing.T) {
 		defer httpmock.Reset()
-		httpmock.RegisterResponder("POST", abuseipdb.ReportUrl,
+		httpmock.RegisterResponder("POST", abuseipdb.DefaultBaseUrl+"/report",
 			func(request *http.Request) (*http.Response, error) {
 				assert.Equal(t, "test_key", request.Header.Get("Key"))
 				assert.Equal(t, "application/x-www-form-urlencoded", request.Header.Get("Content-Type"))
@@ -113,3 +134,264 @@ func TestHttpServer_Report(t *testing.T) {
 		assert.Equal(t, 1, httpmock.GetTotalCallCount())
 	})
 }
+
+func TestHttpServer_ReportTemplate(t *testing.T) {
+	HttpClient := &http.Client{
+		Transport: http.DefaultTransport,
+	}
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	defer PrepareServers(t, func(cfg *config.Config) {
+		cfg.Http.Enabled = true
+		cfg.Abuseipdb.Enabled = true
+		cfg.Abuseipdb.Key = "test_key"
+		cfg.Abuseipdb.Interval = 0
+		cfg.Abuseipdb.OmitPassword = true
+		cfg.Abuseipdb.Comment = `{{.Count}} {{.Kind}} attempts from {{.Ip}}{{if not .PasswordOmitted}} with {{.Password}}{{end}}`
+		cfg.Abuseipdb.Kinds = map[string]config.AbuseipdbKind{
+			"http": {MinCount: 1, Categories: []string{"Brute-Force", "Bad Web Bot"}},
+		}
+	})()
+
+	httpmock.RegisterResponder("POST", abuseipdb.DefaultBaseUrl+"/report",
+		func(request *http.Request) (*http.Response, error) {
+			assert.NoError(t, request.ParseForm())
+			assert.Equal(t, "18,19", request.Form.Get("categories"))
+			assert.Equal(t, "1 http attempts from 127.0.0.1", request.Form.Get("comment"))
+			return httpmock.NewStringResponse(200, `{}`), nil
+		})
+
+	req, err := http.NewRequest("GET", "http://127.0.0.1:8080", nil)
+	require.NoError(t, err)
+	req.SetBasicAuth("username", "password")
+	_, _ = HttpClient.Do(req)
+
+	WaitAssert(time.Second, func() bool {
+		return httpmock.GetTotalCallCount() > 0
+	})
+	assert.Equal(t, 1, httpmock.GetTotalCallCount())
+}
+
+func TestHttpServer_ReportMinDuration(t *testing.T) {
+	HttpClient := &http.Client{
+		Transport: http.DefaultTransport,
+	}
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	defer PrepareServers(t, func(cfg *config.Config) {
+		cfg.Http.Enabled = true
+		cfg.Abuseipdb.Enabled = true
+		cfg.Abuseipdb.Key = "test_key"
+		cfg.Abuseipdb.Interval = 0
+		cfg.Abuseipdb.MinCount = 1
+		cfg.Abuseipdb.MinDuration = time.Hour
+	})()
+
+	httpmock.RegisterResponder("POST", abuseipdb.DefaultBaseUrl+"/report", httpmock.NewStringResponder(200, `{}`))
+
+	for i := 0; i < 5; i++ {
+		req, err := http.NewRequest("GET", "http://127.0.0.1:8080", nil)
+		require.NoError(t, err)
+		req.SetBasicAuth("username", "password")
+		_, _ = HttpClient.Do(req)
+	}
+
+	// the attempts last shorter than the min duration
+	time.Sleep(200 * time.Millisecond)
+	assert.Zero(t, httpmock.GetTotalCallCount())
+}
+
+func TestHttpServer_ReportQueue(t *testing.T) {
+	HttpClient := &http.Client{
+		Transport: http.DefaultTransport,
+	}
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	defer PrepareServers(t, func(cfg *config.Config) {
+		cfg.Http.Enabled = true
+		cfg.Abuseipdb.Enabled = true
+		cfg.Abuseipdb.Key = "test_key"
+		cfg.Abuseipdb.Interval = 0
+		cfg.Abuseipdb.MinCount = 1
+		cfg.Abuseipdb.Queue = config.AbuseipdbQueue{
+			Enabled:       true,
+			RetryDelay:    100 * time.Millisecond,
+			MaxRetryDelay: time.Second,
+			MaxAge:        time.Hour,
+			BatchSize:     10,
+		}
+	})()
+
+	var comments []string
+	httpmock.RegisterResponder("POST", abuseipdb.DefaultBaseUrl+"/report",
+		func(request *http.Request) (*http.Response, error) {
+			assert.NoError(t, request.ParseForm())
+			comments = append(comments, request.Form.Get("comment"))
+			if len(comments) == 1 {
+				return httpmock.NewStringResponse(500, `{}`), nil
+			}
+			return httpmock.NewStringResponse(200, `{}`), nil
+		})
+
+	req, err := http.NewRequest("GET", "http://127.0.0.1:8080", nil)
+	require.NoError(t, err)
+	req.SetBasicAuth("username", "password")
+	_, _ = HttpClient.Do(req)
+
+	// failed, then retried by the queue
+	WaitAssert(2*time.Second, func() bool {
+		return httpmock.GetTotalCallCount() >= 2
+	})
+	time.Sleep(300 * time.Millisecond)
+	assert.Equal(t, 2, httpmock.GetTotalCallCount(), "sent only once after the retry")
+	require.Len(t, comments, 2)
+	assert.Equal(t, comments[0], comments[1])
+}
+
+func TestHttpServer_ReportQuota(t *testing.T) {
+	HttpClient := &http.Client{
+		Transport: http.DefaultTransport,
+	}
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	defer PrepareServers(t, func(cfg *config.Config) {
+		cfg.Http.Enabled = true
+		cfg.Abuseipdb.Enabled = true
+		cfg.Abuseipdb.Key = "test_key"
+		cfg.Abuseipdb.Interval = 0
+		cfg.Abuseipdb.MinCount = 1
+		cfg.Abuseipdb.Queue = config.AbuseipdbQueue{
+			Enabled:       true,
+			RetryDelay:    100 * time.Millisecond,
+			MaxRetryDelay: time.Second,
+			MaxAge:        time.Hour,
+			BatchSize:     10,
+			DailyQuota:    1,
+		}
+	})()
+
+	httpmock.RegisterResponder("POST", abuseipdb.DefaultBaseUrl+"/report", httpmock.NewStringResponder(200, `{}`))
+
+	for i := 0; i < 3; i++ {
+		req, err := http.NewRequest("GET", "http://127.0.0.1:8080", nil)
+		require.NoError(t, err)
+		req.SetBasicAuth("username", "password")
+		_, _ = HttpClient.Do(req)
+	}
+
+	// the others are queued until the next day
+	WaitAssert(time.Second, func() bool {
+		return httpmock.GetTotalCallCount() > 0
+	})
+	time.Sleep(300 * time.Millisecond)
+	assert.Equal(t, 1, httpmock.GetTotalCallCount())
+}
+
+func TestHttpServer_ReportBulk(t *testing.T) {
+	HttpClient := &http.Client{
+		Transport: http.DefaultTransport,
+	}
+
+	var bulks atomic.Int32
+	abuseipdbServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/bulk-report", r.URL.Path)
+		file, _, err := r.FormFile("csv")
+		require.NoError(t, err)
+		records, err := csv.NewReader(file).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, records, 2)
+		assert.Equal(t, "127.0.0.1", records[1][0])
+		assert.Equal(t, "18,21", records[1][1])
+		assert.Contains(t, records[1][3], "Funeypot detected 3 http attempts")
+		bulks.Add(1)
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data": {"savedReports": 1, "invalidReports": []}}`))
+	}))
+	defer abuseipdbServer.Close()
+
+	defer PrepareServers(t, func(cfg *config.Config) {
+		cfg.Http.Enabled = true
+		cfg.Abuseipdb.Enabled = true
+		cfg.Abuseipdb.BaseUrl = abuseipdbServer.URL
+		cfg.Abuseipdb.Key = "test_key"
+		cfg.Abuseipdb.Interval = time.Hour
+		cfg.Abuseipdb.MinCount = 1
+		cfg.Abuseipdb.Bulk = config.AbuseipdbBulk{
+			Enabled:   true,
+			Interval:  500 * time.Millisecond,
+			BatchSize: 100,
+		}
+	})()
+
+	// merged into one report before the bulk
+	for i := 0; i < 3; i++ {
+		req, err := http.NewRequest("GET", "http://127.0.0.1:8080", nil)
+		require.NoError(t, err)
+		req.SetBasicAuth("username", "password")
+		_, _ = HttpClient.Do(req)
+	}
+
+	WaitAssert(2*time.Second, func() bool {
+		return bulks.Load() > 0
+	})
+	time.Sleep(time.Second)
+	assert.Equal(t, int32(1), bulks.Load())
+}
+
+func TestHttpServer_ReportBulkQuota(t *testing.T) {
+	HttpClient := &http.Client{
+		Transport: http.DefaultTransport,
+	}
+
+	var bulks atomic.Int32
+	abuseipdbServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bulks.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data": {"savedReports": 1, "invalidReports": []}}`))
+	}))
+	defer abuseipdbServer.Close()
+
+	dsn := filepath.Join(t.TempDir(), "funeypot.db")
+	defer PrepareServers(t, func(cfg *config.Config) {
+		cfg.Http.Enabled = true
+		cfg.Database.Dsn = dsn
+		cfg.Abuseipdb.Enabled = true
+		cfg.Abuseipdb.BaseUrl = abuseipdbServer.URL
+		cfg.Abuseipdb.Key = "test_key"
+		cfg.Abuseipdb.Interval = time.Hour
+		cfg.Abuseipdb.MinCount = 1
+		cfg.Abuseipdb.Queue.DailyQuota = 1
+		cfg.Abuseipdb.Bulk = config.AbuseipdbBulk{
+			Enabled:   true,
+			Interval:  200 * time.Millisecond,
+			BatchSize: 100,
+		}
+	})()
+
+	// the quota of today has been used up
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.Create(&model.AbuseipdbReport{Ip: "203.0.113.1", ReportedAt: time.Now(), Score: 50}).Error)
+
+	req, err := http.NewRequest("GET", "http://127.0.0.1:8080", nil)
+	require.NoError(t, err)
+	req.SetBasicAuth("username", "password")
+	_, _ = HttpClient.Do(req)
+
+	var pending model.AbuseipdbPending
+	WaitAssert(time.Second, func() bool {
+		return db.Last(&pending).Error == nil
+	})
+	assert.Equal(t, "127.0.0.1", pending.Ip)
+	time.Sleep(time.Second)
+	assert.Equal(t, int32(0), bulks.Load())
+}
@@ -18,6 +18,12 @@ import (
 )
 
 func PrepareServers(t *testing.T, modifyConfig func(cfg *config.Config)) func() {
+	_, cleanup := PrepareEntrypoint(t, modifyConfig)
+	return cleanup
+}
+
+// PrepareEntrypoint is like PrepareServers, but also returns the started entrypoint.
+func PrepareEntrypoint(t *testing.T, modifyConfig func(cfg *config.Config)) (*entry.Entrypoint, func()) {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	cfg, err := config.Load(filepath.Join(t.TempDir(), "funeypot.yaml"), true)
@@ -29,6 +35,9 @@ func PrepareServers(t *testing.T, modifyConfig func(cfg *config.Config)) func()
 	cfg.Ssh.Address = ":2222"
 	cfg.Http.Address = ":8080"
 	cfg.Ftp.Address = ":2121"
+	cfg.Redis.Address = ":6380"
+	cfg.Rdp.Address = ":3390"
+	cfg.Vnc.Address = ":5901"
 	cfg.Log.Level = "error"
 	cfg.Database.Dsn = filepath.Join(t.TempDir(), "funeypot.db")
 
@@ -48,56 +57,60 @@ func PrepareServers(t *testing.T, modifyConfig func(cfg *config.Config)) func()
 
 	waitServers(t, cfg)
 
-	return func() {
+	return entrypoint, func() {
 		cancel()
 		entrypoint.Shutdown(ctx)
 	}
 }
 
 func waitServers(t *testing.T, cfg *config.Config) {
-	wg := &sync.WaitGroup{}
-	deadline := time.Now().Add(5 * time.Second)
-
-	var (
-		sshErr  error
-		httpErr error
-		ftpErr  error
-	)
-
-	{
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			sshErr = waitTcp(deadline, cfg.Ssh.Address)
-		}()
+	type target struct {
+		name    string
+		address string
+	}
+	var targets []target
+	for _, v := range cfg.Ssh.AllListeners() {
+		targets = append(targets, target{"ssh", v.Address})
 	}
-
 	if cfg.Http.Enabled {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			httpErr = waitTcp(deadline, cfg.Http.Address)
-		}()
+		for _, v := range cfg.Http.AllListeners() {
+			targets = append(targets, target{"http", v.Address})
+		}
 	}
-
 	if cfg.Ftp.Enabled {
+		for _, v := range cfg.Ftp.AllListeners() {
+			targets = append(targets, target{"ftp", v.Address})
+		}
+	}
+	if cfg.Redis.Enabled {
+		targets = append(targets, target{"redis", cfg.Redis.Address})
+	}
+	if cfg.Rdp.Enabled {
+		targets = append(targets, target{"rdp", cfg.Rdp.Address})
+	}
+	if cfg.Vnc.Enabled {
+		targets = append(targets, target{"vnc", cfg.Vnc.Address})
+	}
+	for _, v := range cfg.Tcp {
+		targets = append(targets, target{"tcp", v.Address})
+	}
+
+	wg := &sync.WaitGroup{}
+	deadline := time.Now().Add(5 * time.Second)
+	errs := make([]error, len(targets))
+	for i, v := range targets {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			ftpErr = waitTcp(deadline, cfg.Ftp.Address)
+			errs[i] = waitTcp(deadline, v.address)
 		}()
 	}
-
 	wg.Wait()
 
-	if sshErr != nil {
-		t.Fatalf("ssh server not ready: %v", sshErr)
-	}
-	if httpErr != nil {
-		t.Fatalf("http server not ready: %v", httpErr)
-	}
-	if ftpErr != nil {
-		t.Fatalf("ftp server not ready: %v", ftpErr)
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("%s server on %s not ready: %v", targets[i].name, targets[i].address, err)
+		}
 	}
 }
 
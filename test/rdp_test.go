@@ -0,0 +1,127 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package test
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/funeypot/funeypot/internal/app/config"
+	"github.com/funeypot/funeypot/internal/pkg/abuseipdb"
+	"github.com/funeypot/funeypot/internal/pkg/ntlm"
+	"github.com/funeypot/funeypot/internal/pkg/rdp"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRdpServer(t *testing.T) {
+	defer PrepareServers(t, func(cfg *config.Config) {
+		cfg.Rdp.Enabled = true
+	})()
+
+	t.Run("standard security", func(t *testing.T) {
+		conn, err := net.Dial("tcp", "127.0.0.1:3390")
+		require.NoError(t, err)
+		defer conn.Close() // nolint:errcheck
+
+		confirm := dialRdp(t, conn, &rdp.ConnectionRequest{
+			Cookie:             "administrator",
+			Negotiation:        true,
+			RequestedProtocols: rdp.ProtocolRdp,
+		})
+		assert.Equal(t, rdp.FailureHybridRequired, confirm.Failure)
+	})
+
+	t.Run("nla", func(t *testing.T) {
+		conn, err := net.Dial("tcp", "127.0.0.1:3390")
+		require.NoError(t, err)
+		defer conn.Close() // nolint:errcheck
+
+		authenticateRdp(t, conn, "CORP", "administrator")
+	})
+}
+
+func TestRdpServer_Report(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	defer PrepareServers(t, func(cfg *config.Config) {
+		cfg.Rdp.Enabled = true
+		cfg.Abuseipdb.Enabled = true
+		cfg.Abuseipdb.Key = "test_key"
+		cfg.Abuseipdb.Interval = 0
+	})()
+
+	httpmock.RegisterResponder("POST", abuseipdb.DefaultBaseUrl+"/report",
+		func(request *http.Request) (*http.Response, error) {
+			assert.NoError(t, request.ParseForm())
+			assert.Equal(t, "127.0.0.1", request.Form.Get("ip"))
+			assert.Equal(t, "18,15", request.Form.Get("categories"))
+			assert.Equal(t, `Funeypot detected 5 rdp attempts in 0s. Last by user "CORP\\administrator", password "", client "ssl|hybrid (DESKTOP-1)".`, request.Form.Get("comment"))
+			return httpmock.NewStringResponse(200, `{}`), nil
+		})
+
+	for i := 0; i < 5; i++ {
+		conn, err := net.Dial("tcp", "127.0.0.1:3390")
+		require.NoError(t, err)
+		authenticateRdp(t, conn, "CORP", "administrator")
+		_ = conn.Close()
+	}
+
+	WaitAssert(time.Second, func() bool {
+		return httpmock.GetTotalCallCount() > 0
+	})
+	assert.Equal(t, 1, httpmock.GetTotalCallCount())
+}
+
+func dialRdp(t *testing.T, conn net.Conn, req *rdp.ConnectionRequest) *rdp.ConnectionConfirm {
+	require.NoError(t, rdp.WriteTpkt(conn, req.Marshal()))
+	payload, err := rdp.ReadTpkt(conn)
+	require.NoError(t, err)
+	confirm, err := rdp.ParseConnectionConfirm(payload)
+	require.NoError(t, err)
+	return confirm
+}
+
+func authenticateRdp(t *testing.T, conn net.Conn, domain, user string) {
+	confirm := dialRdp(t, conn, &rdp.ConnectionRequest{
+		Cookie:             user,
+		Negotiation:        true,
+		RequestedProtocols: rdp.ProtocolSsl | rdp.ProtocolHybrid,
+	})
+	require.Equal(t, rdp.ProtocolHybrid, confirm.SelectedProtocol)
+
+	tlsConn := tls.Client(conn, &tls.Config{InsecureSkipVerify: true}) // nolint:gosec
+	require.NoError(t, tlsConn.Handshake())
+
+	flags := uint32(ntlm.FlagNegotiateUnicode | ntlm.FlagNegotiateNtlm)
+	require.NoError(t, rdp.WriteTsRequest(tlsConn, &rdp.TsRequest{
+		Version:    6,
+		NegoTokens: []rdp.NegoToken{{Token: (&ntlm.Negotiate{Flags: flags}).Marshal()}},
+	}))
+	resp, err := rdp.ReadTsRequest(tlsConn)
+	require.NoError(t, err)
+	challenge, err := ntlm.ParseChallenge(resp.Token())
+	require.NoError(t, err)
+	assert.Regexp(t, `^WIN-[A-Z0-9]{11}$`, challenge.ComputerName)
+
+	require.NoError(t, rdp.WriteTsRequest(tlsConn, &rdp.TsRequest{
+		Version: 6,
+		NegoTokens: []rdp.NegoToken{{Token: (&ntlm.Authenticate{
+			Flags:       flags,
+			Domain:      domain,
+			User:        user,
+			Workstation: "DESKTOP-1",
+			NtResponse:  make([]byte, 24),
+		}).Marshal()}},
+	}))
+	resp, err = rdp.ReadTsRequest(tlsConn)
+	require.NoError(t, err)
+	assert.Equal(t, rdp.StatusLogonFailure, resp.ErrorCode)
+}
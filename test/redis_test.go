@@ -0,0 +1,144 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package test
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/funeypot/funeypot/internal/app/config"
+	"github.com/funeypot/funeypot/internal/pkg/abuseipdb"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisServer(t *testing.T) {
+	t.Run("auth", func(t *testing.T) {
+		defer PrepareServers(t, func(cfg *config.Config) {
+			cfg.Redis.Enabled = true
+			cfg.Redis.Auth = true
+			cfg.Redis.Delay = 0
+		})()
+
+		client := dialRedis(t)
+		defer client.Close() // nolint:errcheck
+
+		assert.Equal(t, "-NOAUTH Authentication required.", client.Do("PING"))
+		assert.Equal(t, "-WRONGPASS invalid username-password pair or user is disabled.", client.Do("AUTH", "password"))
+		assert.Equal(t, "-WRONGPASS invalid username-password pair or user is disabled.", client.Do("AUTH", "username", "password"))
+		assert.Equal(t, "+OK", client.Do("QUIT"))
+	})
+
+	t.Run("open", func(t *testing.T) {
+		defer PrepareServers(t, func(cfg *config.Config) {
+			cfg.Redis.Enabled = true
+			cfg.Redis.Auth = false
+		})()
+
+		client := dialRedis(t)
+		defer client.Close() // nolint:errcheck
+
+		assert.Equal(t, "+PONG", client.Do("PING"))
+		assert.Equal(t, "+OK", client.Do("SET", "key", "value"))
+		assert.Equal(t, "value", client.Do("GET", "key"))
+		assert.Equal(t, "$-1", client.Do("GET", "missing"))
+		assert.Equal(t, ":1", client.Do("DBSIZE"))
+		assert.Equal(t, "-OOM command not allowed when used memory > 'maxmemory'.", client.Do("SET", strings.Repeat("k", 2048), "value"))
+		assert.Equal(t, "+OK", client.Do("CONFIG", "SET", "dir", "/var/spool/cron/"))
+		assert.Equal(t, "+OK", client.Do("CONFIG", "SET", "dbfilename", "root"))
+		assert.Equal(t, "+OK", client.Do("SAVE"))
+		assert.Equal(t, "-ERR Error loading the extension. Please check the server logs.", client.Do("MODULE", "LOAD", "/tmp/exp.so"))
+		assert.True(t, strings.HasPrefix(client.Do("NOSUCHCOMMAND"), "-ERR unknown command 'nosuchcommand'"))
+		assert.Equal(t, "+PONG", client.DoInline("PING"))
+	})
+}
+
+func TestRedisServer_Report(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	defer PrepareServers(t, func(cfg *config.Config) {
+		cfg.Redis.Enabled = true
+		cfg.Redis.Auth = true
+		cfg.Redis.Delay = 0
+		cfg.Abuseipdb.Enabled = true
+		cfg.Abuseipdb.Key = "test_key"
+		cfg.Abuseipdb.Interval = 0
+	})()
+
+	httpmock.RegisterResponder("POST", abuseipdb.DefaultBaseUrl+"/report",
+		func(request *http.Request) (*http.Response, error) {
+			assert.Equal(t, "test_key", request.Header.Get("Key"))
+			assert.NoError(t, request.ParseForm())
+			assert.Equal(t, "127.0.0.1", request.Form.Get("ip"))
+			assert.Equal(t, "18,15", request.Form.Get("categories"))
+			assert.Equal(t, `Funeypot detected 5 redis attempts in 0s. Last by user "default", password "pas***rd4", client "".`, request.Form.Get("comment"))
+			return httpmock.NewStringResponse(200, `{}`), nil
+		})
+
+	client := dialRedis(t)
+	defer client.Close() // nolint:errcheck
+	for i := 0; i < 5; i++ {
+		client.Do("AUTH", fmt.Sprintf("password%d", i))
+	}
+
+	WaitAssert(time.Second, func() bool {
+		return httpmock.GetTotalCallCount() > 0
+	})
+	assert.Equal(t, 1, httpmock.GetTotalCallCount())
+}
+
+type redisClient struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func dialRedis(t *testing.T) *redisClient {
+	conn, err := net.Dial("tcp", "127.0.0.1:6380")
+	require.NoError(t, err)
+	return &redisClient{
+		Conn:   conn,
+		reader: bufio.NewReader(conn),
+	}
+}
+
+// Do sends the command and returns the first line of the reply, or the content of a bulk string reply.
+func (c *redisClient) Do(args ...string) string {
+	b := &strings.Builder{}
+	_, _ = fmt.Fprintf(b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		_, _ = fmt.Fprintf(b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	return c.send(b.String())
+}
+
+func (c *redisClient) DoInline(args ...string) string {
+	return c.send(strings.Join(args, " ") + "\r\n")
+}
+
+func (c *redisClient) send(s string) string {
+	if _, err := c.Write([]byte(s)); err != nil {
+		return err.Error()
+	}
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return err.Error()
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if strings.HasPrefix(line, "$") && line != "$-1" {
+		line, err = c.reader.ReadString('\n')
+		if err != nil {
+			return err.Error()
+		}
+		line = strings.TrimSuffix(line, "\r\n")
+	}
+	return line
+}
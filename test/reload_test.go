@@ -0,0 +1,120 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/funeypot/funeypot/internal/app/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestReload(t *testing.T) {
+	entrypoint, cleanup := PrepareEntrypoint(t, func(cfg *config.Config) {
+		cfg.Http.Enabled = true
+		cfg.Dashboard.Enabled = true
+		cfg.Dashboard.Username = "dashboard_username"
+		cfg.Dashboard.Password = "dashboard_password"
+	})
+	defer cleanup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	next := *entrypoint.Config
+	next.Ssh.Delay = 0
+	next.Http.Address = ":8081"
+	next.Dashboard.Password = "new_password"
+	next.Database.Dsn = filepath.Join(t.TempDir(), "other.db")
+	require.NoError(t, entrypoint.Reload(ctx, cancel, &next))
+	assert.Same(t, &next, entrypoint.Config)
+
+	t.Run("delay", func(t *testing.T) {
+		start := time.Now()
+		_, err := ssh.Dial("tcp", "127.0.0.1:2222", &ssh.ClientConfig{
+			User:            "username",
+			Auth:            []ssh.AuthMethod{ssh.Password("password")},
+			HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		})
+		assert.ErrorContains(t, err, "ssh: handshake failed: ssh: unable to authenticate")
+		assert.Less(t, time.Since(start), 2*time.Second)
+	})
+
+	t.Run("address", func(t *testing.T) {
+		require.NoError(t, waitTcp(time.Now().Add(5*time.Second), ":8081"))
+		_, err := net.DialTimeout("tcp", "127.0.0.1:8080", time.Second)
+		assert.Error(t, err)
+	})
+
+	t.Run("credentials", func(t *testing.T) {
+		verify := func(password string) int {
+			req, err := http.NewRequest("GET", "http://127.0.0.1:8081/api/v1/points", nil)
+			require.NoError(t, err)
+			req.SetBasicAuth("dashboard_username", password)
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			_ = resp.Body.Close()
+			return resp.StatusCode
+		}
+		assert.Equal(t, 401, verify("dashboard_password"))
+		assert.Equal(t, 200, verify("new_password"))
+	})
+
+	t.Run("sinks", func(t *testing.T) {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer conn.Close() // nolint:errcheck
+
+		withSyslog := next
+		withSyslog.Syslog.Enabled = true
+		withSyslog.Syslog.Address = conn.LocalAddr().String()
+		require.NoError(t, entrypoint.Reload(ctx, cancel, &withSyslog))
+		next = withSyslog
+
+		req, err := http.NewRequest("GET", "http://127.0.0.1:8081", nil)
+		require.NoError(t, err)
+		req.SetBasicAuth("username", "password")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+
+		buf := make([]byte, 2048)
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+		n, _, err := conn.ReadFrom(buf)
+		require.NoError(t, err)
+		assert.Contains(t, string(buf[:n]), `http login from 127.0.0.1 as user "username"`)
+	})
+
+	t.Run("busy address", func(t *testing.T) {
+		busy, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer busy.Close() // nolint:errcheck
+
+		current, servers := entrypoint.Config, entrypoint.Servers
+		withBusy := next
+		withBusy.Http.Address = busy.Addr().String()
+		assert.ErrorContains(t, entrypoint.Reload(ctx, cancel, &withBusy), "listen on "+busy.Addr().String())
+		assert.Same(t, current, entrypoint.Config)
+		assert.Equal(t, servers, entrypoint.Servers)
+
+		// the process keeps running with the current servers
+		time.Sleep(100 * time.Millisecond)
+		assert.NoError(t, ctx.Err())
+		require.NoError(t, waitTcp(time.Now().Add(time.Second), ":8081"))
+	})
+
+	t.Run("nothing changed", func(t *testing.T) {
+		same := next
+		require.NoError(t, entrypoint.Reload(ctx, cancel, &same))
+		assert.Len(t, entrypoint.Servers, 2)
+	})
+}
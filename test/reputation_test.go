@@ -0,0 +1,79 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/funeypot/funeypot/internal/app/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReputation(t *testing.T) {
+	HttpClient := &http.Client{
+		Transport: http.DefaultTransport,
+	}
+
+	var checks, blacklists atomic.Int32
+	abuseipdbServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/check":
+			assert.Equal(t, "127.0.0.1", r.URL.Query().Get("ipAddress"))
+			assert.Equal(t, "30", r.URL.Query().Get("maxAgeInDays"))
+			checks.Add(1)
+			_, _ = w.Write([]byte(`{"data": {"ipAddress": "127.0.0.1", "abuseConfidenceScore": 100, "usageType": "Reserved", "isp": "Loopback", "totalReports": 12}}`))
+		case "/blacklist":
+			blacklists.Add(1)
+			_, _ = w.Write([]byte(`{"meta": {"generatedAt": "2024-03-04T05:06:07+00:00"}, "data": [{"ipAddress": "127.0.0.1", "abuseConfidenceScore": 100}]}`))
+		default:
+			t.Errorf("unexpected path %q", r.URL.Path)
+		}
+	}))
+	defer abuseipdbServer.Close()
+
+	defer PrepareServers(t, func(cfg *config.Config) {
+		cfg.Http.Enabled = true
+		cfg.Abuseipdb.Enabled = true
+		cfg.Abuseipdb.BaseUrl = abuseipdbServer.URL
+		cfg.Abuseipdb.Key = "test_key"
+		cfg.Abuseipdb.MinCount = 100
+		cfg.Abuseipdb.Check = config.AbuseipdbCheck{
+			Enabled:      true,
+			Ttl:          time.Hour,
+			MaxAgeInDays: 30,
+		}
+		cfg.Abuseipdb.Blacklist = config.AbuseipdbBlacklist{
+			Enabled:           true,
+			Interval:          time.Hour,
+			ConfidenceMinimum: 100,
+			Limit:             10,
+		}
+	})()
+
+	// fetched once it starts
+	WaitAssert(time.Second, func() bool {
+		return blacklists.Load() > 0
+	})
+
+	for i := 0; i < 3; i++ {
+		req, err := http.NewRequest("GET", "http://127.0.0.1:8080", nil)
+		require.NoError(t, err)
+		req.SetBasicAuth("username", "password")
+		_, _ = HttpClient.Do(req)
+	}
+
+	WaitAssert(time.Second, func() bool {
+		return checks.Load() > 0
+	})
+	time.Sleep(300 * time.Millisecond)
+	assert.Equal(t, int32(1), checks.Load(), "the result is cached")
+	assert.Equal(t, int32(1), blacklists.Load())
+}
@@ -4,17 +4,23 @@
 package test
 
 import (
+	"bufio"
 	"net"
 	"net/http"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/funeypot/funeypot/internal/app/config"
+	"github.com/funeypot/funeypot/internal/app/model"
 	"github.com/funeypot/funeypot/internal/pkg/abuseipdb"
 
+	"github.com/glebarez/sqlite"
 	"github.com/jarcoal/httpmock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"golang.org/x/crypto/ssh"
+	"gorm.io/gorm"
 )
 
 func TestSshServer(t *testing.T) {
@@ -52,6 +58,50 @@ func TestSshServer_FixedKey(t *testing.T) {
 	assert.Greater(t, time.Since(start), 2*time.Second)
 }
 
+func TestSshServer_Listeners(t *testing.T) {
+	dsn := filepath.Join(t.TempDir(), "funeypot.db")
+	defer PrepareServers(t, func(cfg *config.Config) {
+		cfg.Database.Dsn = dsn
+		delay := time.Duration(0)
+		cfg.Ssh.Listeners = []config.SshListener{
+			{Address: ":2223", Delay: &delay, Version: "OpenSSH_9.6"},
+		}
+	})()
+
+	conn, err := net.Dial("tcp", "127.0.0.1:2223")
+	require.NoError(t, err)
+	version, err := bufio.NewReader(conn).ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "SSH-2.0-OpenSSH_9.6\r\n", version)
+	_ = conn.Close()
+
+	sshConfig := &ssh.ClientConfig{
+		User:            "username",
+		Auth:            []ssh.AuthMethod{ssh.Password("password")},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	}
+
+	start := time.Now()
+	_, err = ssh.Dial("tcp", "127.0.0.1:2223", sshConfig)
+	assert.ErrorContains(t, err, "ssh: handshake failed: ssh: unable to authenticate")
+	assert.Less(t, time.Since(start), 2*time.Second)
+
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	require.NoError(t, err)
+	attempt := &model.BruteAttempt{}
+	WaitAssert(time.Second, func() bool {
+		return db.Last(attempt).Error == nil
+	})
+	assert.Equal(t, 2223, attempt.LocalPort)
+
+	_, err = ssh.Dial("tcp", "127.0.0.1:2222", sshConfig)
+	assert.ErrorContains(t, err, "ssh: handshake failed: ssh: unable to authenticate")
+	WaitAssert(time.Second, func() bool {
+		return db.Last(attempt).Error == nil && attempt.Count == 2
+	})
+	assert.Equal(t, 2222, attempt.LocalPort)
+}
+
 func TestSshServer_Report(t *testing.T) {
 	httpmock.Activate()
 	defer httpmock.DeactivateAndReset()
@@ -64,7 +114,7 @@ func TestSshServer_Report(t *testing.T) {
 
 	t.Run("first", func(t *testing.T) {
 		defer httpmock.Reset()
-		httpmock.RegisterResponder("POST", abuseipdb.ReportUrl,
+		httpmock.RegisterResponder("POST", abuseipdb.DefaultBaseUrl+"/report",
 			func(request *http.Request) (*http.Response, error) {
 				assert.Equal(t, "test_key", request.Header.Get("Key"))
 				assert.Equal(t, "application/x-www-form-urlencoded", request.Header.Get("Content-Type"))
@@ -93,7 +143,7 @@ func TestSshServer_Report(t *testing.T) {
 
 	t.Run("continue", func(t *testing.T) {
 		defer httpmock.Reset()
-		httpmock.RegisterResponder("POST", abuseipdb.ReportUrl,
+		httpmock.RegisterResponder("POST", abuseipdb.DefaultBaseUrl+"/report",
 			func(request *http.Request) (*http.Response, error) {
 				assert.Equal(t, "test_key", request.Header.Get("Key"))
 				assert.Equal(t, "application/x-www-form-urlencoded", request.Header.Get("Content-Type"))
@@ -119,3 +169,57 @@ func TestSshServer_Report(t *testing.T) {
 		assert.Equal(t, 1, httpmock.GetTotalCallCount())
 	})
 }
+
+func TestSshServer_Aggregation(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	dsn := filepath.Join(t.TempDir(), "funeypot.db")
+	defer PrepareServers(t, func(cfg *config.Config) {
+		cfg.Database.Dsn = dsn
+		cfg.Ssh.Delay = 0
+		cfg.Aggregation.Kinds = map[string]config.AggregationRule{
+			"ssh": {Mode: config.AggregationModeFixed, Window: time.Second},
+		}
+		cfg.Abuseipdb.Enabled = true
+		cfg.Abuseipdb.Key = "test_key"
+		cfg.Abuseipdb.Interval = 0
+		cfg.Abuseipdb.Kinds = map[string]config.AbuseipdbKind{
+			"ssh": {MinCount: 2},
+		}
+	})()
+
+	httpmock.RegisterResponder("POST", abuseipdb.DefaultBaseUrl+"/report",
+		func(request *http.Request) (*http.Response, error) {
+			assert.NoError(t, request.ParseForm())
+			assert.Contains(t, request.Form.Get("comment"), "Funeypot detected 2 ssh attempts")
+			return httpmock.NewStringResponse(200, `{}`), nil
+		})
+
+	sshConfig := &ssh.ClientConfig{
+		User:            "username",
+		Auth:            []ssh.AuthMethod{ssh.Password("password")},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	}
+	for i := 0; i < 2; i++ {
+		_, _ = ssh.Dial("tcp", "127.0.0.1:2222", sshConfig)
+	}
+	WaitAssert(time.Second, func() bool {
+		return httpmock.GetTotalCallCount() > 0
+	})
+	assert.Equal(t, 1, httpmock.GetTotalCallCount())
+
+	// the window of the row is over
+	time.Sleep(time.Second)
+	_, _ = ssh.Dial("tcp", "127.0.0.1:2222", sshConfig)
+
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	require.NoError(t, err)
+	var attempts []*model.BruteAttempt
+	WaitAssert(time.Second, func() bool {
+		return db.Order("id").Find(&attempts).Error == nil && len(attempts) == 2
+	})
+	require.Len(t, attempts, 2)
+	assert.Equal(t, int64(2), attempts[0].Count)
+	assert.Equal(t, int64(1), attempts[1].Count)
+}
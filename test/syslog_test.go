@@ -0,0 +1,44 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package test
+
+import (
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/funeypot/funeypot/internal/app/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyslog(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close() // nolint:errcheck
+
+	defer PrepareServers(t, func(cfg *config.Config) {
+		cfg.Http.Enabled = true
+		cfg.Syslog.Enabled = true
+		cfg.Syslog.Address = conn.LocalAddr().String()
+		cfg.Syslog.MaskPassword = true
+	})()
+
+	req, err := http.NewRequest("GET", "http://127.0.0.1:8080", nil)
+	require.NoError(t, err)
+	req.SetBasicAuth("username", "password")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	buf := make([]byte, 2048)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	n, _, err := conn.ReadFrom(buf)
+	require.NoError(t, err)
+	message := string(buf[:n])
+	assert.Regexp(t, `^<133>1 \S+ \S+ funeypot \d+ attempt \[attempt@32473 kind="http" ip="127.0.0.1" user="username" password="pa\*\*\*\*rd" `, message)
+	assert.Contains(t, message, `http login from 127.0.0.1 as user "username"`)
+}
@@ -0,0 +1,94 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package test
+
+import (
+	"crypto/tls"
+	"io"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/funeypot/funeypot/internal/app/config"
+	"github.com/funeypot/funeypot/internal/app/model"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestTcpServer(t *testing.T) {
+	dsn := filepath.Join(t.TempDir(), "funeypot.db")
+
+	defer PrepareServers(t, func(cfg *config.Config) {
+		cfg.Database.Dsn = dsn
+		cfg.Tcp = []config.Tcp{
+			{Address: ":2323", Banner: "login: ", ReadTimeout: 200 * time.Millisecond, MaxBytes: 16},
+			{Address: ":8443", ReadTimeout: time.Second, MaxBytes: 1024},
+		}
+	})()
+
+	t.Run("banner", func(t *testing.T) {
+		conn, err := net.Dial("tcp", "127.0.0.1:2323")
+		require.NoError(t, err)
+		defer conn.Close() // nolint:errcheck
+
+		banner := make([]byte, 7)
+		_, err = io.ReadFull(conn, banner)
+		require.NoError(t, err)
+		assert.Equal(t, "login: ", string(banner))
+
+		// more than max bytes, the server closes the connection once the buffer is full
+		_, err = conn.Write([]byte("root\r\n123456\r\nuname -a\r\n"))
+		require.NoError(t, err)
+		_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+		_, err = io.ReadAll(conn)
+		assert.Error(t, err) // reset by the server since there is unread data
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		conn, err := net.Dial("tcp", "127.0.0.1:2323")
+		require.NoError(t, err)
+		defer conn.Close() // nolint:errcheck
+
+		_, err = io.ReadAll(conn)
+		require.NoError(t, err)
+	})
+
+	t.Run("tls", func(t *testing.T) {
+		conn, err := net.Dial("tcp", "127.0.0.1:8443")
+		require.NoError(t, err)
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: "example.com"})
+		_ = tlsConn.SetDeadline(time.Now().Add(100 * time.Millisecond))
+		_ = tlsConn.Handshake() // it fails since the server never replies
+		_ = conn.Close()
+	})
+
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	require.NoError(t, err)
+
+	// the connections without data include the ones to check whether the servers are ready
+	var payloads []*model.TcpPayload
+	WaitAssert(time.Second, func() bool {
+		payloads = nil
+		return db.Where("size > 0").Order("port").Find(&payloads).Error == nil && len(payloads) == 2
+	})
+	require.Len(t, payloads, 2)
+
+	assert.Equal(t, 2323, payloads[0].Port)
+	assert.Equal(t, "unknown", payloads[0].Protocol)
+	assert.Equal(t, 16, payloads[0].Size)
+	assert.Equal(t, "root..123456..un", payloads[0].Printable)
+	assert.Equal(t, "726f6f740d0a3132333435360d0a756e", payloads[0].Hex)
+
+	assert.Equal(t, 8443, payloads[1].Port)
+	assert.Equal(t, "tls", payloads[1].Protocol)
+	assert.Equal(t, "127.0.0.1", payloads[1].Ip)
+
+	var empty int64
+	require.NoError(t, db.Model(&model.TcpPayload{}).Where("port = ? AND protocol = ?", 2323, "none").Count(&empty).Error)
+	assert.GreaterOrEqual(t, empty, int64(2))
+}
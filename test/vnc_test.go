@@ -0,0 +1,149 @@
+// Copyright 2024 The Funeypot Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package test
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/funeypot/funeypot/internal/app/config"
+	"github.com/funeypot/funeypot/internal/pkg/abuseipdb"
+	"github.com/funeypot/funeypot/internal/pkg/vncauth"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVncServer(t *testing.T) {
+	wordlist := filepath.Join(t.TempDir(), "wordlist.txt")
+	require.NoError(t, os.WriteFile(wordlist, []byte("123456\npassword\n"), 0o644))
+
+	for _, version := range []string{"RFB 003.003", "RFB 003.007", "RFB 003.008"} {
+		t.Run(version, func(t *testing.T) {
+			defer PrepareServers(t, func(cfg *config.Config) {
+				cfg.Vnc.Enabled = true
+				cfg.Vnc.Version = version
+				cfg.Vnc.Wordlist = wordlist
+			})()
+
+			conn, err := net.Dial("tcp", "127.0.0.1:5901")
+			require.NoError(t, err)
+			defer conn.Close() // nolint:errcheck
+
+			reason := authenticateVnc(t, conn, "", "password")
+			if version == "RFB 003.008" {
+				assert.Equal(t, "Authentication failed", reason)
+			}
+		})
+	}
+
+	t.Run("newer client", func(t *testing.T) {
+		defer PrepareServers(t, func(cfg *config.Config) {
+			cfg.Vnc.Enabled = true
+			cfg.Vnc.Version = "RFB 003.003"
+			cfg.Vnc.Wordlist = wordlist
+		})()
+
+		conn, err := net.Dial("tcp", "127.0.0.1:5901")
+		require.NoError(t, err)
+		defer conn.Close() // nolint:errcheck
+
+		// the server decides the security type, as RFB 3.3 is the lower version
+		authenticateVnc(t, conn, "RFB 003.008", "password")
+	})
+}
+
+func TestVncServer_Report(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	wordlist := filepath.Join(t.TempDir(), "wordlist.txt")
+	require.NoError(t, os.WriteFile(wordlist, []byte("password\n"), 0o644))
+
+	defer PrepareServers(t, func(cfg *config.Config) {
+		cfg.Vnc.Enabled = true
+		cfg.Vnc.Wordlist = wordlist
+		cfg.Abuseipdb.Enabled = true
+		cfg.Abuseipdb.Key = "test_key"
+		cfg.Abuseipdb.Interval = 0
+	})()
+
+	httpmock.RegisterResponder("POST", abuseipdb.DefaultBaseUrl+"/report",
+		func(request *http.Request) (*http.Response, error) {
+			assert.NoError(t, request.ParseForm())
+			assert.Equal(t, "127.0.0.1", request.Form.Get("ip"))
+			assert.Equal(t, "18,15", request.Form.Get("categories"))
+			assert.Equal(t, `Funeypot detected 5 vnc attempts in 0s. Last by user "", password "pa****rd", client "RFB 003.008".`, request.Form.Get("comment"))
+			return httpmock.NewStringResponse(200, `{}`), nil
+		})
+
+	for i := 0; i < 5; i++ {
+		conn, err := net.Dial("tcp", "127.0.0.1:5901")
+		require.NoError(t, err)
+		authenticateVnc(t, conn, "", "password")
+		_ = conn.Close()
+	}
+
+	WaitAssert(time.Second, func() bool {
+		return httpmock.GetTotalCallCount() > 0
+	})
+	assert.Equal(t, 1, httpmock.GetTotalCallCount())
+}
+
+// authenticateVnc does the VNC authentication with the client version, or the same version as the server if it's empty,
+// and returns the failure reason if any.
+func authenticateVnc(t *testing.T, conn net.Conn, clientVersion, password string) string {
+	version := make([]byte, 12)
+	_, err := io.ReadFull(conn, version)
+	require.NoError(t, err)
+	if clientVersion != "" {
+		_, err = conn.Write([]byte(clientVersion + "\n"))
+		// both sides use the lower version, the format makes them comparable as strings
+		version = []byte(min(string(version), clientVersion+"\n"))
+	} else {
+		_, err = conn.Write(version)
+	}
+	require.NoError(t, err)
+
+	buf := make([]byte, 4)
+	if string(version) == "RFB 003.003\n" {
+		_, err = io.ReadFull(conn, buf)
+		require.NoError(t, err)
+		require.Equal(t, uint32(2), binary.BigEndian.Uint32(buf))
+	} else {
+		_, err = io.ReadFull(conn, buf[:2])
+		require.NoError(t, err)
+		require.Equal(t, []byte{1, 2}, buf[:2])
+		_, err = conn.Write([]byte{2})
+		require.NoError(t, err)
+	}
+
+	var challenge [vncauth.ChallengeSize]byte
+	_, err = io.ReadFull(conn, challenge[:])
+	require.NoError(t, err)
+	response := vncauth.Encrypt(challenge, password)
+	_, err = conn.Write(response[:])
+	require.NoError(t, err)
+
+	_, err = io.ReadFull(conn, buf)
+	require.NoError(t, err)
+	require.Equal(t, uint32(1), binary.BigEndian.Uint32(buf))
+
+	if string(version) != "RFB 003.008\n" {
+		return ""
+	}
+	_, err = io.ReadFull(conn, buf)
+	require.NoError(t, err)
+	reason := make([]byte, binary.BigEndian.Uint32(buf))
+	_, err = io.ReadFull(conn, reason)
+	require.NoError(t, err)
+	return string(reason)
+}